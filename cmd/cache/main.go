@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/siddon/web3insights/internal/cache"
+	"github.com/siddon/web3insights/internal/config"
+)
+
+func main() {
+	var (
+		configFile = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
+		chain      = flag.String("chain", "", "Blockchain whose cache to manage (default: from config, supports: btc, eth)")
+		prune      = flag.Bool("prune", false, "Evict synced dates until the cache fits in the byte budget")
+		maxBytes   = flag.String("max-bytes", "", "Byte budget for pruning, e.g. 50GB (default: cache_max_bytes from config)")
+		policy     = flag.String("policy", "", "Eviction policy: lru or oldest (default: cache_policy from config)")
+		evictDate  = flag.String("evict", "", "Remove a single synced date (YYYY-MM-DD format)")
+		dryRun     = flag.Bool("dry-run", false, "Show what would be removed without deleting anything")
+	)
+	flag.Parse()
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+	if *configFile != "" {
+		cfg, err = config.LoadFromPath(*configFile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Override chain from command line if provided
+	if *chain != "" {
+		cfg.Chain = *chain
+	}
+
+	manager := cache.NewManager(cfg)
+	if *maxBytes != "" {
+		size, err := config.ParseSize(*maxBytes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		manager.MaxBytes = size
+	}
+	if *policy != "" {
+		if err := config.ValidateCachePolicy(*policy); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		manager.Policy = *policy
+	}
+	if *dryRun {
+		manager.DryRun = true
+	}
+
+	if *evictDate != "" {
+		if err := evictSingleDate(manager, *evictDate); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *prune {
+		if manager.MaxBytes <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: no byte budget configured (set cache_max_bytes or pass -max-bytes)\n")
+			os.Exit(1)
+		}
+		evicted, err := manager.Enforce()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error pruning cache: %v\n", err)
+			os.Exit(1)
+		}
+		var freed int64
+		for _, usage := range evicted {
+			freed += usage.Bytes
+		}
+		fmt.Printf("Evicted %d dates, freed %s\n\n", len(evicted), formatBytes(freed))
+	}
+
+	usages, err := manager.Usage()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning cache: %v\n", err)
+		os.Exit(1)
+	}
+	printUsage(manager, usages)
+}
+
+// evictSingleDate removes one date, refusing to drop files that are not fully synced
+func evictSingleDate(manager *cache.Manager, date string) error {
+	usages, err := manager.Usage()
+	if err != nil {
+		return err
+	}
+	for _, usage := range usages {
+		if usage.Date != date {
+			continue
+		}
+		if !usage.Synced {
+			return fmt.Errorf("date %s has unsynced files, refusing to evict", date)
+		}
+		return manager.Evict(date)
+	}
+	return fmt.Errorf("date %s is not in the cache", date)
+}
+
+// printUsage prints a per-date table followed by a summary line
+func printUsage(manager *cache.Manager, usages []cache.DateUsage) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tFILES\tSIZE\tSYNCED\tLAST ACCESS")
+
+	var total, synced int64
+	for _, usage := range usages {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%t\t%s\n", usage.Date, usage.Files, formatBytes(usage.Bytes), usage.Synced, usage.LastAccess.Format("2006-01-02 15:04:05"))
+		total += usage.Bytes
+		if usage.Synced {
+			synced += usage.Bytes
+		}
+	}
+	w.Flush()
+
+	budget := "unlimited"
+	if manager.MaxBytes > 0 {
+		budget = formatBytes(manager.MaxBytes)
+	}
+	fmt.Printf("\nTotal: %d dates, %s (%s evictable), budget: %s, policy: %s\n", len(usages), formatBytes(total), formatBytes(synced), budget, manager.Policy)
+}
+
+// formatBytes renders a byte count using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
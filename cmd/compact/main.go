@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/siddon/web3insights/internal/cache"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/lake"
+	"github.com/siddon/web3insights/internal/registry"
+)
+
+func main() {
+	var (
+		configFile = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
+		date       = flag.String("date", "", "Date to compact (YYYY-MM-DD format, e.g., 2024-01-01)")
+		startDate  = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
+		endDate    = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
+		chain      = flag.String("chain", "", "Blockchain to compact (default: from config, supports: btc)")
+		out        = flag.String("out", "", "Lake root directory (default: lake_dir from config, or ./lake)")
+		force      = flag.Bool("force", false, "Rewrite table files that already exist")
+	)
+	flag.Parse()
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+	if *configFile != "" {
+		cfg, err = config.LoadFromPath(*configFile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Override chain and lake root from command line if provided
+	if *chain != "" {
+		cfg.Chain = *chain
+	}
+	if *out != "" {
+		cfg.LakeDir = *out
+	}
+	c, err := registry.Lookup(cfg.Chain)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !lake.Supported(c) {
+		fmt.Fprintf(os.Stderr, "Error: compact does not support chain %s (supported: btc)\n", c.Name)
+		os.Exit(1)
+	}
+
+	// Validate flags
+	if *date != "" && (*startDate != "" || *endDate != "") {
+		fmt.Fprintf(os.Stderr, "Error: cannot specify both -date and -start/-end\n")
+		os.Exit(1)
+	}
+	if *date == "" && (*startDate == "" || *endDate == "") {
+		fmt.Fprintf(os.Stderr, "Error: must specify either -date or both -start and -end\n")
+		os.Exit(1)
+	}
+
+	start, end := *startDate, *endDate
+	if *date != "" {
+		start, end = *date, *date
+	}
+	startTime, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid start date: %s (expected YYYY-MM-DD)\n", start)
+		os.Exit(1)
+	}
+	endTime, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid end date: %s (expected YYYY-MM-DD)\n", end)
+		os.Exit(1)
+	}
+	if endTime.Before(startTime) {
+		fmt.Fprintf(os.Stderr, "Error: end date must be after or equal to start date\n")
+		os.Exit(1)
+	}
+
+	cacheManager := cache.NewManager(cfg)
+
+	for current := startTime; !current.After(endTime); current = current.AddDate(0, 0, 1) {
+		dateStr := current.Format("2006-01-02")
+		fmt.Printf("\n--- Processing date: %s ---\n", dateStr)
+
+		cacheManager.Touch(dateStr)
+
+		results, err := lake.Compact(cfg, c, cfg.LakeDir, dateStr, *force)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error compacting date %s: %v\n", dateStr, err)
+			os.Exit(1)
+		}
+		for _, result := range results {
+			if result.Skipped {
+				fmt.Printf("Skipping existing %s file: %s\n", result.Table, result.Path)
+				continue
+			}
+			fmt.Printf("Wrote %d %s to %s\n", result.Rows, strings.ReplaceAll(result.Table, "_", " "), result.Path)
+		}
+	}
+
+	fmt.Printf("\nCompact completed: %s\n", cfg.LakeDir)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/siddon/web3insights/internal/awsdata"
+	"github.com/siddon/web3insights/internal/cache"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
+)
+
+// catalogRow combines the upstream catalog entry for a date with its local state
+type catalogRow struct {
+	awsdata.CatalogEntry
+	LocalFiles int   `json:"local_files"`
+	LocalBytes int64 `json:"local_bytes"`
+	Synced     bool  `json:"synced"`
+}
+
+// listCatalog prints the upstream dates in [start, end] with local and sync status
+func listCatalog(ctx context.Context, cfg *config.Config, start, end, format string) error {
+	if start != "" {
+		if err := validateDate(start); err != nil {
+			return fmt.Errorf("invalid start date format: %w", err)
+		}
+	}
+	if end != "" {
+		if err := validateDate(end); err != nil {
+			return fmt.Errorf("invalid end date format: %w", err)
+		}
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unsupported format: %s (expected 'table' or 'json')", format)
+	}
+
+	c, err := registry.Lookup(cfg.Chain)
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.Catalog(ctx, cfg, start, end)
+	if err != nil {
+		return err
+	}
+
+	usages, err := cache.NewManager(cfg).Usage()
+	if err != nil {
+		return fmt.Errorf("failed to scan local files: %w", err)
+	}
+	local := make(map[string]cache.DateUsage, len(usages))
+	for _, usage := range usages {
+		local[usage.Date] = usage
+	}
+
+	rows := make([]catalogRow, 0, len(entries))
+	for _, entry := range entries {
+		usage := local[entry.Date]
+		rows = append(rows, catalogRow{
+			CatalogEntry: entry,
+			LocalFiles:   usage.Files,
+			LocalBytes:   usage.Bytes,
+			Synced:       usage.Synced,
+		})
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	}
+	printCatalogTable(rows, c.DatasetNames())
+	return nil
+}
+
+// printCatalogTable prints one line per date followed by a summary of missing dates
+func printCatalogTable(rows []catalogRow, datasets []string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	header := []string{"DATE"}
+	for _, dataset := range datasets {
+		name := strings.ToUpper(dataset)
+		header = append(header, name+" OBJECTS", name+" BYTES")
+	}
+	header = append(header, "LOCAL FILES", "SYNCED", "MISSING UPSTREAM")
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	var missingDates int
+	for _, row := range rows {
+		fields := []string{row.Date}
+		for _, dataset := range datasets {
+			stats := row.Datasets[dataset]
+			fields = append(fields, fmt.Sprintf("%d", stats.Objects), fmt.Sprintf("%d", stats.Bytes))
+		}
+		missing := "-"
+		if len(row.Missing) > 0 {
+			missing = strings.Join(row.Missing, ",")
+			missingDates++
+		}
+		fields = append(fields, fmt.Sprintf("%d", row.LocalFiles), fmt.Sprintf("%t", row.Synced), missing)
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d dates listed, %d with missing upstream datasets\n", len(rows), missingDates)
+}
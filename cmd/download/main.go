@@ -5,10 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/siddon/web3insights/internal/awsdata"
+	"github.com/siddon/web3insights/internal/cache"
 	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
 )
 
 func main() {
@@ -17,7 +19,10 @@ func main() {
 		date       = flag.String("date", "", "Download data for a specific date (YYYY-MM-DD format, e.g., 2019-01-01)")
 		startDate  = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
 		endDate    = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
-		chain      = flag.String("chain", "", "Blockchain to download (default: from config, currently supports: btc)")
+		chain      = flag.String("chain", "", "Blockchain to download (default: from config, supports: "+strings.Join(registry.Names(), ", ")+")")
+		list       = flag.Bool("list", false, "List dates available upstream with object counts, sizes and local status instead of downloading (optionally limited by -date or -start/-end)")
+		format     = flag.String("format", "table", "Output format for -list: table or json")
+		limitRate  = flag.String("limit-rate", "", "Cap total download bandwidth, e.g. 5MB for 5 MiB/s (default: download_max_bytes_per_sec from config)")
 	)
 	flag.Parse()
 
@@ -38,12 +43,13 @@ func main() {
 	if *chain != "" {
 		cfg.Chain = *chain
 	}
-
-	// Validate that we have at least one date option
-	if *date == "" && (*startDate == "" || *endDate == "") {
-		fmt.Fprintf(os.Stderr, "Error: must specify either -date or both -start and -end\n")
-		flag.Usage()
-		os.Exit(1)
+	if *limitRate != "" {
+		rate, err := config.ParseSize(*limitRate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -limit-rate: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.DownloadMaxBytesPerSec = rate
 	}
 
 	// Validate that date and date range are not both specified
@@ -55,6 +61,26 @@ func main() {
 
 	ctx := context.Background()
 
+	// Handle catalog listing; the date options are optional filters here
+	if *list {
+		start, end := *startDate, *endDate
+		if *date != "" {
+			start, end = *date, *date
+		}
+		if err := listCatalog(ctx, cfg, start, end, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing catalog: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Validate that we have at least one date option
+	if *date == "" && (*startDate == "" || *endDate == "") {
+		fmt.Fprintf(os.Stderr, "Error: must specify either -date or both -start and -end\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	// Handle single date
 	if *date != "" {
 		if err := downloadForDate(ctx, cfg, *date); err != nil {
@@ -79,12 +105,19 @@ func downloadForDate(ctx context.Context, cfg *config.Config, date string) error
 
 	fmt.Printf("Downloading %s data for date: %s\n", cfg.Chain, date)
 
-	switch cfg.Chain {
-	case "bitcoin", "btc":
-		return awsdata.DownloadBTC(ctx, cfg, date)
-	default:
-		return fmt.Errorf("unsupported chain: %s (currently only 'btc' or 'bitcoin' is supported)", cfg.Chain)
+	c, err := registry.Lookup(cfg.Chain)
+	if err != nil {
+		return err
+	}
+	if err := c.Download(ctx, cfg, date); err != nil {
+		return err
+	}
+
+	// Make room for the next date by evicting already synced ones
+	if _, err := cache.NewManager(cfg).Enforce(date); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to prune cache: %v\n", err)
 	}
+	return nil
 }
 
 // downloadForDateRange downloads data for a range of dates (inclusive)
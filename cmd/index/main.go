@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/siddon/web3insights/internal/cache"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
+	"github.com/siddon/web3insights/internal/tidb"
+)
+
+func main() {
+	var (
+		configFile   = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
+		date         = flag.String("date", "", "Date to index (YYYY-MM-DD format, e.g., 2024-04-20)")
+		startDate    = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
+		endDate      = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
+		fromHeight   = flag.Int64("from-height", -1, "Roll the token tables back to before this block and index again from it")
+		createTables = flag.Bool("create-tables", false, "Create the btc tables (CREATE TABLE IF NOT EXISTS) before indexing")
+	)
+	flag.Parse()
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+	if *configFile != "" {
+		cfg, err = config.LoadFromPath(*configFile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Tokens are Bitcoin protocols, so the chain is always btc
+	c, err := registry.Lookup("btc")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	profiles, err := tidb.ParseProfiles(cfg.ColumnProfiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validate flags
+	if *date != "" && (*startDate != "" || *endDate != "") {
+		fmt.Fprintf(os.Stderr, "Error: cannot specify both -date and -start/-end\n")
+		os.Exit(1)
+	}
+	if *date == "" && (*startDate == "" || *endDate == "") {
+		fmt.Fprintf(os.Stderr, "Error: must specify either -date or both -start and -end\n")
+		os.Exit(1)
+	}
+
+	start, end := *startDate, *endDate
+	if *date != "" {
+		start, end = *date, *date
+	}
+	startTime, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid start date: %s (expected YYYY-MM-DD)\n", start)
+		os.Exit(1)
+	}
+	endTime, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid end date: %s (expected YYYY-MM-DD)\n", end)
+		os.Exit(1)
+	}
+	if endTime.Before(startTime) {
+		fmt.Fprintf(os.Stderr, "Error: end date must be after or equal to start date\n")
+		os.Exit(1)
+	}
+
+	// Open database connection
+	db, err := tidb.OpenSQL(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to TiDB: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if *createTables {
+		if err := tidb.CreateTables(db, c.SchemaDDL(profiles)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s tables: %v\n", c.Name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %s tables\n", c.Name)
+	}
+
+	if *fromHeight >= 0 {
+		if err := tidb.RollbackTokens(db, *fromHeight); err != nil {
+			fmt.Fprintf(os.Stderr, "Error rolling back to block %d: %v\n", *fromHeight, err)
+			os.Exit(1)
+		}
+	}
+
+	index, err := tidb.OpenTokenIndex(db, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading token index: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Token index is at block %d\n", index.Height())
+
+	ctx := context.Background()
+	cacheManager := cache.NewManager(cfg)
+
+	for current := startTime; !current.After(endTime); current = current.AddDate(0, 0, 1) {
+		dateStr := current.Format("2006-01-02")
+		fmt.Printf("\n--- Processing date: %s ---\n", dateStr)
+
+		// Download files if needed (downloads skip files that already exist)
+		if err := c.Download(ctx, cfg, dateStr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error downloading data for date %s: %v\n", dateStr, err)
+			os.Exit(1)
+		}
+		cacheManager.Touch(dateStr)
+
+		if err := index.IndexDate(c.DateDir(cfg, "transactions", dateStr)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error indexing date %s: %v\n", dateStr, err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("\nToken index is at block %d\n", index.Height())
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
+	"github.com/siddon/web3insights/internal/tidb"
+)
+
+func main() {
+	var (
+		configFile   = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
+		date         = flag.String("date", "", "Date to compute metrics for (YYYY-MM-DD format, e.g., 2024-04-20)")
+		startDate    = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
+		endDate      = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
+		metricsFlag  = flag.String("metrics", "all", "Comma-separated metrics to compute (difficulty, coin_days) or all")
+		reset        = flag.Bool("reset", false, "Clear the coin_days state and start it again from the start date")
+		createTables = flag.Bool("create-tables", false, "Create the btc tables (CREATE TABLE IF NOT EXISTS) before computing")
+	)
+	flag.Parse()
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+	if *configFile != "" {
+		cfg, err = config.LoadFromPath(*configFile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Metrics are computed from the btc tables
+	c, err := registry.Lookup("btc")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	profiles, err := tidb.ParseProfiles(cfg.ColumnProfiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	metrics, err := tidb.ParseMetrics(*metricsFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validate flags
+	if *date != "" && (*startDate != "" || *endDate != "") {
+		fmt.Fprintf(os.Stderr, "Error: cannot specify both -date and -start/-end\n")
+		os.Exit(1)
+	}
+	if *date == "" && (*startDate == "" || *endDate == "") {
+		fmt.Fprintf(os.Stderr, "Error: must specify either -date or both -start and -end\n")
+		os.Exit(1)
+	}
+
+	start, end := *startDate, *endDate
+	if *date != "" {
+		start, end = *date, *date
+	}
+	startTime, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid start date: %s (expected YYYY-MM-DD)\n", start)
+		os.Exit(1)
+	}
+	endTime, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid end date: %s (expected YYYY-MM-DD)\n", end)
+		os.Exit(1)
+	}
+	if endTime.Before(startTime) {
+		fmt.Fprintf(os.Stderr, "Error: end date must be after or equal to start date\n")
+		os.Exit(1)
+	}
+
+	// Open database connection
+	db, err := tidb.OpenSQL(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to TiDB: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if *createTables {
+		if err := tidb.CreateTables(db, c.SchemaDDL(profiles)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s tables: %v\n", c.Name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %s tables\n", c.Name)
+	}
+
+	if metrics.Enabled(tidb.MetricDifficulty) {
+		report, err := tidb.ComputeDifficultyMetrics(db, cfg, start, end)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error computing difficulty metrics: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Wrote the hashrate of %d blocks and %d difficulty epochs\n", report.Blocks, report.Epochs)
+		if len(report.ChainworkDecrease) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: chainwork does not increase at %d blocks (see the warnings above)\n", len(report.ChainworkDecrease))
+			os.Exit(1)
+		}
+	}
+
+	if metrics.Enabled(tidb.MetricCoinDays) {
+		if *reset {
+			if err := tidb.ResetCoinDays(db); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Reset coin_days")
+		}
+		for current := startTime; !current.After(endTime); current = current.AddDate(0, 0, 1) {
+			dateStr := current.Format("2006-01-02")
+			if err := tidb.ComputeCoinDays(db, dateStr); err != nil {
+				fmt.Fprintf(os.Stderr, "Error computing coin days for date %s: %v\n", dateStr, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Computed coin days for %s\n", dateStr)
+		}
+	}
+}
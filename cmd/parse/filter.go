@@ -0,0 +1,685 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// A filter is a boolean expression over the columns of a row, e.g.
+//
+//	fee > 0.1 && is_coinbase == false
+//	number >= 820000 && number < 820010
+//	outputs.address == 'bc1q...'
+//
+// Comparisons are column op literal with ==, !=, <, <=, > or >=, combined with
+// &&, || and !, and grouped with parentheses. Literals are numbers, quoted
+// strings, true, false and null; timestamps compare against quoted dates such
+// as '2024-01-01' or '2024-01-01T12:00:00Z'. Nested list columns are addressed
+// with dots (inputs.address) and match when any element matches.
+
+// valueKind is the comparison type of a column
+type valueKind int
+
+const (
+	kindInt valueKind = iota
+	kindFloat
+	kindString
+	kindBool
+	kindTime
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindInt:
+		return "integer"
+	case kindFloat:
+		return "number"
+	case kindString:
+		return "string"
+	case kindBool:
+		return "boolean"
+	default:
+		return "timestamp"
+	}
+}
+
+// filterExpr is a compiled filter expression
+type filterExpr interface {
+	// match reports whether the row matches
+	match(rec record) bool
+	// mayMatch reports whether any row of the row group can match, using the
+	// column chunk statistics; it returns true when the statistics can't tell
+	mayMatch(rowGroup parquet.RowGroup) bool
+}
+
+type andExpr struct{ left, right filterExpr }
+
+func (e andExpr) match(rec record) bool { return e.left.match(rec) && e.right.match(rec) }
+
+func (e andExpr) mayMatch(rg parquet.RowGroup) bool {
+	return e.left.mayMatch(rg) && e.right.mayMatch(rg)
+}
+
+type orExpr struct{ left, right filterExpr }
+
+func (e orExpr) match(rec record) bool { return e.left.match(rec) || e.right.match(rec) }
+
+func (e orExpr) mayMatch(rg parquet.RowGroup) bool {
+	return e.left.mayMatch(rg) || e.right.mayMatch(rg)
+}
+
+type notExpr struct{ expr filterExpr }
+
+func (e notExpr) match(rec record) bool { return !e.expr.match(rec) }
+
+// Statistics only bound the values that exist, so a negation can't be pruned
+func (e notExpr) mayMatch(parquet.RowGroup) bool { return true }
+
+// literal is a constant coerced to the kind of the column it is compared with
+type literal struct {
+	null bool
+	i    int64
+	f    float64
+	s    string
+	b    bool
+	t    time.Time
+}
+
+// compareExpr compares a column with a literal
+type compareExpr struct {
+	path   []string // column path as written, e.g. ["outputs", "address"]
+	leaf   []string // full parquet leaf path, e.g. ["outputs", "list", "element", "address"]
+	nested bool     // whether the column is inside a list
+	kind   valueKind
+	op     string
+	lit    literal
+}
+
+func (e compareExpr) match(rec record) bool {
+	for _, value := range lookupValues(rec, e.path) {
+		if e.matchValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue compares one row value with the literal
+func (e compareExpr) matchValue(value any) bool {
+	value = normalizeValue(value)
+	if e.lit.null || value == nil {
+		equal := e.lit.null == (value == nil)
+		switch e.op {
+		case "==":
+			return equal
+		case "!=":
+			return !equal
+		default:
+			return false
+		}
+	}
+	c, ok := e.compare(value)
+	if !ok {
+		return false
+	}
+	return compareResult(e.op, c)
+}
+
+// compare returns -1, 0 or 1 as value is less than, equal to or greater than the literal
+func (e compareExpr) compare(value any) (int, bool) {
+	switch v := value.(type) {
+	case int64:
+		if e.kind == kindInt {
+			return cmpOrdered(v, e.lit.i), true
+		}
+		return cmpOrdered(float64(v), e.lit.f), true
+	case float64:
+		return cmpOrdered(v, e.lit.f), true
+	case string:
+		return strings.Compare(v, e.lit.s), true
+	case bool:
+		return cmpOrdered(boolInt(v), boolInt(e.lit.b)), true
+	case time.Time:
+		return v.Compare(e.lit.t), true
+	default:
+		return 0, false
+	}
+}
+
+func (e compareExpr) mayMatch(rg parquet.RowGroup) bool {
+	// INT96 timestamps have no usable ordering and INT64 ones are stored in
+	// the column's unit, so only prune on plain values
+	if e.kind == kindTime {
+		return true
+	}
+	columnIndex, ok := lookupLeaf(rg.Schema(), e.leaf)
+	if !ok || columnIndex >= len(rg.ColumnChunks()) {
+		return true
+	}
+	chunk, ok := rg.ColumnChunks()[columnIndex].(*parquet.FileColumnChunk)
+	if !ok {
+		return true
+	}
+
+	nullCount := chunk.NullCount()
+	if e.lit.null {
+		// Nulls inside lists also count empty lists, so only prune flat columns
+		if e.op == "==" && !e.nested {
+			return nullCount > 0
+		}
+		return true
+	}
+	// Optional columns decode nulls as zero values, so a row group with nulls
+	// can match whenever the zero value does
+	if nullCount > 0 && e.matchValue(zeroValue(e.kind)) {
+		return true
+	}
+
+	minValue, maxValue, ok := chunk.Bounds()
+	if !ok {
+		return true
+	}
+	lo, ok1 := statValue(minValue)
+	hi, ok2 := statValue(maxValue)
+	if !ok1 || !ok2 {
+		return true
+	}
+	cmpLo, ok1 := e.compare(lo)
+	cmpHi, ok2 := e.compare(hi)
+	if !ok1 || !ok2 {
+		return true
+	}
+
+	switch e.op {
+	case "==":
+		return cmpLo <= 0 && cmpHi >= 0
+	case "!=":
+		return !(cmpLo == 0 && cmpHi == 0)
+	case "<":
+		return cmpLo < 0
+	case "<=":
+		return cmpLo <= 0
+	case ">":
+		return cmpHi > 0
+	case ">=":
+		return cmpHi >= 0
+	}
+	return true
+}
+
+// lookupValues returns the values of a column path; nested lists yield one
+// value per element
+func lookupValues(rec record, path []string) []any {
+	value, ok := rec.get(path[0])
+	if !ok {
+		return nil
+	}
+	rest := path[1:]
+	switch v := value.(type) {
+	case []record:
+		var values []any
+		for _, item := range v {
+			values = append(values, lookupValues(item, rest)...)
+		}
+		return values
+	case record:
+		return lookupValues(v, rest)
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		return values
+	default:
+		if len(rest) > 0 {
+			return nil
+		}
+		return []any{value}
+	}
+}
+
+// normalizeValue widens integers and floats so they compare as int64 and float64
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case float32:
+		return float64(v)
+	default:
+		return value
+	}
+}
+
+// statValue converts a column chunk min/max statistic into a comparable value
+func statValue(v parquet.Value) (any, bool) {
+	if v.IsNull() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case parquet.Boolean:
+		return v.Boolean(), true
+	case parquet.Int32:
+		return int64(v.Int32()), true
+	case parquet.Int64:
+		return v.Int64(), true
+	case parquet.Float:
+		return float64(v.Float()), true
+	case parquet.Double:
+		return v.Double(), true
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return string(v.ByteArray()), true
+	default:
+		return nil, false
+	}
+}
+
+func zeroValue(kind valueKind) any {
+	switch kind {
+	case kindInt:
+		return int64(0)
+	case kindFloat:
+		return float64(0)
+	case kindString:
+		return ""
+	case kindBool:
+		return false
+	default:
+		// Zero timestamps decode as null
+		return nil
+	}
+}
+
+func compareResult(op string, c int) bool {
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func cmpOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// resolveColumn finds the leaf node of a dotted column path in schema.
+// List wrapper groups (list/element) are skipped, so "outputs.address"
+// resolves to outputs.list.element.address.
+func resolveColumn(schema parquet.Node, path []string) (leaf []string, node parquet.Node, nested bool, ok bool) {
+	node = schema
+	for _, name := range path {
+		for {
+			if child := fieldByName(node, name); child != nil {
+				leaf = append(leaf, name)
+				nested = nested || child.Repeated()
+				node = child
+				break
+			}
+			wrapper, ok := listWrapper(node)
+			if !ok {
+				return nil, nil, false, false
+			}
+			leaf = append(leaf, wrapper.Name())
+			nested = true
+			node = wrapper
+		}
+	}
+	// A list of scalars (e.g. topics) compares its elements
+	for !node.Leaf() {
+		wrapper, ok := listWrapper(node)
+		if !ok {
+			return nil, nil, false, false
+		}
+		leaf = append(leaf, wrapper.Name())
+		nested = true
+		node = wrapper
+	}
+	return leaf, node, nested || node.Repeated(), true
+}
+
+func fieldByName(node parquet.Node, name string) parquet.Field {
+	if node.Leaf() {
+		return nil
+	}
+	for _, field := range node.Fields() {
+		if field.Name() == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// listWrapper returns the single child of a list group
+func listWrapper(node parquet.Node) (parquet.Field, bool) {
+	if node.Leaf() || len(node.Fields()) != 1 {
+		return nil, false
+	}
+	return node.Fields()[0], true
+}
+
+// lookupLeaf returns the index of the leaf column at path in a file schema
+func lookupLeaf(schema *parquet.Schema, path []string) (int, bool) {
+	if leaf, ok := schema.Lookup(path...); ok {
+		return leaf.ColumnIndex, true
+	}
+	// The file may name its list wrappers differently from the Go schema
+	names := make([]string, 0, len(path))
+	for _, name := range path {
+		if name != "list" && name != "element" {
+			names = append(names, name)
+		}
+	}
+	full, _, _, ok := resolveColumn(schema, names)
+	if !ok {
+		return 0, false
+	}
+	leaf, ok := schema.Lookup(full...)
+	return leaf.ColumnIndex, ok
+}
+
+// kindOf returns the comparison kind of a leaf column
+func kindOf(node parquet.Node) valueKind {
+	t := node.Type()
+	if lt := t.LogicalType(); lt != nil && lt.Timestamp != nil {
+		return kindTime
+	}
+	switch t.Kind() {
+	case parquet.Boolean:
+		return kindBool
+	case parquet.Int32, parquet.Int64:
+		return kindInt
+	case parquet.Int96:
+		return kindTime
+	case parquet.FixedLenByteArray:
+		// chain.Int96Timestamp is declared as fixed_len_byte_array(12) in the Go schema
+		if t.Length() == 12 {
+			return kindTime
+		}
+		return kindString
+	case parquet.Float, parquet.Double:
+		return kindFloat
+	default:
+		return kindString
+	}
+}
+
+// parseFilter compiles a filter expression against the columns of schema
+func parseFilter(input string, schema *parquet.Schema) (filterExpr, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens, schema: schema}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, fmt.Errorf("unexpected %q in filter", p.peek().text)
+	}
+	return expr, nil
+}
+
+type tokenType int
+
+const (
+	tokIdent tokenType = iota
+	tokNumber
+	tokString
+	tokOp
+	tokEOF
+)
+
+type token struct {
+	typ  tokenType
+	text string
+}
+
+// tokenize splits a filter expression into tokens
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := rune(input[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(input[i+1:], input[i])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in filter: %s", input[i:])
+			}
+			tokens = append(tokens, token{tokString, input[i+1 : i+1+end]})
+			i += end + 2
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(input) && unicode.IsDigit(rune(input[i+1]))):
+			j := i + 1
+			for j < len(input) && strings.ContainsRune("0123456789.eE+-", rune(input[j])) {
+				if (input[j] == '+' || input[j] == '-') && input[j-1] != 'e' && input[j-1] != 'E' {
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, token{tokNumber, input[i:j]})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(input) && (unicode.IsLetter(rune(input[j])) || unicode.IsDigit(rune(input[j])) || input[j] == '_' || input[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, input[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q in filter", c)
+			}
+			tokens = append(tokens, token{tokOp, op})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{typ: tokEOF}), nil
+}
+
+// filterParser is a recursive descent parser for filter expressions
+type filterParser struct {
+	tokens []token
+	pos    int
+	schema *parquet.Schema
+}
+
+func (p *filterParser) peek() token { return p.tokens[p.pos] }
+
+func (p *filterParser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) atEnd() bool { return p.peek().typ == tokEOF }
+
+func (p *filterParser) acceptOp(op string) bool {
+	if t := p.peek(); t.typ == tokOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.acceptOp("!") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	if p.acceptOp("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptOp(")") {
+			return nil, fmt.Errorf("missing ) in filter")
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterExpr, error) {
+	column := p.next()
+	if column.typ != tokIdent {
+		return nil, fmt.Errorf("expected column name in filter, got %q", column.text)
+	}
+	path := strings.Split(column.text, ".")
+	leaf, node, nested, ok := resolveColumn(p.schema, path)
+	if !ok {
+		return nil, fmt.Errorf("unknown column in filter: %s", column.text)
+	}
+
+	op := p.next()
+	if op.typ != tokOp || !isComparison(op.text) {
+		return nil, fmt.Errorf("expected comparison operator after %s, got %q", column.text, op.text)
+	}
+
+	kind := kindOf(node)
+	value := p.next()
+	lit, err := parseLiteral(value, kind)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", column.text, err)
+	}
+	if lit.null && op.text != "==" && op.text != "!=" {
+		return nil, fmt.Errorf("null can only be compared with == or != (column %s)", column.text)
+	}
+
+	return compareExpr{path: path, leaf: leaf, nested: nested, kind: kind, op: op.text, lit: lit}, nil
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// parseLiteral converts a literal token to the kind of the column it is compared with
+func parseLiteral(t token, kind valueKind) (literal, error) {
+	if t.typ == tokIdent && t.text == "null" {
+		return literal{null: true}, nil
+	}
+	switch kind {
+	case kindInt, kindFloat:
+		if t.typ != tokNumber {
+			return literal{}, fmt.Errorf("expected a %s, got %q", kind, t.text)
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return literal{}, fmt.Errorf("invalid number %q", t.text)
+		}
+		i, err := strconv.ParseInt(t.text, 10, 64)
+		if kind == kindInt && err != nil {
+			return literal{}, fmt.Errorf("expected an integer, got %q", t.text)
+		}
+		return literal{i: i, f: f}, nil
+	case kindString:
+		if t.typ != tokString {
+			return literal{}, fmt.Errorf("expected a quoted string, got %q", t.text)
+		}
+		return literal{s: t.text}, nil
+	case kindBool:
+		if t.typ != tokIdent || (t.text != "true" && t.text != "false") {
+			return literal{}, fmt.Errorf("expected true or false, got %q", t.text)
+		}
+		return literal{b: t.text == "true"}, nil
+	default:
+		if t.typ != tokString {
+			return literal{}, fmt.Errorf("expected a quoted timestamp, got %q", t.text)
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+			if ts, err := time.Parse(layout, t.text); err == nil {
+				return literal{t: ts}, nil
+			}
+		}
+		return literal{}, fmt.Errorf("invalid timestamp %q (expected YYYY-MM-DD or RFC 3339)", t.text)
+	}
+}
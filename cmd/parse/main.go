@@ -1,16 +1,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/parquet-go/parquet-go"
-	"github.com/siddon/web3insights/internal/chain"
+	"github.com/siddon/web3insights/internal/cache"
 	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
 )
 
 func main() {
@@ -19,6 +22,14 @@ func main() {
 		date       = flag.String("date", "", "Date to parse (YYYY-MM-DD format, e.g., 2009-01-03)")
 		startDate  = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
 		endDate    = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
+		chain      = flag.String("chain", "", "Blockchain to parse (default: from config, supports: "+strings.Join(registry.Names(), ", ")+")")
+		datasetArg = flag.String("dataset", "", "Only parse this dataset (e.g., blocks or transactions)")
+		format     = flag.String("format", "text", "Output format: text, json, jsonl, csv or table")
+		columnsArg = flag.String("columns", "", "Comma-separated columns to output (e.g., hash,fee; not supported with -format text)")
+		limit      = flag.Int64("limit", 0, "Maximum number of rows to output per dataset (0 means no limit)")
+		offset     = flag.Int64("offset", 0, "Number of rows to skip per dataset before output")
+		where      = flag.String("where", "", "Only output rows matching a filter, e.g. 'fee > 0.1 && is_coinbase == false'")
+		stats      = flag.Bool("stats", false, "Print schema, row group, column and size statistics per date instead of rows (-format text, json or jsonl)")
 	)
 	flag.Parse()
 
@@ -35,7 +46,73 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Override chain from command line if provided
+	if *chain != "" {
+		cfg.Chain = *chain
+	}
+	c, err := registry.Lookup(cfg.Chain)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	datasets := c.Datasets
+	if *datasetArg != "" {
+		dataset, ok := c.Dataset(*datasetArg)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown dataset %s for chain %s (supported: %s)\n", *datasetArg, c.Name, strings.Join(c.DatasetNames(), ", "))
+			os.Exit(1)
+		}
+		datasets = []registry.Dataset{dataset}
+	}
+
 	// Validate flags
+	if *stats {
+		if *columnsArg != "" || *where != "" || *limit != 0 || *offset != 0 {
+			fmt.Fprintf(os.Stderr, "Error: -stats cannot be combined with -columns, -where, -limit or -offset\n")
+			os.Exit(1)
+		}
+		if *format != "text" && *format != "json" && *format != "jsonl" {
+			fmt.Fprintf(os.Stderr, "Error: -stats supports -format text, json or jsonl\n")
+			os.Exit(1)
+		}
+	}
+	if *limit < 0 || *offset < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -limit and -offset must not be negative\n")
+		os.Exit(1)
+	}
+	if !*stats && len(datasets) > 1 && (*format == "json" || *format == "csv" || *format == "table") {
+		fmt.Fprintf(os.Stderr, "Error: -format %s requires -dataset (one of: %s)\n", *format, strings.Join(c.DatasetNames(), ", "))
+		os.Exit(1)
+	}
+	var columns []string
+	if *columnsArg != "" {
+		if *format == "text" {
+			fmt.Fprintf(os.Stderr, "Error: -columns requires -format json, jsonl, csv or table\n")
+			os.Exit(1)
+		}
+		if len(datasets) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: -columns requires -dataset (one of: %s)\n", strings.Join(c.DatasetNames(), ", "))
+			os.Exit(1)
+		}
+		columns, err = parseColumns(*columnsArg, datasets[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	var filter filterExpr
+	if *where != "" {
+		if len(datasets) > 1 {
+			fmt.Fprintf(os.Stderr, "Error: -where requires -dataset (one of: %s)\n", strings.Join(c.DatasetNames(), ", "))
+			os.Exit(1)
+		}
+		filter, err = parseFilter(*where, datasets[0].Schema)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	if *date != "" && (*startDate != "" || *endDate != "") {
 		fmt.Fprintf(os.Stderr, "Error: cannot specify both -date and -start/-end\n")
 		os.Exit(1)
@@ -89,149 +166,144 @@ func main() {
 		}
 	}
 
-	// Process each date
-	for _, dateStr := range dates {
-		fmt.Printf("\n=== Processing date: %s ===\n\n", dateStr)
+	// Rows go to stdout; in machine-readable formats progress messages go to stderr
+	// so the output can be piped into jq, spreadsheets or scripts
+	p := &parser{log: os.Stdout}
+	if *format != "text" {
+		p.log = os.Stderr
+	}
 
-		// Parse blocks
-		blocksDir := filepath.Join(cfg.OutDir, "btc", "blocks", dateStr)
-		if err := parseBlocks(blocksDir); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing blocks for date %s: %v\n", dateStr, err)
-			// Continue to transactions even if blocks fail
+	cacheManager := cache.NewManager(cfg)
+
+	if *stats {
+		var summaries []*datasetStats
+		for _, dateStr := range dates {
+			cacheManager.Touch(dateStr)
+			for _, dataset := range datasets {
+				summary, err := p.statsDataset(dateStr, c.DateDir(cfg, dataset.Name, dateStr), dataset)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading statistics of %s for date %s: %v\n", dataset.Name, dateStr, err)
+					continue
+				}
+				summaries = append(summaries, summary)
+			}
 		}
-
-		// Parse transactions
-		transactionsDir := filepath.Join(cfg.OutDir, "btc", "transactions", dateStr)
-		if err := parseTransactions(transactionsDir); err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing transactions for date %s: %v\n", dateStr, err)
-			// Continue to next date even if transactions fail
+		if err := writeStats(os.Stdout, *format, summaries); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write output: %v\n", err)
+			os.Exit(1)
 		}
-	}
-}
-
-func parseBlocks(blocksDir string) error {
-	// Check if directory exists
-	if _, err := os.Stat(blocksDir); os.IsNotExist(err) {
-		fmt.Printf("Blocks directory does not exist: %s\n", blocksDir)
-		return nil
+		return
 	}
 
-	return filepath.Walk(blocksDir, func(path string, info os.FileInfo, err error) error {
+	// Filter, offset and limit apply per dataset across all files and dates
+	sinks := make([]*rowSink, len(datasets))
+	for i := range datasets {
+		writer, err := newRowWriter(os.Stdout, *format, columns)
 		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if filepath.Ext(path) != ".parquet" {
-			return nil
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		sinks[i] = &rowSink{writer: writer, filter: filter, offset: *offset, limit: *limit}
+	}
 
-		// Check file size
-		if info.Size() == 0 {
-			fmt.Printf("Skipping empty file: %s\n", path)
-			return nil
+	// Process each date
+	for _, dateStr := range dates {
+		if allDone(sinks) {
+			break
 		}
+		fmt.Fprintf(p.log, "\n=== Processing date: %s ===\n\n", dateStr)
+		cacheManager.Touch(dateStr)
 
-		fmt.Printf("--- Parsing block file: %s ---\n", path)
-
-		// Use a recover to catch panics from parquet library
-		var readErr error
-		func() {
-			file, err := os.Open(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to open file %s: %v\n", path, err)
-				readErr = err
-				return
+		for i, dataset := range datasets {
+			if sinks[i].done() {
+				continue
 			}
-			defer file.Close()
-
-			// Get file info for size
-			fileInfo, err := file.Stat()
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to get file info: %v\n", err)
-				readErr = err
-				return
+			dir := c.DateDir(cfg, dataset.Name, dateStr)
+			if err := p.parseDataset(dir, dataset, sinks[i]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing %s for date %s: %v\n", dataset.Name, dateStr, err)
+				// Continue to the next dataset even if this one fails
 			}
+		}
+	}
 
-			// Build reader using SchemaOf
-			schema := parquet.SchemaOf(chain.BtcBlock{})
-			parquetFile, err := parquet.OpenFile(file, fileInfo.Size())
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to open parquet file: %v\n", err)
-				readErr = err
-				return
-			}
-			reader := parquet.NewGenericReader[chain.BtcBlock](parquetFile, schema)
-			defer reader.Close()
-
-			// Read and print all blocks
-			rowCount := 0
-			for {
-				rows := make([]chain.BtcBlock, 100) // Read in batches
-				n, err := reader.Read(rows)
-
-				if err != nil && err != io.EOF {
-					fmt.Fprintf(os.Stderr, "Failed to read parquet file %s: %v\n", path, err)
-					readErr = err
-					return
-				}
-
-				if n == 0 {
-					break
-				}
-
-				// Print each block
-				for i := 0; i < n; i++ {
-					fmt.Println(rows[i].String())
-					fmt.Println()
-					rowCount++
-				}
-			}
+	for _, sink := range sinks {
+		if err := sink.writer.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write output: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
 
-			if rowCount == 0 {
-				fmt.Printf("File %s contains no rows\n", path)
-			} else {
-				fmt.Printf("Successfully parsed %d blocks from %s\n", rowCount, path)
-			}
-		}()
+// parser reads parquet files and reports progress to log
+type parser struct {
+	log io.Writer
+}
 
-		if readErr != nil {
-			// Error already printed, just continue
-			return nil
+// allDone reports whether every dataset has reached its -limit
+func allDone(sinks []*rowSink) bool {
+	for _, sink := range sinks {
+		if !sink.done() {
+			return false
 		}
+	}
+	return true
+}
 
-		return nil
-	})
+// parseColumns splits a -columns list and checks each name against the dataset's columns
+func parseColumns(arg string, dataset registry.Dataset) ([]string, error) {
+	var names []string
+	available := make(map[string]bool)
+	for _, field := range dataset.Schema.Fields() {
+		names = append(names, field.Name())
+		available[field.Name()] = true
+	}
+	var columns []string
+	for _, name := range strings.Split(arg, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !available[name] {
+			return nil, fmt.Errorf("unknown column %s for %s (available: %s)", name, dataset.Name, strings.Join(names, ", "))
+		}
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given in -columns")
+	}
+	return columns, nil
 }
 
-func parseTransactions(transactionsDir string) error {
+// parseDataset writes every row of every parquet file in dir to sink
+func (p *parser) parseDataset(dir string, dataset registry.Dataset, sink *rowSink) error {
 	// Check if directory exists
-	if _, err := os.Stat(transactionsDir); os.IsNotExist(err) {
-		fmt.Printf("Transactions directory does not exist: %s\n", transactionsDir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		fmt.Fprintf(p.log, "%s directory does not exist: %s\n", dataset.Name, dir)
 		return nil
 	}
 
-	return filepath.Walk(transactionsDir, func(path string, info os.FileInfo, err error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
 			return nil
 		}
+		if sink.done() {
+			return filepath.SkipAll
+		}
 		if filepath.Ext(path) != ".parquet" {
 			return nil
 		}
 
 		// Check file size
 		if info.Size() == 0 {
-			fmt.Printf("Skipping empty file: %s\n", path)
+			fmt.Fprintf(p.log, "Skipping empty file: %s\n", path)
 			return nil
 		}
 
-		fmt.Printf("--- Parsing transaction file: %s ---\n", path)
+		fmt.Fprintf(p.log, "--- Parsing %s file: %s ---\n", dataset.Name, path)
 
-		// Use a recover to catch panics from parquet library
 		var readErr error
 		func() {
 			file, err := os.Open(path)
@@ -250,8 +322,6 @@ func parseTransactions(transactionsDir string) error {
 				return
 			}
 
-			// Build reader using SchemaOf
-			schema := parquet.SchemaOf(chain.BtcTransaction{})
 			parquetFile, err := parquet.OpenFile(file, fileInfo.Size())
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to open parquet file: %v\n", err)
@@ -259,38 +329,38 @@ func parseTransactions(transactionsDir string) error {
 				return
 			}
 
-			fmt.Printf("Schema: %s\n", schema.String())
-			reader := parquet.NewGenericReader[chain.BtcTransaction](parquetFile, schema)
-			defer reader.Close()
-
-			// Read and print all transactions
-			rowCount := 0
-			for {
-				rows := make([]chain.BtcTransaction, 100) // Read in batches
-				n, err := reader.Read(rows)
+			fmt.Fprintf(p.log, "Schema: %s\n", dataset.Schema.String())
 
-				if err != nil && err != io.EOF {
-					fmt.Fprintf(os.Stderr, "Failed to read parquet file %s: %v\n", path, err)
-					readErr = err
-					return
+			// Read all rows, skipping row groups whose statistics rule out the
+			// filter and stopping early once the limit is reached
+			rowCount, skipped := 0, 0
+			rowGroups := parquetFile.RowGroups()
+			for _, rowGroup := range rowGroups {
+				if sink.filter != nil && !sink.filter.mayMatch(rowGroup) {
+					skipped++
+					continue
 				}
-
-				if n == 0 {
-					break
-				}
-
-				// Print each transaction
-				for i := 0; i < n; i++ {
-					fmt.Println(rows[i].String())
-					fmt.Println()
+				err = dataset.Read(rowGroup, func(row any) error {
 					rowCount++
+					return sink.add(row)
+				})
+				if err != nil {
+					break
 				}
 			}
+			if skipped > 0 {
+				fmt.Fprintf(p.log, "Skipped %d of %d row groups using column statistics\n", skipped, len(rowGroups))
+			}
+			if err != nil && !errors.Is(err, errStop) {
+				fmt.Fprintf(os.Stderr, "Failed to read parquet file %s: %v\n", path, err)
+				readErr = err
+				return
+			}
 
 			if rowCount == 0 {
-				fmt.Printf("File %s contains no rows\n", path)
+				fmt.Fprintf(p.log, "File %s contains no rows\n", path)
 			} else {
-				fmt.Printf("Successfully parsed %d transactions from %s\n", rowCount, path)
+				fmt.Fprintf(p.log, "Successfully parsed %d %s from %s\n", rowCount, dataset.Name, path)
 			}
 		}()
 
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/siddon/web3insights/internal/chain"
+)
+
+// errStop is returned from a row callback to stop reading once -limit is reached
+var errStop = errors.New("stop reading")
+
+// field is one named column value of a decoded row
+type field struct {
+	name  string
+	value any
+}
+
+// record is a decoded row as ordered (column name, value) pairs.
+// Column names are the parquet column names; nested lists hold []record.
+type record []field
+
+// MarshalJSON keeps the parquet column order in JSON objects
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// get returns the value of a column and whether the column exists
+func (r record) get(name string) (any, bool) {
+	for _, f := range r {
+		if f.name == name {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// project keeps only the given columns, in the given order
+func (r record) project(columns []string) record {
+	if len(columns) == 0 {
+		return r
+	}
+	projected := make(record, 0, len(columns))
+	for _, name := range columns {
+		value, _ := r.get(name)
+		projected = append(projected, field{name: name, value: value})
+	}
+	return projected
+}
+
+func (r record) names() []string {
+	names := make([]string, len(r))
+	for i, f := range r {
+		names[i] = f.name
+	}
+	return names
+}
+
+var int96Type = reflect.TypeOf(chain.Int96Timestamp{})
+
+// toRecord converts a chain row struct into a record using its parquet tags
+func toRecord(row any) record {
+	v := reflect.ValueOf(row)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	return structRecord(v)
+}
+
+func structRecord(v reflect.Value) record {
+	t := v.Type()
+	rec := make(record, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := columnName(sf)
+		if name == "-" {
+			continue
+		}
+		rec = append(rec, field{name: name, value: plainValue(v.Field(i))})
+	}
+	return rec
+}
+
+// columnName returns the parquet column name of a struct field
+func columnName(sf reflect.StructField) string {
+	tag := sf.Tag.Get("parquet")
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return sf.Name
+}
+
+// plainValue converts a struct field into a JSON/CSV friendly value
+func plainValue(v reflect.Value) any {
+	if v.Type() == int96Type {
+		ts := v.Interface().(chain.Int96Timestamp).Time()
+		if ts.IsZero() {
+			return nil
+		}
+		return ts
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return structRecord(v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			items := make([]record, v.Len())
+			for i := range items {
+				items[i] = structRecord(v.Index(i))
+			}
+			return items
+		}
+		return v.Interface()
+	default:
+		return v.Interface()
+	}
+}
+
+// formatValue renders a value as a single CSV/table cell
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case record, []record, []string:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// rowWriter writes decoded rows in one output format
+type rowWriter interface {
+	Write(row any) error
+	Close() error
+}
+
+// newRowWriter creates a writer for format; columns selects and orders the
+// output columns (empty means all columns)
+func newRowWriter(w io.Writer, format string, columns []string) (rowWriter, error) {
+	switch format {
+	case "text":
+		return &textWriter{w: w}, nil
+	case "json":
+		return &jsonWriter{w: w, columns: columns}, nil
+	case "jsonl":
+		return &jsonlWriter{encoder: json.NewEncoder(w), columns: columns}, nil
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w), columns: columns}, nil
+	case "table":
+		return &tableWriter{w: tabwriter.NewWriter(w, 0, 0, 2, ' ', 0), columns: columns}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %s (expected text, json, jsonl, csv or table)", format)
+	}
+}
+
+// textWriter prints rows with their hand-written String() methods
+type textWriter struct {
+	w io.Writer
+}
+
+func (t *textWriter) Write(row any) error {
+	_, err := fmt.Fprintf(t.w, "%v\n\n", row)
+	return err
+}
+
+func (t *textWriter) Close() error { return nil }
+
+// jsonWriter writes a single JSON array of objects
+type jsonWriter struct {
+	w       io.Writer
+	columns []string
+	count   int
+}
+
+func (j *jsonWriter) Write(row any) error {
+	data, err := json.Marshal(toRecord(row).project(j.columns))
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if j.count == 0 {
+		sep = "[\n  "
+	}
+	j.count++
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	closing := "\n]\n"
+	if j.count == 0 {
+		closing = "[]\n"
+	}
+	_, err := io.WriteString(j.w, closing)
+	return err
+}
+
+// jsonlWriter writes one JSON object per line
+type jsonlWriter struct {
+	encoder *json.Encoder
+	columns []string
+}
+
+func (j *jsonlWriter) Write(row any) error {
+	return j.encoder.Encode(toRecord(row).project(j.columns))
+}
+
+func (j *jsonlWriter) Close() error { return nil }
+
+// csvWriter writes a header line followed by one line per row.
+// Nested lists are written as JSON in a single cell.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	header  bool
+}
+
+func (c *csvWriter) Write(row any) error {
+	rec := toRecord(row).project(c.columns)
+	if !c.header {
+		if err := c.w.Write(rec.names()); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	cells := make([]string, len(rec))
+	for i, f := range rec {
+		cells[i] = formatValue(f.value)
+	}
+	return c.w.Write(cells)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// tableWriter writes aligned columns for reading in a terminal
+type tableWriter struct {
+	w       *tabwriter.Writer
+	columns []string
+	header  bool
+}
+
+func (t *tableWriter) Write(row any) error {
+	rec := toRecord(row).project(t.columns)
+	if !t.header {
+		names := rec.names()
+		for i := range names {
+			names[i] = strings.ToUpper(names[i])
+		}
+		if _, err := fmt.Fprintln(t.w, strings.Join(names, "\t")); err != nil {
+			return err
+		}
+		t.header = true
+	}
+	cells := make([]string, len(rec))
+	for i, f := range rec {
+		cells[i] = strings.ReplaceAll(formatValue(f.value), "\t", " ")
+	}
+	_, err := fmt.Fprintln(t.w, strings.Join(cells, "\t"))
+	return err
+}
+
+func (t *tableWriter) Close() error {
+	return t.w.Flush()
+}
+
+// rowSink applies -where, -offset and -limit across all files of a dataset before writing
+type rowSink struct {
+	writer rowWriter
+	filter filterExpr // nil means every row matches
+	offset int64
+	limit  int64 // 0 means no limit
+	seen   int64
+	wrote  int64
+}
+
+// add writes row if it matches the filter and doesn't fall before the offset;
+// it returns errStop once the limit has been reached
+func (s *rowSink) add(row any) error {
+	if s.limit > 0 && s.wrote >= s.limit {
+		return errStop
+	}
+	if s.filter != nil && !s.filter.match(toRecord(row)) {
+		return nil
+	}
+	s.seen++
+	if s.seen <= s.offset {
+		return nil
+	}
+	if err := s.writer.Write(row); err != nil {
+		return err
+	}
+	s.wrote++
+	if s.limit > 0 && s.wrote >= s.limit {
+		return errStop
+	}
+	return nil
+}
+
+// done reports whether the limit has been reached
+func (s *rowSink) done() bool {
+	return s.limit > 0 && s.wrote >= s.limit
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/chain"
+	"github.com/siddon/web3insights/internal/registry"
+	chainschema "github.com/siddon/web3insights/internal/schema"
+)
+
+// maxStatWidth truncates long min/max values (hashes, scripts) in the column table
+const maxStatWidth = 24
+
+// datasetStats summarizes every parquet file of one dataset for one date
+type datasetStats struct {
+	Date              string           `json:"date"`
+	Dataset           string           `json:"dataset"`
+	Files             []fileStats      `json:"files"`
+	Rows              int64            `json:"rows"`
+	RowGroups         int              `json:"row_groups"`
+	CompressedBytes   int64            `json:"compressed_bytes"`
+	UncompressedBytes int64            `json:"uncompressed_bytes"`
+	Codecs            []string         `json:"codecs"`
+	Schema            string           `json:"schema"`
+	SchemaMismatch    []string         `json:"schema_mismatch,omitempty"` // Files whose schema differs from the first file
+	Drift             []string         `json:"drift,omitempty"`           // Differences from the Go row type's schema
+	Columns           []*columnStats   `json:"columns"`
+	Nested            []nestedCount    `json:"nested,omitempty"`
+	Timestamps        []timestampRange `json:"timestamps,omitempty"`
+
+	columns map[string]*columnStats
+	nested  map[string]*nestedCount
+	times   map[string]*timestampRange
+}
+
+// fileStats describes one parquet file
+type fileStats struct {
+	Path      string     `json:"path"`
+	Bytes     int64      `json:"bytes"`
+	Rows      int64      `json:"rows"`
+	RowGroups []rowGroup `json:"row_groups"`
+}
+
+// rowGroup describes one row group of a file
+type rowGroup struct {
+	Rows              int64 `json:"rows"`
+	CompressedBytes   int64 `json:"compressed_bytes"`
+	UncompressedBytes int64 `json:"uncompressed_bytes"`
+}
+
+// columnStats aggregates the column chunk metadata of one leaf column
+type columnStats struct {
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	Codecs            []string `json:"codecs"`
+	Values            int64    `json:"values"`
+	Nulls             int64    `json:"nulls"`
+	Min               string   `json:"min,omitempty"`
+	Max               string   `json:"max,omitempty"`
+	CompressedBytes   int64    `json:"compressed_bytes"`
+	UncompressedBytes int64    `json:"uncompressed_bytes"`
+
+	typ    parquet.Type
+	min    parquet.Value
+	max    parquet.Value
+	bounds bool
+}
+
+// nestedCount is the total number of elements of a list column (e.g. inputs)
+type nestedCount struct {
+	Column   string `json:"column"`
+	Elements int64  `json:"elements"`
+}
+
+// timestampRange is the range of a timestamp column decoded via chain.Int96Timestamp
+type timestampRange struct {
+	Column string    `json:"column"`
+	Min    time.Time `json:"min"`
+	Max    time.Time `json:"max"`
+}
+
+// statsDataset collects the statistics of every parquet file in dir
+func (p *parser) statsDataset(date, dir string, dataset registry.Dataset) (*datasetStats, error) {
+	stats := &datasetStats{
+		Date:    date,
+		Dataset: dataset.Name,
+		columns: make(map[string]*columnStats),
+		nested:  make(map[string]*nestedCount),
+		times:   make(map[string]*timestampRange),
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		fmt.Fprintf(p.log, "%s directory does not exist: %s\n", dataset.Name, dir)
+		return stats, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".parquet" || info.Size() == 0 {
+			return nil
+		}
+		if err := stats.addFile(path, info.Size(), dataset.Schema); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read statistics of %s: %v\n", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats.finish()
+	return stats, nil
+}
+
+// addFile merges the metadata of one parquet file into the summary
+func (s *datasetStats) addFile(path string, size int64, expected *parquet.Schema) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	parquetFile, err := parquet.OpenFile(file, size)
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	schema := parquetFile.Schema()
+	if s.Schema == "" {
+		s.Schema = schema.String()
+		for _, change := range chainschema.Diff(expected, schema) {
+			s.Drift = append(s.Drift, change.String())
+		}
+	} else if schema.String() != s.Schema {
+		s.SchemaMismatch = append(s.SchemaMismatch, path)
+	}
+
+	metadata := parquetFile.Metadata()
+	fs := fileStats{Path: path, Bytes: size, Rows: metadata.NumRows}
+	leafPaths := schema.Columns()
+	listLeaves := firstListLeaves(schema)
+
+	for i, rg := range parquetFile.RowGroups() {
+		meta := metadata.RowGroups[i]
+		var compressed int64
+		for _, column := range meta.Columns {
+			compressed += column.MetaData.TotalCompressedSize
+		}
+		fs.RowGroups = append(fs.RowGroups, rowGroup{
+			Rows:              meta.NumRows,
+			CompressedBytes:   compressed,
+			UncompressedBytes: meta.TotalByteSize,
+		})
+
+		for j, chunk := range rg.ColumnChunks() {
+			name := strings.Join(leafPaths[j], ".")
+			leaf, _ := schema.Lookup(leafPaths[j]...)
+			s.addChunk(name, leaf.Node.Type(), chunk, meta.Columns[j].MetaData.Codec.String(),
+				meta.Columns[j].MetaData.TotalCompressedSize, meta.Columns[j].MetaData.TotalUncompressedSize)
+
+			if list, ok := listLeaves[j]; ok {
+				if err := s.countElements(list.column, chunk, list.level); err != nil {
+					return fmt.Errorf("failed to count %s elements: %w", list.column, err)
+				}
+			}
+			if len(leafPaths[j]) == 1 && kindOf(leaf.Node) == kindTime {
+				if err := s.scanTimestamps(name, chunk); err != nil {
+					return fmt.Errorf("failed to read %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	s.Files = append(s.Files, fs)
+	s.Rows += fs.Rows
+	s.RowGroups += len(fs.RowGroups)
+	return nil
+}
+
+// addChunk merges one column chunk into the column's summary
+func (s *datasetStats) addChunk(name string, typ parquet.Type, chunk parquet.ColumnChunk, codec string, compressed, uncompressed int64) {
+	column, ok := s.columns[name]
+	if !ok {
+		column = &columnStats{Path: name, Type: typeName(typ), typ: typ}
+		s.columns[name] = column
+		s.Columns = append(s.Columns, column)
+	}
+	column.Values += chunk.NumValues()
+	column.CompressedBytes += compressed
+	column.UncompressedBytes += uncompressed
+	column.Codecs = appendUnique(column.Codecs, codec)
+
+	fileChunk, ok := chunk.(*parquet.FileColumnChunk)
+	if !ok {
+		return
+	}
+	column.Nulls += fileChunk.NullCount()
+	// INT96 statistics are not ordered by time; see the timestamp ranges instead
+	if typ.Kind() == parquet.Int96 {
+		return
+	}
+	minValue, maxValue, ok := fileChunk.Bounds()
+	if !ok {
+		return
+	}
+	if !column.bounds || typ.Compare(minValue, column.min) < 0 {
+		column.min = minValue.Clone()
+	}
+	if !column.bounds || typ.Compare(maxValue, column.max) > 0 {
+		column.max = maxValue.Clone()
+	}
+	column.bounds = true
+}
+
+// countElements adds the number of list elements in a chunk of the list's first
+// leaf column; an element exists where the definition level reaches the
+// repeated group's level, while lower levels mark null or empty lists
+func (s *datasetStats) countElements(name string, chunk parquet.ColumnChunk, level int) error {
+	count, ok := s.nested[name]
+	if !ok {
+		count = &nestedCount{Column: name}
+		s.nested[name] = count
+	}
+	return scanValues(chunk, func(v parquet.Value) {
+		if v.DefinitionLevel() >= level {
+			count.Elements++
+		}
+	})
+}
+
+// scanTimestamps extends the range of a timestamp column with the values of a chunk
+func (s *datasetStats) scanTimestamps(name string, chunk parquet.ColumnChunk) error {
+	r, ok := s.times[name]
+	if !ok {
+		r = &timestampRange{Column: name}
+		s.times[name] = r
+	}
+	return scanValues(chunk, func(v parquet.Value) {
+		t := chain.ValueTime(v, chunk.Type())
+		if t.IsZero() {
+			return
+		}
+		if r.Min.IsZero() || t.Before(r.Min) {
+			r.Min = t
+		}
+		if r.Max.IsZero() || t.After(r.Max) {
+			r.Max = t
+		}
+	})
+}
+
+// finish formats min/max values and collects totals once every file was added
+func (s *datasetStats) finish() {
+	for _, column := range s.Columns {
+		if column.bounds {
+			column.Min = formatStat(column.typ, column.min)
+			column.Max = formatStat(column.typ, column.max)
+		}
+		s.CompressedBytes += column.CompressedBytes
+		s.UncompressedBytes += column.UncompressedBytes
+		for _, codec := range column.Codecs {
+			s.Codecs = appendUnique(s.Codecs, codec)
+		}
+	}
+	for _, count := range s.nested {
+		s.Nested = append(s.Nested, *count)
+	}
+	sort.Slice(s.Nested, func(i, j int) bool { return s.Nested[i].Column < s.Nested[j].Column })
+	for _, r := range s.times {
+		if !r.Min.IsZero() {
+			s.Timestamps = append(s.Timestamps, *r)
+		}
+	}
+	sort.Slice(s.Timestamps, func(i, j int) bool { return s.Timestamps[i].Column < s.Timestamps[j].Column })
+}
+
+// listLeaf identifies the leaf column used to count the elements of a list
+type listLeaf struct {
+	column string // top-level list column, e.g. "inputs"
+	level  int    // definition level at which a list element exists
+}
+
+// firstListLeaves maps the first leaf column of each top-level list to its list
+func firstListLeaves(schema *parquet.Schema) map[int]listLeaf {
+	leaves := make(map[int]listLeaf)
+	seen := make(map[string]bool)
+	for i, path := range schema.Columns() {
+		if seen[path[0]] {
+			continue
+		}
+		level, node := 0, parquet.Node(schema)
+		for _, name := range path {
+			node = fieldByName(node, name)
+			if node.Optional() || node.Repeated() {
+				level++
+			}
+			if node.Repeated() {
+				leaves[i] = listLeaf{column: path[0], level: level}
+				seen[path[0]] = true
+				break
+			}
+		}
+	}
+	return leaves
+}
+
+// scanValues calls fn for every value of a column chunk, including nulls
+func scanValues(chunk parquet.ColumnChunk, fn func(parquet.Value)) error {
+	pages := chunk.Pages()
+	defer pages.Close()
+
+	values := make([]parquet.Value, 1024)
+	for {
+		page, err := pages.ReadPage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		reader := page.Values()
+		for {
+			n, err := reader.ReadValues(values)
+			for i := 0; i < n; i++ {
+				fn(values[i])
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				parquet.Release(page)
+				return err
+			}
+		}
+		parquet.Release(page)
+	}
+}
+
+// typeName describes a leaf column type, e.g. "binary (STRING)"
+func typeName(typ parquet.Type) string {
+	name := strings.ToLower(typ.Kind().String())
+	if typ.Kind() == parquet.FixedLenByteArray {
+		name = fmt.Sprintf("fixed_len_byte_array(%d)", typ.Length())
+	}
+	if lt := typ.LogicalType(); lt != nil {
+		name += " (" + lt.String() + ")"
+	}
+	return name
+}
+
+// formatStat renders a min/max statistic for display
+func formatStat(typ parquet.Type, v parquet.Value) string {
+	switch typ.Kind() {
+	case parquet.ByteArray:
+		if b := v.ByteArray(); utf8.Valid(b) {
+			return string(b)
+		}
+		return hex.EncodeToString(v.ByteArray())
+	case parquet.FixedLenByteArray:
+		return hex.EncodeToString(v.ByteArray())
+	default:
+		return v.String()
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// truncate shortens s to n runes for table output
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-3]) + "..."
+}
+
+// printStats writes a human-readable summary
+func printStats(w io.Writer, s *datasetStats) {
+	fmt.Fprintf(w, "=== %s %s ===\n", s.Dataset, s.Date)
+	if len(s.Files) == 0 {
+		fmt.Fprintf(w, "No files\n\n")
+		return
+	}
+	fmt.Fprintf(w, "Files: %d, Rows: %d, Row groups: %d\n", len(s.Files), s.Rows, s.RowGroups)
+	fmt.Fprintf(w, "Size: %s compressed, %s uncompressed, Codecs: %s\n",
+		formatBytes(s.CompressedBytes), formatBytes(s.UncompressedBytes), strings.Join(s.Codecs, ", "))
+	for _, count := range s.Nested {
+		fmt.Fprintf(w, "Nested %s: %d elements\n", count.Column, count.Elements)
+	}
+	for _, r := range s.Timestamps {
+		fmt.Fprintf(w, "Time range %s: %s .. %s\n", r.Column,
+			r.Min.Format("2006-01-02 15:04:05"), r.Max.Format("2006-01-02 15:04:05"))
+	}
+	for _, change := range s.Drift {
+		fmt.Fprintf(w, "Schema drift: %s\n", change)
+	}
+	for _, path := range s.SchemaMismatch {
+		fmt.Fprintf(w, "Warning: schema differs from the first file: %s\n", path)
+	}
+
+	fmt.Fprintf(w, "\nSchema: %s\n\n", s.Schema)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tROW GROUPS\tROWS\tSIZE\tROW GROUP ROWS")
+	for _, f := range s.Files {
+		rows := make([]string, len(f.RowGroups))
+		for i, rg := range f.RowGroups {
+			rows[i] = fmt.Sprint(rg.Rows)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", filepath.Base(f.Path), len(f.RowGroups), f.Rows,
+			formatBytes(f.Bytes), truncate(strings.Join(rows, ","), 40))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tTYPE\tCODEC\tVALUES\tNULLS\tMIN\tMAX\tCOMPRESSED\tUNCOMPRESSED")
+	for _, c := range s.Columns {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\n", c.Path, c.Type, strings.Join(c.Codecs, ","),
+			c.Values, c.Nulls, truncate(c.Min, maxStatWidth), truncate(c.Max, maxStatWidth),
+			formatBytes(c.CompressedBytes), formatBytes(c.UncompressedBytes))
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+// writeStats writes the summaries in the -format requested; json writes a
+// single array, jsonl one summary per line
+func writeStats(w io.Writer, format string, summaries []*datasetStats) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(summaries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case "jsonl":
+		encoder := json.NewEncoder(w)
+		for _, s := range summaries {
+			if err := encoder.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		for _, s := range summaries {
+			printStats(w, s)
+		}
+		return nil
+	}
+}
+
+// formatBytes formats a byte count in binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
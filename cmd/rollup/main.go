@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
+	"github.com/siddon/web3insights/internal/tidb"
+)
+
+func main() {
+	var (
+		configFile   = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
+		date         = flag.String("date", "", "Date to roll up (YYYY-MM-DD format, e.g., 2024-04-20)")
+		startDate    = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
+		endDate      = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
+		createTables = flag.Bool("create-tables", false, "Create the btc tables (CREATE TABLE IF NOT EXISTS) before rolling up")
+	)
+	flag.Parse()
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+	if *configFile != "" {
+		cfg, err = config.LoadFromPath(*configFile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The daily stats are computed from the btc tables
+	c, err := registry.Lookup("btc")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	profiles, err := tidb.ParseProfiles(cfg.ColumnProfiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Validate flags
+	if *date != "" && (*startDate != "" || *endDate != "") {
+		fmt.Fprintf(os.Stderr, "Error: cannot specify both -date and -start/-end\n")
+		os.Exit(1)
+	}
+	if *date == "" && (*startDate == "" || *endDate == "") {
+		fmt.Fprintf(os.Stderr, "Error: must specify either -date or both -start and -end\n")
+		os.Exit(1)
+	}
+
+	start, end := *startDate, *endDate
+	if *date != "" {
+		start, end = *date, *date
+	}
+	startTime, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid start date: %s (expected YYYY-MM-DD)\n", start)
+		os.Exit(1)
+	}
+	endTime, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid end date: %s (expected YYYY-MM-DD)\n", end)
+		os.Exit(1)
+	}
+	if endTime.Before(startTime) {
+		fmt.Fprintf(os.Stderr, "Error: end date must be after or equal to start date\n")
+		os.Exit(1)
+	}
+
+	// Open database connection
+	db, err := tidb.OpenSQL(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to TiDB: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if *createTables {
+		if err := tidb.CreateTables(db, c.SchemaDDL(profiles)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s tables: %v\n", c.Name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %s tables\n", c.Name)
+	}
+	// Tables written after every date exist even if -create-tables predates them
+	if err := tidb.CreateTables(db, c.DeriveDDL(profiles)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s derived tables: %v\n", c.Name, err)
+		os.Exit(1)
+	}
+
+	days := 0
+	for current := startTime; !current.After(endTime); current = current.AddDate(0, 0, 1) {
+		dateStr := current.Format("2006-01-02")
+		if err := tidb.RollupBtcDate(db, dateStr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error rolling up date %s: %v\n", dateStr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rolled up %s\n", dateStr)
+		days++
+	}
+
+	fmt.Printf("\nRolled up %d dates into btc_daily_stats\n", days)
+}
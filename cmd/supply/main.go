@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/consensus"
+	"github.com/siddon/web3insights/internal/tidb"
+)
+
+func main() {
+	var (
+		configFile = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
+		height     = flag.Int64("height", -1, "Block to report the supply after (default: the latest tracked block)")
+	)
+	flag.Parse()
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+	if *configFile != "" {
+		cfg, err = config.LoadFromPath(*configFile)
+	} else {
+		cfg, err = config.Load()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Open database connection
+	db, err := tidb.OpenSQL(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to TiDB: %v\n", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	var supply *tidb.Supply
+	if *height >= 0 {
+		supply, err = tidb.SupplyAt(db, *height)
+	} else {
+		supply, err = tidb.LatestSupply(db)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if supply == nil {
+		if *height < 0 {
+			fmt.Fprintf(os.Stderr, "Error: btc_supply is empty (enable the supply analyzer and sync)\n")
+			os.Exit(1)
+		}
+		fmt.Printf("Block %d is not tracked in btc_supply\n", *height)
+		fmt.Printf("The halving schedule issues %s BTC through it\n", tidb.FormatSats(consensus.ExpectedSupply(*height)))
+		return
+	}
+
+	fmt.Printf("Block %d (%s, %s)\n", supply.BlockNumber, supply.RecordDate.Format("2006-01-02"), supply.BlockHash)
+	fmt.Printf("  Circulating supply: %s BTC\n", tidb.FormatSats(supply.Supply))
+	fmt.Printf("  Schedule supply:    %s BTC\n", tidb.FormatSats(supply.ExpectedSupply))
+	fmt.Printf("  Never issued:       %s BTC\n", tidb.FormatSats(supply.ExpectedSupply-supply.Supply))
+	fmt.Printf("  Block subsidy:      %s BTC due, %s BTC claimed, %s BTC fees\n",
+		tidb.FormatSats(supply.ExpectedSubsidy), tidb.FormatSats(supply.ClaimedSubsidy), tidb.FormatSats(supply.Fees))
+	if supply.Unclaimed > 0 {
+		fmt.Printf("  Under-claimed:      %s BTC\n", tidb.FormatSats(supply.Unclaimed))
+	}
+	if !supply.Exact {
+		fmt.Println("  Note: blocks before the first tracked one are assumed to claim their full subsidy")
+	}
+}
@@ -2,25 +2,33 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/siddon/web3insights/internal/awsdata"
+	"github.com/siddon/web3insights/internal/cache"
 	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
+	"github.com/siddon/web3insights/internal/schema"
 	"github.com/siddon/web3insights/internal/sync"
 	"github.com/siddon/web3insights/internal/tidb"
 )
 
 func main() {
 	var (
-		configFile = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
-		date       = flag.String("date", "", "Date to sync (YYYY-MM-DD format, e.g., 2009-01-03)")
-		startDate  = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
-		endDate    = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
-		_          = flag.Bool("latest", false, "Sync today's date (uses current date in UTC)")
+		configFile   = flag.String("config", "", "Path to config file (default: .config or value from WEB3INSIGHTS_CONFIG env var)")
+		date         = flag.String("date", "", "Date to sync (YYYY-MM-DD format, e.g., 2009-01-03)")
+		startDate    = flag.String("start", "", "Start date for date range (YYYY-MM-DD format)")
+		endDate      = flag.String("end", "", "End date for date range (YYYY-MM-DD format, inclusive)")
+		_            = flag.Bool("latest", false, "Sync today's date (uses current date in UTC)")
+		chain        = flag.String("chain", "", "Blockchain to sync (default: from config, supports: "+strings.Join(registry.Names(), ", ")+")")
+		createTables = flag.Bool("create-tables", false, "Create the chain's tables (CREATE TABLE IF NOT EXISTS) before syncing")
+		printSchema  = flag.Bool("print-schema", false, "Print the chain's CREATE TABLE statements for the configured column profiles and exit")
+		allowDrift   = flag.String("allow-drift", "", "Schema drift to accept instead of failing: comma-separated added, missing, retyped, or all (default: from config)")
 	)
 	flag.Parse()
 
@@ -45,6 +53,44 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Override chain from command line if provided
+	if *chain != "" {
+		cfg.Chain = *chain
+	}
+
+	c, err := registry.Lookup(cfg.Chain)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *allowDrift != "" {
+		cfg.AllowSchemaDrift = *allowDrift
+	}
+	allowedDrift, err := schema.ParseAllowed(cfg.AllowSchemaDrift)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	profiles, err := tidb.ParseProfiles(cfg.ColumnProfiles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := tidb.ParseDeriveAddresses(cfg.DeriveAddresses); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := tidb.ParseAnalyzers(cfg.Analyzers); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *printSchema {
+		fmt.Print(c.SchemaDDL(profiles))
+		return
+	}
+
 	// Handle -latest flag: use today's date
 	if latestSet {
 		today := time.Now().UTC().Format("2006-01-02")
@@ -74,10 +120,20 @@ func main() {
 	}
 	defer db.Close()
 
-	ctx := context.Background()
+	if *createTables {
+		if err := tidb.CreateTables(db, c.SchemaDDL(profiles)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating %s tables: %v\n", c.Name, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created %s tables\n", c.Name)
+	}
+	// Tables written after every date exist even if -create-tables predates them
+	if err := tidb.CreateTables(db, c.DeriveDDL(profiles)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s derived tables: %v\n", c.Name, err)
+		os.Exit(1)
+	}
 
-	// Save interval for status updates (save every N batches)
-	const saveInterval = 10
+	ctx := context.Background()
 
 	// Build list of dates to process
 	var dates []string
@@ -119,142 +175,113 @@ func main() {
 		}
 	}
 
+	cacheManager := cache.NewManager(cfg)
+
 	// Process each date: download if needed, then load
 	for _, dateStr := range dates {
 		fmt.Printf("\n--- Processing date: %s ---\n", dateStr)
 
-		// Download files if needed (DownloadBTC checks if files exist)
-		if err := awsdata.DownloadBTC(ctx, cfg, dateStr); err != nil {
+		// Download files if needed (downloads skip files that already exist)
+		if err := c.Download(ctx, cfg, dateStr); err != nil {
 			fmt.Fprintf(os.Stderr, "Error downloading data for date %s: %v\n", dateStr, err)
 			os.Exit(1)
 		}
 
-		// Load all block files for this date
-		blocksDir := filepath.Join(cfg.OutDir, "btc", "blocks", dateStr)
-		fmt.Printf("Loading blocks for date %s...\n", dateStr)
-		err = filepath.Walk(blocksDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-			if filepath.Ext(path) != ".parquet" {
-				return nil
+		for _, dataset := range c.Datasets {
+			dir := c.DateDir(cfg, dataset.Name, dateStr)
+			fmt.Printf("Loading %s for date %s...\n", dataset.Name, dateStr)
+			if err := syncDatasetDir(db, cfg, dir, dataset, allowedDrift); err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading %s for date %s: %v\n", dataset.Name, dateStr, err)
+				os.Exit(1)
 			}
-
-			// Load status for this specific file
-			statusPath := sync.GetStatusPathForFile(path)
-			fileStatus, err := sync.LoadStatus(statusPath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to load status for %s: %v\n", path, err)
-				fileStatus = &sync.Status{}
+		}
+		if c.Derive != nil {
+			dir := func(dataset string) string { return c.DateDir(cfg, dataset, dateStr) }
+			if err := c.Derive(db, cfg, dir, dateStr); err != nil {
+				fmt.Fprintf(os.Stderr, "Error deriving tables for date %s: %v\n", dateStr, err)
+				os.Exit(1)
 			}
+		}
 
-			// Check if file is already fully processed
-			if fileStatus.IsComplete() {
-				fmt.Printf("Skipping already completed block file: %s (%d/%d rows)\n", path, fileStatus.LastRow, fileStatus.NumRows)
-				return nil
-			}
+		// Keep the local cache within budget now that this date is synced
+		cacheManager.Touch(dateStr)
+		if _, err := cacheManager.Enforce(dateStr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to prune cache: %v\n", err)
+		}
+	}
 
-			startRow := fileStatus.LastRow
-			if startRow > 0 {
-				fmt.Printf("Resuming block file: %s from row %d\n", path, startRow)
-			} else {
-				fmt.Printf("Loading block file: %s\n", path)
-			}
+	fmt.Println("\nSuccessfully synced all dates to TiDB")
+}
 
-			// Track batch count for save interval
-			batchCount := 0
-			onProgress := func(filePath string, row int64, numRows int64) error {
-				fileStatus.LastRow = row
-				fileStatus.NumRows = numRows
-				batchCount++
-				// Save status every N batches or at the end
-				if batchCount%saveInterval == 0 {
-					return sync.SaveStatus(statusPath, fileStatus)
-				}
-				return nil
-			}
-			if err := tidb.LoadBtcBlocksWithProgressAndRow(db, path, cfg, onProgress, startRow); err != nil {
-				return err
-			}
-			// Final save after file completion
-			if err := sync.SaveStatus(statusPath, fileStatus); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save status for %s: %v\n", path, err)
-			}
+// Save interval for status updates (save every N batches)
+const saveInterval = 10
 
-			return nil
-		})
+// syncDatasetDir loads every parquet file in dir, resuming from and updating
+// the per-file sync status. Files whose schema drifted from the dataset's Go
+// row type fail the sync unless the drift kind is allowed.
+func syncDatasetDir(db *sql.DB, cfg *config.Config, dir string, dataset registry.Dataset, allowedDrift []schema.ChangeKind) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading blocks for date %s: %v\n", dateStr, err)
-			os.Exit(1)
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if filepath.Ext(path) != ".parquet" {
+			return nil
 		}
 
-		// Load all transaction files for this date
-		transactionsDir := filepath.Join(cfg.OutDir, "btc", "transactions", dateStr)
-		fmt.Printf("Loading transactions for date %s...\n", dateStr)
-		err = filepath.Walk(transactionsDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if info.IsDir() {
-				return nil
-			}
-			if filepath.Ext(path) != ".parquet" {
-				return nil
-			}
+		// Load status for this specific file
+		statusPath := sync.GetStatusPathForFile(path)
+		fileStatus, err := sync.LoadStatus(statusPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to load status for %s: %v\n", path, err)
+			fileStatus = &sync.Status{}
+		}
 
-			// Load status for this specific file
-			statusPath := sync.GetStatusPathForFile(path)
-			fileStatus, err := sync.LoadStatus(statusPath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to load status for %s: %v\n", path, err)
-				fileStatus = &sync.Status{}
-			}
+		// Check if file is already fully processed
+		if fileStatus.IsComplete() {
+			fmt.Printf("Skipping already completed %s file: %s (%d/%d rows)\n", dataset.Name, path, fileStatus.LastRow, fileStatus.NumRows)
+			return nil
+		}
 
-			// Check if file is already fully processed
-			if fileStatus.IsComplete() {
-				fmt.Printf("Skipping already completed transaction file: %s (%d/%d rows)\n", path, fileStatus.LastRow, fileStatus.NumRows)
-				return nil
-			}
+		changes, err := schema.CheckFile(path, dataset.Schema, allowedDrift)
+		if err != nil {
+			return fmt.Errorf("%w\nuse -allow-drift or allow_schema_drift to load it anyway", err)
+		}
+		for _, change := range changes {
+			fmt.Fprintf(os.Stderr, "Warning: %s in %s (allowed)\n", change, path)
+		}
 
-			startRow := fileStatus.LastRow
-			if startRow > 0 {
-				fmt.Printf("Resuming transaction file: %s from row %d\n", path, startRow)
-			} else {
-				fmt.Printf("Loading transaction file: %s\n", path)
-			}
+		startRow := fileStatus.LastRow
+		if startRow > 0 {
+			fmt.Printf("Resuming %s file: %s from row %d\n", dataset.Name, path, startRow)
+		} else {
+			fmt.Printf("Loading %s file: %s\n", dataset.Name, path)
+		}
 
-			// Track batch count for save interval
-			batchCount := 0
-			onProgress := func(filePath string, row int64, numRows int64) error {
-				fileStatus.LastRow = row
-				fileStatus.NumRows = numRows
-				batchCount++
-				// Save status every N batches or at the end
-				if batchCount%saveInterval == 0 {
-					return sync.SaveStatus(statusPath, fileStatus)
-				}
-				return nil
-			}
-			if err := tidb.LoadBtcTransactionsWithProgressAndRow(db, path, cfg, onProgress, startRow); err != nil {
-				return err
+		// Track batch count for save interval
+		batchCount := 0
+		onProgress := func(filePath string, row int64, numRows int64) error {
+			fileStatus.LastRow = row
+			fileStatus.NumRows = numRows
+			batchCount++
+			// Save status every N batches or at the end
+			if batchCount%saveInterval == 0 {
+				return sync.SaveStatus(statusPath, fileStatus)
 			}
-			// Final save after file completion
-			if err := sync.SaveStatus(statusPath, fileStatus); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save status for %s: %v\n", path, err)
-			}
-
 			return nil
-		})
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading transactions for date %s: %v\n", dateStr, err)
-			os.Exit(1)
 		}
-	}
+		if err := dataset.Load(db, path, cfg, onProgress, startRow); err != nil {
+			return err
+		}
+		// Final save after file completion
+		if err := sync.SaveStatus(statusPath, fileStatus); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to save status for %s: %v\n", path, err)
+		}
 
-	fmt.Println("\nSuccessfully synced all dates to TiDB")
+		return nil
+	})
 }
 
 // validateDate validates the date format (YYYY-MM-DD)
@@ -8,6 +8,7 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.92.1
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/parquet-go/parquet-go v0.25.1
+	golang.org/x/crypto v0.24.0
 )
 
 require (
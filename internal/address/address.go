@@ -0,0 +1,163 @@
+// Package address derives Bitcoin addresses from output scripts and validates
+// addresses.
+//
+// P2PKH and P2SH addresses use base58check; segwit addresses use bech32 for
+// witness version 0 and bech32m for version 1 and later. P2PK outputs and the
+// keys of bare multisig outputs are reported as the P2PKH address of each key.
+package address
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/siddon/web3insights/internal/script"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Network holds the address encodings of a Bitcoin network
+type Network struct {
+	Name              string
+	PubKeyHashVersion byte   // Base58check version byte of P2PKH addresses
+	ScriptHashVersion byte   // Base58check version byte of P2SH addresses
+	HRP               string // Human-readable part of segwit addresses
+}
+
+// Networks whose addresses can be derived and validated. Signet shares the
+// testnet encodings.
+var (
+	Mainnet = Network{Name: "mainnet", PubKeyHashVersion: 0x00, ScriptHashVersion: 0x05, HRP: "bc"}
+	Testnet = Network{Name: "testnet", PubKeyHashVersion: 0x6f, ScriptHashVersion: 0xc4, HRP: "tb"}
+)
+
+var networks = []Network{Mainnet, Testnet}
+
+// Address is a decoded address
+type Address struct {
+	Network        Network
+	Class          script.Class // PubKeyHash, ScriptHash or one of the witness classes
+	Hash           []byte       // Pubkey hash, script hash or witness program
+	WitnessVersion int          // -1 for base58 addresses
+}
+
+// String returns the canonical encoding of the address
+func (a Address) String() string {
+	switch a.Class {
+	case script.PubKeyHash:
+		return base58CheckEncode(a.Network.PubKeyHashVersion, a.Hash)
+	case script.ScriptHash:
+		return base58CheckEncode(a.Network.ScriptHashVersion, a.Hash)
+	default:
+		return encodeSegwit(a.Network.HRP, a.WitnessVersion, a.Hash)
+	}
+}
+
+// Decode parses a base58check or segwit address of any known network
+func Decode(addr string) (Address, error) {
+	for _, network := range networks {
+		if strings.HasPrefix(strings.ToLower(addr), network.HRP+"1") {
+			return decodeSegwitAddress(addr, network)
+		}
+	}
+
+	version, payload, err := base58CheckDecode(addr)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid address %s: %w", addr, err)
+	}
+	if len(payload) != 20 {
+		return Address{}, fmt.Errorf("invalid address %s: payload is %d bytes, expected 20", addr, len(payload))
+	}
+	for _, network := range networks {
+		switch version {
+		case network.PubKeyHashVersion:
+			return Address{Network: network, Class: script.PubKeyHash, Hash: payload, WitnessVersion: -1}, nil
+		case network.ScriptHashVersion:
+			return Address{Network: network, Class: script.ScriptHash, Hash: payload, WitnessVersion: -1}, nil
+		}
+	}
+	return Address{}, fmt.Errorf("invalid address %s: unknown version byte 0x%02x", addr, version)
+}
+
+func decodeSegwitAddress(addr string, network Network) (Address, error) {
+	hrp, version, program, err := decodeSegwit(addr)
+	if err != nil {
+		return Address{}, fmt.Errorf("invalid address %s: %w", addr, err)
+	}
+	if hrp != network.HRP {
+		return Address{}, fmt.Errorf("invalid address %s: unknown prefix %s", addr, hrp)
+	}
+	return Address{Network: network, Class: witnessClass(version, program), Hash: program, WitnessVersion: version}, nil
+}
+
+// Validate checks that addr is a well-formed address of network
+func Validate(addr string, network Network) error {
+	decoded, err := Decode(addr)
+	if err != nil {
+		return err
+	}
+	if decoded.Network.Name != network.Name {
+		return fmt.Errorf("invalid address %s: %s address, expected %s", addr, decoded.Network.Name, network.Name)
+	}
+	return nil
+}
+
+// Canonical returns the canonical encoding of a valid address, e.g. a
+// lowercase bech32 string
+func Canonical(addr string) (string, error) {
+	decoded, err := Decode(addr)
+	if err != nil {
+		return "", err
+	}
+	return decoded.String(), nil
+}
+
+// FromTemplate returns the addresses a classified script pays to on network:
+// one for P2PK, P2PKH, P2SH and witness scripts, one per key for bare multisig,
+// and none for OP_RETURN and non-standard scripts
+func FromTemplate(t script.Template, network Network) []string {
+	switch t.Class {
+	case script.PubKeyHash, script.ScriptHash:
+		return []string{Address{Network: network, Class: t.Class, Hash: t.Hash, WitnessVersion: -1}.String()}
+	case script.WitnessV0KeyHash, script.WitnessV0ScriptHash, script.WitnessV1Taproot, script.WitnessUnknown:
+		return []string{Address{Network: network, Class: t.Class, Hash: t.WitnessProgram, WitnessVersion: t.WitnessVersion}.String()}
+	case script.PubKey, script.MultiSig:
+		addresses := make([]string, len(t.PubKeys))
+		for i, key := range t.PubKeys {
+			addresses[i] = Address{Network: network, Class: script.PubKeyHash, Hash: Hash160(key), WitnessVersion: -1}.String()
+		}
+		return addresses
+	}
+	return nil
+}
+
+// FromScriptHex classifies script_hex and returns the addresses it pays to
+func FromScriptHex(scriptHex string, network Network) ([]string, error) {
+	if strings.TrimSpace(scriptHex) == "" {
+		return nil, nil
+	}
+	t, err := script.ClassifyHex(scriptHex)
+	if err != nil {
+		return nil, err
+	}
+	return FromTemplate(t, network), nil
+}
+
+// Hash160 returns RIPEMD160(SHA256(data)), the hash of P2PKH and P2SH addresses
+func Hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
+
+func witnessClass(version int, program []byte) script.Class {
+	switch {
+	case version == 0 && len(program) == 20:
+		return script.WitnessV0KeyHash
+	case version == 0:
+		return script.WitnessV0ScriptHash
+	case version == 1 && len(program) == 32:
+		return script.WitnessV1Taproot
+	}
+	return script.WitnessUnknown
+}
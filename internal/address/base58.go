@@ -0,0 +1,80 @@
+package address
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Index = func() [256]int {
+	var index [256]int
+	for i := range index {
+		index[i] = -1
+	}
+	for i, c := range base58Alphabet {
+		index[c] = i
+	}
+	return index
+}()
+
+// base58CheckEncode encodes a version byte and payload with a 4-byte double-SHA256 checksum
+func base58CheckEncode(version byte, payload []byte) string {
+	data := append([]byte{version}, payload...)
+	data = append(data, checksum(data)...)
+
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// Each leading zero byte is written as '1'
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58CheckDecode returns the version byte and payload of a base58check string
+func base58CheckDecode(s string) (byte, []byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for i := 0; i < len(s); i++ {
+		digit := base58Index[s[i]]
+		if digit < 0 {
+			return 0, nil, errors.New("invalid base58 character")
+		}
+		if digit == 0 && zeros == i {
+			zeros++
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+	data := append(make([]byte, zeros), n.Bytes()...)
+	if len(data) < 5 {
+		return 0, nil, errors.New("base58 string too short")
+	}
+	body, sum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum(body), sum) {
+		return 0, nil, errors.New("invalid base58 checksum")
+	}
+	return body[0], body[1:], nil
+}
+
+func checksum(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
@@ -0,0 +1,145 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Checksum constants of BIP-173 (bech32, witness v0) and BIP-350 (bech32m, v1+)
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// encodeSegwit encodes a witness program as a bech32 (v0) or bech32m (v1+) address
+func encodeSegwit(hrp string, version int, program []byte) string {
+	data := append([]byte{byte(version)}, convertBits(program, 8, 5, true)...)
+	constant := uint32(bech32Const)
+	if version > 0 {
+		constant = bech32mConst
+	}
+	values := append(hrpExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ constant
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteByte('1')
+	for _, d := range data {
+		b.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>(5*(5-i)))&31])
+	}
+	return b.String()
+}
+
+// decodeSegwit returns the human-readable part, witness version and program of
+// a segwit address, checking the BIP-173/BIP-350 rules
+func decodeSegwit(addr string) (string, int, []byte, error) {
+	if len(addr) > 90 {
+		return "", 0, nil, errors.New("bech32 string too long")
+	}
+	if strings.ToLower(addr) != addr && strings.ToUpper(addr) != addr {
+		return "", 0, nil, errors.New("bech32 string has mixed case")
+	}
+	addr = strings.ToLower(addr)
+	sep := strings.LastIndexByte(addr, '1')
+	if sep < 1 || sep+7 > len(addr) {
+		return "", 0, nil, errors.New("invalid bech32 separator position")
+	}
+	hrp := addr[:sep]
+	data := make([]byte, 0, len(addr)-sep-1)
+	for i := sep + 1; i < len(addr); i++ {
+		d := strings.IndexByte(bech32Charset, addr[i])
+		if d < 0 {
+			return "", 0, nil, fmt.Errorf("invalid bech32 character %q", addr[i])
+		}
+		data = append(data, byte(d))
+	}
+
+	polymod := bech32Polymod(append(hrpExpand(hrp), data...))
+	data = data[:len(data)-6]
+	if len(data) == 0 {
+		return "", 0, nil, errors.New("missing witness version")
+	}
+	version := int(data[0])
+	switch {
+	case version > 16:
+		return "", 0, nil, fmt.Errorf("invalid witness version %d", version)
+	case version == 0 && polymod != bech32Const, version > 0 && polymod != bech32mConst:
+		return "", 0, nil, errors.New("invalid bech32 checksum")
+	}
+	program, err := regroupBits(data[1:], 5, 8)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return "", 0, nil, fmt.Errorf("invalid witness program length %d", len(program))
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return "", 0, nil, fmt.Errorf("invalid witness v0 program length %d", len(program))
+	}
+	return hrp, version, program, nil
+}
+
+// convertBits regroups bits from one word size to another, padding the last word
+func convertBits(data []byte, from, to uint, pad bool) []byte {
+	var out []byte
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad && bits > 0 {
+		out = append(out, byte(acc<<(to-bits)&maxv))
+	}
+	return out
+}
+
+// regroupBits converts 5-bit words back to bytes, rejecting non-zero padding
+func regroupBits(data []byte, from, to uint) ([]byte, error) {
+	out := convertBits(data, from, to, false)
+	bits := uint(len(data)) * from % to
+	if bits >= from {
+		return nil, errors.New("invalid bech32 padding")
+	}
+	if len(data) > 0 && data[len(data)-1]&(1<<bits-1) != 0 {
+		return nil, errors.New("non-zero bech32 padding")
+	}
+	return out, nil
+}
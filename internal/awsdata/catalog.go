@@ -0,0 +1,201 @@
+package awsdata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/siddon/web3insights/internal/config"
+)
+
+// DatasetStats summarizes the parquet objects of one dataset for one date
+type DatasetStats struct {
+	Objects int   `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// CatalogEntry describes what exists upstream for a single date
+type CatalogEntry struct {
+	Date     string                  `json:"date"`
+	Datasets map[string]DatasetStats `json:"datasets"`
+	Missing  []string                `json:"missing,omitempty"` // Datasets with no parquet objects for this date
+}
+
+// ListCatalog lists the dates available upstream for every dataset under prefix
+// together with object counts and sizes. If start and end are empty the full
+// history is listed. Every date between the first and last listed date gets an
+// entry, so gaps in the upstream dataset show up with Missing filled in.
+func ListCatalog(ctx context.Context, cfg *config.Config, prefix string, datasets []string, start, end string) ([]CatalogEntry, error) {
+	s3Client, err := newS3Client(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	shaper := getShaper(cfg)
+
+	byDate := make(map[string]*CatalogEntry)
+	for _, dataset := range datasets {
+		datasetPrefix := fmt.Sprintf("%s%s/", prefix, dataset)
+
+		// Discover date partitions first using the delimiter, then size them
+		dates, err := listDatePartitions(ctx, s3Client, cfg, shaper, datasetPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s partitions: %w", dataset, err)
+		}
+		dates = filterDates(dates, start, end)
+		if len(dates) == 0 {
+			continue
+		}
+
+		stats, err := listDateObjects(ctx, s3Client, cfg, shaper, datasetPrefix, dates[0], dates[len(dates)-1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s objects: %w", dataset, err)
+		}
+
+		for _, date := range dates {
+			entry, ok := byDate[date]
+			if !ok {
+				entry = &CatalogEntry{Date: date, Datasets: make(map[string]DatasetStats)}
+				byDate[date] = entry
+			}
+			entry.Datasets[dataset] = stats[date]
+		}
+	}
+
+	first, last := start, end
+	for date := range byDate {
+		if first == "" || date < first {
+			first = date
+		}
+		if last == "" || date > last {
+			last = date
+		}
+	}
+	if first == "" || last == "" {
+		return nil, nil
+	}
+
+	firstTime, err := time.Parse("2006-01-02", first)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %w", err)
+	}
+	lastTime, err := time.Parse("2006-01-02", last)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date: %w", err)
+	}
+
+	var entries []CatalogEntry
+	for current := firstTime; !current.After(lastTime); current = current.AddDate(0, 0, 1) {
+		date := current.Format("2006-01-02")
+		entry, ok := byDate[date]
+		if !ok {
+			entry = &CatalogEntry{Date: date, Datasets: make(map[string]DatasetStats)}
+		}
+		for _, dataset := range datasets {
+			if entry.Datasets[dataset].Objects == 0 {
+				entry.Missing = append(entry.Missing, dataset)
+			}
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+// listDatePartitions returns the sorted dates of the date=YYYY-MM-DD/ partitions under prefix
+func listDatePartitions(ctx context.Context, s3Client *s3.Client, cfg *config.Config, shaper *shaper, prefix string) ([]string, error) {
+	listInput := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(cfg.AWSS3Bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+
+	var dates []string
+	paginator := s3.NewListObjectsV2Paginator(s3Client, listInput)
+	for paginator.HasMorePages() {
+		page, err := do(ctx, shaper, "list objects", func() (*s3.ListObjectsV2Output, error) {
+			return paginator.NextPage(ctx)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, commonPrefix := range page.CommonPrefixes {
+			if date, ok := dateFromKey(aws.ToString(commonPrefix.Prefix)); ok {
+				dates = append(dates, date)
+			}
+		}
+	}
+	sort.Strings(dates)
+	return dates, nil
+}
+
+// listDateObjects counts parquet objects and bytes per date for dates in [start, end].
+// It walks the keys in order starting at the first partition and stops once it
+// passes the last one, so a narrow range does not list the whole dataset.
+func listDateObjects(ctx context.Context, s3Client *s3.Client, cfg *config.Config, shaper *shaper, prefix, start, end string) (map[string]DatasetStats, error) {
+	listInput := &s3.ListObjectsV2Input{
+		Bucket:     aws.String(cfg.AWSS3Bucket),
+		Prefix:     aws.String(prefix),
+		StartAfter: aws.String(fmt.Sprintf("%sdate=%s", prefix, start)),
+	}
+
+	stats := make(map[string]DatasetStats)
+	paginator := s3.NewListObjectsV2Paginator(s3Client, listInput)
+	for paginator.HasMorePages() {
+		page, err := do(ctx, shaper, "list objects", func() (*s3.ListObjectsV2Output, error) {
+			return paginator.NextPage(ctx)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			date, ok := dateFromKey(key)
+			if !ok {
+				continue
+			}
+			if date > end {
+				return stats, nil
+			}
+			if !strings.HasSuffix(key, ".parquet") {
+				continue
+			}
+			s := stats[date]
+			s.Objects++
+			s.Bytes += aws.ToInt64(obj.Size)
+			stats[date] = s
+		}
+	}
+	return stats, nil
+}
+
+// dateFromKey extracts YYYY-MM-DD from the date=YYYY-MM-DD segment of an S3 key
+func dateFromKey(key string) (string, bool) {
+	idx := strings.Index(key, "date=")
+	if idx < 0 || len(key) < idx+15 {
+		return "", false
+	}
+	date := key[idx+5 : idx+15]
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+// filterDates keeps sorted dates within [start, end]; empty bounds are open
+func filterDates(dates []string, start, end string) []string {
+	var filtered []string
+	for _, date := range dates {
+		if start != "" && date < start {
+			continue
+		}
+		if end != "" && date > end {
+			continue
+		}
+		filtered = append(filtered, date)
+	}
+	return filtered
+}
@@ -7,24 +7,45 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/siddon/web3insights/internal/config"
 )
 
-// DownloadBTC downloads Bitcoin parquet files from AWS S3 for a given date.
-// It downloads both blocks and transactions files to the configured output directory.
+// Download downloads the parquet files of every dataset under prefix from AWS S3
+// for a given date into out/<chainDir>/<dataset>/<date>.
 // The date should be in YYYY-MM-DD format (e.g., "2019-01-01").
 // Uses unsigned requests for public bucket access (equivalent to --no-sign-request).
 // It will always check S3 for new files and only download ones that don't exist locally.
-func DownloadBTC(ctx context.Context, cfg *config.Config, date string) error {
+func Download(ctx context.Context, cfg *config.Config, prefix, chainDir string, datasets []string, date string) error {
 	// Validate date format
 	if len(date) != 10 || date[4] != '-' || date[7] != '-' {
 		return fmt.Errorf("invalid date format, expected YYYY-MM-DD, got: %s", date)
 	}
 
+	s3Client, err := newS3Client(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Download each dataset (idempotent: skips files that already exist locally)
+	for _, dataset := range datasets {
+		datasetPrefix := fmt.Sprintf("%s%s/date=%s/", prefix, dataset, date)
+		if err := downloadDatasetFiles(ctx, s3Client, cfg, datasetPrefix, chainDir, dataset, date); err != nil {
+			return fmt.Errorf("failed to download %s: %w", dataset, err)
+		}
+	}
+
+	return nil
+}
+
+// newS3Client creates an S3 client for the public dataset bucket.
+// Uses unsigned requests for public bucket access (equivalent to --no-sign-request).
+func newS3Client(ctx context.Context, cfg *config.Config) (*s3.Client, error) {
 	// Load AWS config with anonymous credentials for public bucket access
 	// Use AnonymousCredentials to allow unsigned requests for public buckets
 	awsCfg, err := awsconfig.LoadDefaultConfig(ctx,
@@ -32,30 +53,21 @@ func DownloadBTC(ctx context.Context, cfg *config.Config, date string) error {
 		awsconfig.WithCredentialsProvider(
 			aws.NewCredentialsCache(aws.AnonymousCredentials{}),
 		),
+		// The SDK retries network errors and server errors as usual, but not
+		// throttling: its retries of 503 SlowDown would bypass the request rate
+		// limit and hide throttling from the shaper, so do in throttle.go backs
+		// off from those instead
+		awsconfig.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.Retryables = append([]retry.IsErrorRetryable{retry.IsErrorRetryableFunc(skipThrottleErrors)}, o.Retryables...)
+			})
+		}),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	// Create S3 client
-	// Note: For public buckets, the SDK will still attempt to sign requests
-	// but with placeholder credentials. This may work if the bucket allows unsigned requests.
-	// If it doesn't work, we may need to use a custom HTTP client approach.
-	s3Client := s3.NewFromConfig(awsCfg)
-
-	// Download blocks (idempotent: skips files that already exist locally)
-	blocksPrefix := fmt.Sprintf("%sblocks/date=%s/", cfg.AWSS3BTCPrefix, date)
-	if err := downloadBTCFiles(ctx, s3Client, cfg, blocksPrefix, "blocks", date); err != nil {
-		return fmt.Errorf("failed to download blocks: %w", err)
-	}
-
-	// Download transactions (idempotent: skips files that already exist locally)
-	transactionsPrefix := fmt.Sprintf("%stransactions/date=%s/", cfg.AWSS3BTCPrefix, date)
-	if err := downloadBTCFiles(ctx, s3Client, cfg, transactionsPrefix, "transactions", date); err != nil {
-		return fmt.Errorf("failed to download transactions: %w", err)
-	}
-
-	return nil
+	return s3.NewFromConfig(awsCfg), nil
 }
 
 // checkFilesExist checks if a directory exists and contains at least one parquet file
@@ -79,8 +91,9 @@ func checkFilesExist(dir string) bool {
 	return false
 }
 
-// downloadBTCFiles lists and downloads all Bitcoin parquet files from the given S3 prefix.
-func downloadBTCFiles(ctx context.Context, s3Client *s3.Client, cfg *config.Config, s3Prefix, dataType, date string) error {
+// downloadDatasetFiles lists and downloads all parquet files from the given S3 prefix
+// into out/<chainDir>/<dataType>/<date>.
+func downloadDatasetFiles(ctx context.Context, s3Client *s3.Client, cfg *config.Config, s3Prefix, chainDir, dataType, date string) error {
 	// List objects in S3
 	listInput := &s3.ListObjectsV2Input{
 		Bucket: aws.String(cfg.AWSS3Bucket),
@@ -88,16 +101,21 @@ func downloadBTCFiles(ctx context.Context, s3Client *s3.Client, cfg *config.Conf
 	}
 
 	// Create local directory
-	localDir := filepath.Join(cfg.OutDir, "btc", dataType, date)
+	localDir := filepath.Join(cfg.OutDir, chainDir, dataType, date)
 	if err := os.MkdirAll(localDir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory %s: %w", localDir, err)
 	}
 
+	shaper := getShaper(cfg)
+
 	var downloadedCount int
+	var pending []downloadJob
 	paginator := s3.NewListObjectsV2Paginator(s3Client, listInput)
 
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
+		page, err := do(ctx, shaper, "list objects", func() (*s3.ListObjectsV2Output, error) {
+			return paginator.NextPage(ctx)
+		})
 		if err != nil {
 			return fmt.Errorf("failed to list objects: %w", err)
 		}
@@ -124,16 +142,17 @@ func downloadBTCFiles(ctx context.Context, s3Client *s3.Client, cfg *config.Conf
 				continue
 			}
 
-			// Download file
-			if err := downloadFile(ctx, s3Client, cfg, *obj.Key, localPath); err != nil {
-				return fmt.Errorf("failed to download %s: %w", *obj.Key, err)
-			}
-
-			downloadedCount++
-			fmt.Printf("Downloaded: %s\n", localPath)
+			pending = append(pending, downloadJob{s3Key: *obj.Key, localPath: localPath})
 		}
 	}
 
+	// Download files, up to DownloadConcurrency at a time
+	n, err := downloadFiles(ctx, s3Client, cfg, shaper, pending)
+	downloadedCount += n
+	if err != nil {
+		return err
+	}
+
 	if cfg.DryRun {
 		fmt.Printf("[DRY RUN] Would download %d files for %s/%s\n", downloadedCount, dataType, date)
 	} else {
@@ -143,15 +162,76 @@ func downloadBTCFiles(ctx context.Context, s3Client *s3.Client, cfg *config.Conf
 	return nil
 }
 
+// downloadJob is a single S3 object to fetch into localPath
+type downloadJob struct {
+	s3Key     string
+	localPath string
+}
+
+// downloadFiles downloads jobs with a pool of cfg.DownloadConcurrency workers.
+// All workers share the shaper's bandwidth and request rate limits.
+// It returns the number of files downloaded and the first error encountered.
+func downloadFiles(ctx context.Context, s3Client *s3.Client, cfg *config.Config, shaper *shaper, jobs []downloadJob) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := cfg.DownloadConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobCh := make(chan downloadJob)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		count    int
+		firstErr error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				if err := downloadFile(ctx, s3Client, cfg, shaper, job.s3Key, job.localPath); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("failed to download %s: %w", job.s3Key, err)
+						cancel()
+					}
+					mu.Unlock()
+					continue
+				}
+				mu.Lock()
+				count++
+				mu.Unlock()
+				fmt.Printf("Downloaded: %s\n", job.localPath)
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		jobCh <- job
+	}
+	close(jobCh)
+	wg.Wait()
+
+	return count, firstErr
+}
+
 // downloadFile downloads a single file from S3.
-func downloadFile(ctx context.Context, s3Client *s3.Client, cfg *config.Config, s3Key, localPath string) error {
+func downloadFile(ctx context.Context, s3Client *s3.Client, cfg *config.Config, shaper *shaper, s3Key, localPath string) error {
 	// Get object from S3
 	getInput := &s3.GetObjectInput{
 		Bucket: aws.String(cfg.AWSS3Bucket),
 		Key:    aws.String(s3Key),
 	}
 
-	result, err := s3Client.GetObject(ctx, getInput)
+	result, err := do(ctx, shaper, "get object", func() (*s3.GetObjectOutput, error) {
+		return s3Client.GetObject(ctx, getInput)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get object from S3: %w", err)
 	}
@@ -173,7 +253,7 @@ func downloadFile(ctx context.Context, s3Client *s3.Client, cfg *config.Config,
 	}()
 
 	// Copy content to temporary file
-	_, err = io.Copy(tmpFile, result.Body)
+	_, err = io.Copy(tmpFile, shaper.reader(ctx, result.Body))
 	if err != nil {
 		tmpFile.Close()
 		return fmt.Errorf("failed to write to temporary file: %w", err)
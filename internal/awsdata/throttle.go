@@ -0,0 +1,270 @@
+package awsdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/siddon/web3insights/internal/config"
+)
+
+const (
+	minThrottleBackoff = 500 * time.Millisecond
+	maxThrottleBackoff = 30 * time.Second
+
+	// Smallest request rate the adaptive backoff will reduce to (requests per second)
+	minRequestRate = 0.5
+)
+
+// rateLimiter is a token bucket that is safe for concurrent use.
+// Callers reserve tokens up front and sleep until the bucket has refilled,
+// so concurrent callers share the configured rate fairly.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // bucket capacity
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until n tokens are available or ctx is done
+func (l *rateLimiter) wait(ctx context.Context, n float64) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= n
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// setRate changes the refill rate, keeping tokens already accrued
+func (l *rateLimiter) setRate(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.rate = rate
+}
+
+func (l *rateLimiter) currentRate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// shaper caps download bandwidth and S3 request rate for the whole process
+// and backs off adaptively when S3 responds with 503 SlowDown
+type shaper struct {
+	bandwidth   *rateLimiter // bytes per second, nil means unlimited
+	requests    *rateLimiter // requests per second, nil means unlimited
+	maxRequests float64      // configured request rate to recover towards
+	maxRetries  int
+
+	mu      sync.Mutex
+	backoff time.Duration // current adaptive backoff, grows on SlowDown and decays on success
+}
+
+var (
+	shaperMu     sync.Mutex
+	sharedShaper *shaper
+	shaperConfig [3]int64
+)
+
+// getShaper returns the process-wide shaper for the limits in cfg.
+// All downloads with the same limits share one shaper, so the caps apply to
+// the total traffic rather than to each download separately.
+func getShaper(cfg *config.Config) *shaper {
+	shaperMu.Lock()
+	defer shaperMu.Unlock()
+
+	key := [3]int64{cfg.DownloadMaxBytesPerSec, int64(cfg.S3MaxRequestsPerSec), int64(cfg.MaxRetries)}
+	if sharedShaper != nil && shaperConfig == key {
+		return sharedShaper
+	}
+
+	s := &shaper{maxRetries: cfg.MaxRetries}
+	if cfg.DownloadMaxBytesPerSec > 0 {
+		rate := float64(cfg.DownloadMaxBytesPerSec)
+		s.bandwidth = newRateLimiter(rate, rate)
+	}
+	if cfg.S3MaxRequestsPerSec > 0 {
+		s.maxRequests = float64(cfg.S3MaxRequestsPerSec)
+		s.requests = newRateLimiter(s.maxRequests, s.maxRequests)
+	}
+	sharedShaper = s
+	shaperConfig = key
+	return s
+}
+
+// do runs an S3 call under the request rate limit, retrying with adaptive
+// backoff while S3 reports throttling
+func do[T any](ctx context.Context, s *shaper, operation string, fn func() (T, error)) (T, error) {
+	var result T
+	var err error
+
+	attempts := s.maxRetries
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if s.requests != nil {
+			if err := s.requests.wait(ctx, 1); err != nil {
+				return result, err
+			}
+		}
+
+		result, err = fn()
+		if err == nil {
+			s.onSuccess()
+			return result, nil
+		}
+		if !isThrottleError(err) {
+			return result, err
+		}
+
+		delay := s.onThrottle()
+		if attempt < attempts {
+			fmt.Printf("S3 throttled %s (attempt %d/%d), backing off %v\n", operation, attempt, attempts, delay)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return result, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return result, fmt.Errorf("%s throttled after %d attempts: %w", operation, attempts, err)
+}
+
+// onThrottle doubles the backoff and halves the request rate
+func (s *shaper) onThrottle() time.Duration {
+	s.mu.Lock()
+	s.backoff *= 2
+	if s.backoff < minThrottleBackoff {
+		s.backoff = minThrottleBackoff
+	}
+	if s.backoff > maxThrottleBackoff {
+		s.backoff = maxThrottleBackoff
+	}
+	delay := s.backoff
+	s.mu.Unlock()
+
+	if s.requests != nil {
+		rate := s.requests.currentRate() / 2
+		if rate < minRequestRate {
+			rate = minRequestRate
+		}
+		s.requests.setRate(rate)
+	}
+	return delay
+}
+
+// onSuccess decays the backoff and lets the request rate recover towards the configured cap
+func (s *shaper) onSuccess() {
+	s.mu.Lock()
+	s.backoff /= 2
+	if s.backoff < minThrottleBackoff {
+		s.backoff = 0
+	}
+	s.mu.Unlock()
+
+	if s.requests != nil {
+		if rate := s.requests.currentRate(); rate < s.maxRequests {
+			rate *= 1.1
+			if rate > s.maxRequests {
+				rate = s.maxRequests
+			}
+			s.requests.setRate(rate)
+		}
+	}
+}
+
+// reader wraps r so reads are limited by the shared bandwidth cap
+func (s *shaper) reader(ctx context.Context, r io.Reader) io.Reader {
+	if s.bandwidth == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, r: r, limiter: s.bandwidth}
+}
+
+// throttledReader reads at most one bucket of bytes at a time and waits for the
+// bytes it actually read, so a single large read cannot exceed the cap
+type throttledReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rateLimiter
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if max := int(t.limiter.burst); len(p) > max && max > 0 {
+		p = p[:max]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if waitErr := t.limiter.wait(t.ctx, float64(n)); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+// skipThrottleErrors keeps the SDK retryer from retrying throttling responses,
+// which do retries under the shaper
+func skipThrottleErrors(err error) aws.Ternary {
+	if isThrottleError(err) {
+		return aws.FalseTernary
+	}
+	return aws.UnknownTernary
+}
+
+// isThrottleError reports whether err is an S3 throttling response (503 SlowDown)
+func isThrottleError(err error) bool {
+	var apiErr interface{ ErrorCode() string }
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "SlowDown", "Throttling", "ThrottlingException", "RequestLimitExceeded", "TooManyRequestsException":
+			return true
+		}
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		status := respErr.HTTPStatusCode()
+		return status == http.StatusServiceUnavailable || status == http.StatusTooManyRequests
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+// Package brc20 parses BRC-20 operations from inscription content.
+//
+// A BRC-20 operation is a JSON inscription such as
+//
+//	{"p":"brc-20","op":"mint","tick":"ordi","amt":"1000"}
+//
+// Amounts are decimal strings; they are held as integers scaled by 10^18, the
+// largest number of decimals a token may declare.
+package brc20
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// FirstHeight is the height of the first BRC-20 deploy
+const FirstHeight = 779832
+
+// Operation kinds
+const (
+	Deploy   = "deploy"
+	Mint     = "mint"
+	Transfer = "transfer"
+)
+
+// MaxDecimals is the number of decimals amounts are scaled by
+const MaxDecimals = 18
+
+var (
+	scale = new(big.Int).Exp(big.NewInt(10), big.NewInt(MaxDecimals), nil)
+	// maxAmount is the largest amount in whole tokens, 2^64-1
+	maxAmount = new(big.Int).Mul(new(big.Int).SetUint64(^uint64(0)), scale)
+)
+
+// Operation is a parsed BRC-20 operation. Amounts are scaled by 10^18.
+type Operation struct {
+	Op       string
+	Tick     string // Lowercased; ticks are case-insensitive
+	Max      *big.Int
+	Limit    *big.Int // Per-mint limit of a deploy; defaults to Max
+	Decimals int
+	Amount   *big.Int // Amount of a mint or transfer
+}
+
+type document struct {
+	P    string  `json:"p"`
+	Op   string  `json:"op"`
+	Tick string  `json:"tick"`
+	Max  *string `json:"max"`
+	Lim  *string `json:"lim"`
+	Dec  *string `json:"dec"`
+	Amt  *string `json:"amt"`
+}
+
+// Parse returns the operation of an inscription's content, or false if it
+// isn't a valid BRC-20 operation. Token-level rules, such as whether the tick
+// is deployed or the amount fits its decimals, are left to the indexer.
+func Parse(contentType string, body []byte) (Operation, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(strings.ToLower(mediaType)) {
+	case "text/plain", "application/json":
+	default:
+		return Operation{}, false
+	}
+
+	var doc document
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return Operation{}, false
+	}
+	if doc.P != "brc-20" || len(doc.Tick) != 4 || !utf8.ValidString(doc.Tick) {
+		return Operation{}, false
+	}
+	op := Operation{Op: doc.Op, Tick: strings.ToLower(doc.Tick), Decimals: MaxDecimals}
+
+	switch doc.Op {
+	case Deploy:
+		if doc.Dec != nil {
+			dec, err := parseDecimals(*doc.Dec)
+			if err != nil {
+				return Operation{}, false
+			}
+			op.Decimals = dec
+		}
+		if doc.Max == nil {
+			return Operation{}, false
+		}
+		max, err := ParseAmount(*doc.Max, op.Decimals)
+		if err != nil || max.Sign() == 0 {
+			return Operation{}, false
+		}
+		op.Max, op.Limit = max, max
+		if doc.Lim != nil {
+			limit, err := ParseAmount(*doc.Lim, op.Decimals)
+			if err != nil || limit.Sign() == 0 {
+				return Operation{}, false
+			}
+			op.Limit = limit
+		}
+	case Mint, Transfer:
+		if doc.Amt == nil {
+			return Operation{}, false
+		}
+		// Decimals are checked against the deploy by the indexer
+		amount, err := ParseAmount(*doc.Amt, MaxDecimals)
+		if err != nil || amount.Sign() == 0 {
+			return Operation{}, false
+		}
+		op.Amount = amount
+	default:
+		return Operation{}, false
+	}
+	return op, true
+}
+
+// ParseAmount parses a decimal string with at most decimals fractional digits
+// into an integer scaled by 10^18
+func ParseAmount(s string, decimals int) (*big.Int, error) {
+	whole, fraction, hasPoint := strings.Cut(s, ".")
+	if whole == "" || (hasPoint && fraction == "") || !digits(whole) || !digits(fraction) {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", s, decimals)
+	}
+	n, _ := new(big.Int).SetString(whole+fraction+strings.Repeat("0", MaxDecimals-len(fraction)), 10)
+	if n.Cmp(maxAmount) > 0 {
+		return nil, fmt.Errorf("amount %q is too large", s)
+	}
+	return n, nil
+}
+
+// FitsDecimals reports whether a scaled amount has at most decimals fractional digits
+func FitsDecimals(amount *big.Int, decimals int) bool {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(MaxDecimals-decimals)), nil)
+	return new(big.Int).Mod(amount, unit).Sign() == 0
+}
+
+// FormatAmount formats a scaled amount with 18 decimals, e.g. "21.000000000000000000"
+func FormatAmount(amount *big.Int) string {
+	s := new(big.Int).Abs(amount).String()
+	if len(s) <= MaxDecimals {
+		s = strings.Repeat("0", MaxDecimals-len(s)+1) + s
+	}
+	s = s[:len(s)-MaxDecimals] + "." + s[len(s)-MaxDecimals:]
+	if amount.Sign() < 0 {
+		return "-" + s
+	}
+	return s
+}
+
+func parseDecimals(s string) (int, error) {
+	if !digits(s) || s == "" || len(s) > 2 {
+		return 0, errors.New("invalid decimals")
+	}
+	dec := 0
+	for _, c := range s {
+		dec = dec*10 + int(c-'0')
+	}
+	if dec > MaxDecimals {
+		return 0, errors.New("invalid decimals")
+	}
+	return dec, nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
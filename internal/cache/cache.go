@@ -0,0 +1,253 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
+	"github.com/siddon/web3insights/internal/sync"
+)
+
+const (
+	// PolicyLRU evicts the dates that were least recently downloaded, synced or parsed first.
+	PolicyLRU = "lru"
+	// PolicyOldest evicts the earliest calendar dates first.
+	PolicyOldest = "oldest"
+)
+
+// DateUsage describes the local disk usage of a single date across all datasets
+type DateUsage struct {
+	Date       string    `json:"date"`
+	Files      int       `json:"files"`       // Number of parquet files for this date
+	Bytes      int64     `json:"bytes"`       // Total bytes on disk (parquet and status files)
+	Synced     bool      `json:"synced"`      // True if every parquet file has a complete sync status
+	LastAccess time.Time `json:"last_access"` // Most recent modification time of the date directories
+}
+
+// Manager keeps the out/<chain>/<dataset>/<date> tree within a byte budget
+type Manager struct {
+	Root     string   // Chain directory, e.g. out/btc
+	Datasets []string // Dataset directories under Root, e.g. blocks, transactions
+	MaxBytes int64    // Byte budget, 0 means unlimited
+	Policy   string   // PolicyLRU or PolicyOldest
+	DryRun   bool
+}
+
+// NewManager creates a Manager for the tree of cfg.Chain using the cache settings in cfg.
+// Unknown chains fall back to the BTC tree.
+func NewManager(cfg *config.Config) *Manager {
+	c, err := registry.Lookup(cfg.Chain)
+	if err != nil {
+		c, _ = registry.Lookup("btc")
+	}
+	return &Manager{
+		Root:     c.Dir(cfg),
+		Datasets: c.DatasetNames(),
+		MaxBytes: cfg.CacheMaxBytes,
+		Policy:   cfg.CachePolicy,
+		DryRun:   cfg.DryRun,
+	}
+}
+
+// Usage scans the tree and returns per-date usage sorted by date
+func (m *Manager) Usage() ([]DateUsage, error) {
+	byDate := make(map[string]*DateUsage)
+	unsynced := make(map[string]bool)
+
+	for _, dataset := range m.Datasets {
+		datasetDir := filepath.Join(m.Root, dataset)
+		entries, err := os.ReadDir(datasetDir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", datasetDir, err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			date := entry.Name()
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				continue
+			}
+
+			usage, ok := byDate[date]
+			if !ok {
+				usage = &DateUsage{Date: date}
+				byDate[date] = usage
+			}
+
+			dateDir := filepath.Join(datasetDir, date)
+			if info, err := entry.Info(); err == nil && info.ModTime().After(usage.LastAccess) {
+				usage.LastAccess = info.ModTime()
+			}
+			synced, err := scanDateDir(dateDir, usage)
+			if err != nil {
+				return nil, err
+			}
+			if !synced {
+				unsynced[date] = true
+			}
+		}
+	}
+
+	usages := make([]DateUsage, 0, len(byDate))
+	for date, usage := range byDate {
+		usage.Synced = usage.Files > 0 && !unsynced[date]
+		usages = append(usages, *usage)
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Date < usages[j].Date
+	})
+	return usages, nil
+}
+
+// scanDateDir adds the files in a single dataset/date directory to usage and
+// reports whether every parquet file in it has been fully synced
+func scanDateDir(dateDir string, usage *DateUsage) (bool, error) {
+	entries, err := os.ReadDir(dateDir)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", dateDir, err)
+	}
+
+	synced := true
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return false, fmt.Errorf("failed to stat %s: %w", entry.Name(), err)
+		}
+		usage.Bytes += info.Size()
+		if info.ModTime().After(usage.LastAccess) {
+			usage.LastAccess = info.ModTime()
+		}
+
+		if !strings.HasSuffix(entry.Name(), ".parquet") {
+			continue
+		}
+		usage.Files++
+
+		path := filepath.Join(dateDir, entry.Name())
+		status, err := sync.LoadStatus(sync.GetStatusPathForFile(path))
+		if err != nil || !status.IsComplete() {
+			synced = false
+		}
+	}
+	return synced, nil
+}
+
+// Touch marks a date as recently used for the LRU policy
+func (m *Manager) Touch(date string) {
+	now := time.Now()
+	for _, dataset := range m.Datasets {
+		dateDir := filepath.Join(m.Root, dataset, date)
+		if err := os.Chtimes(dateDir, now, now); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to touch %s: %v\n", dateDir, err)
+		}
+	}
+}
+
+// Candidates returns the synced dates in eviction order for the configured policy.
+// Dates listed in keep are never returned.
+func (m *Manager) Candidates(usages []DateUsage, keep ...string) ([]DateUsage, error) {
+	kept := make(map[string]bool, len(keep))
+	for _, date := range keep {
+		kept[date] = true
+	}
+
+	var candidates []DateUsage
+	for _, usage := range usages {
+		if usage.Synced && !kept[usage.Date] {
+			candidates = append(candidates, usage)
+		}
+	}
+
+	switch m.Policy {
+	case PolicyOldest:
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Date < candidates[j].Date
+		})
+	case PolicyLRU, "":
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].LastAccess.Equal(candidates[j].LastAccess) {
+				return candidates[i].Date < candidates[j].Date
+			}
+			return candidates[i].LastAccess.Before(candidates[j].LastAccess)
+		})
+	default:
+		return nil, fmt.Errorf("unsupported cache policy: %s (expected %q or %q)", m.Policy, PolicyLRU, PolicyOldest)
+	}
+	return candidates, nil
+}
+
+// Enforce evicts synced dates until the tree fits in MaxBytes.
+// Unsynced dates and dates listed in keep are never evicted, so the tree may
+// stay above budget if there is nothing left that is safe to remove.
+func (m *Manager) Enforce(keep ...string) ([]DateUsage, error) {
+	if m.MaxBytes <= 0 {
+		return nil, nil
+	}
+
+	usages, err := m.Usage()
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, usage := range usages {
+		total += usage.Bytes
+	}
+	if total <= m.MaxBytes {
+		return nil, nil
+	}
+
+	candidates, err := m.Candidates(usages, keep...)
+	if err != nil {
+		return nil, err
+	}
+
+	var evicted []DateUsage
+	for _, usage := range candidates {
+		if total <= m.MaxBytes {
+			break
+		}
+		if err := m.Evict(usage.Date); err != nil {
+			return evicted, err
+		}
+		total -= usage.Bytes
+		evicted = append(evicted, usage)
+	}
+
+	if total > m.MaxBytes {
+		fmt.Fprintf(os.Stderr, "Warning: cache is %d bytes, above budget of %d bytes, but no more synced dates can be evicted\n", total, m.MaxBytes)
+	}
+	return evicted, nil
+}
+
+// Evict removes all dataset directories for a date
+func (m *Manager) Evict(date string) error {
+	for _, dataset := range m.Datasets {
+		dateDir := filepath.Join(m.Root, dataset, date)
+		if _, err := os.Stat(dateDir); os.IsNotExist(err) {
+			continue
+		}
+		if m.DryRun {
+			fmt.Printf("[DRY RUN] Would remove: %s\n", dateDir)
+			continue
+		}
+		if err := os.RemoveAll(dateDir); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", dateDir, err)
+		}
+		fmt.Printf("Removed: %s\n", dateDir)
+	}
+	return nil
+}
@@ -33,9 +33,28 @@ func (t Int96Timestamp) Time() time.Time {
 	if days == 0 && nano == 0 {
 		return time.Time{}
 	}
+	// Julian day 0 is never a real date: the first 8 bytes hold an INT64 timestamp
+	if days == 0 {
+		return int64ToTime(int64(nano))
+	}
 	return jdToTime(days, nano)
 }
 
+// int64ToTime converts an INT64 value without a known unit to time.Time.
+// Columns with a TIMESTAMP logical type are converted in their own unit by
+// NormalizeTimestamps, so the unit is only inferred from the magnitude for the
+// rest, which is unambiguous for dates between 1973 and 5138.
+func int64ToTime(v int64) time.Time {
+	switch {
+	case v < 1e14:
+		return time.UnixMilli(v).UTC()
+	case v < 1e17:
+		return time.UnixMicro(v).UTC()
+	default:
+		return time.Unix(0, v).UTC()
+	}
+}
+
 // String implements fmt.Stringer to format Int96Timestamp as DateTime string
 func (t Int96Timestamp) String() string {
 	ts := t.Time()
@@ -53,6 +72,14 @@ func (t *Int96Timestamp) UnmarshalParquet(value parquet.Value) error {
 		*t = Int96Timestamp{}
 		return nil
 	}
+	// INT64 values keep the raw value in the first 8 bytes, as parquet-go does
+	// when converting an INT64 column without a known unit into the fixed-size
+	// array
+	if value.Kind() == parquet.Int64 {
+		*t = Int96Timestamp{}
+		binary.LittleEndian.PutUint64(t[:8], uint64(value.Int64()))
+		return nil
+	}
 	data := value.ByteArray()
 	if len(data) < 12 {
 		*t = Int96Timestamp{}
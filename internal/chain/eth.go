@@ -0,0 +1,135 @@
+package chain
+
+// Ethereum parquet models for the AWS Public Blockchain dataset (v1.0/eth/).
+// Column names follow the ethereum-etl schema used by the dataset.
+// References:
+// - https://github.com/aws-solutions-library-samples/guidance-for-digital-assets-on-aws/blob/main/analytics/consumer/schema/eth.md
+// - https://ethereum-etl.readthedocs.io/en/latest/schema/
+
+// EthBlock represents an Ethereum block from parquet
+type EthBlock struct {
+	Date             string          `parquet:"date"`
+	Timestamp        Int96Timestamp  `parquet:"timestamp,optional"`
+	Number           int64           `parquet:"number"`
+	Hash             string          `parquet:"hash"`
+	ParentHash       string          `parquet:"parent_hash,optional"`
+	Nonce            string          `parquet:"nonce,optional"`
+	Sha3Uncles       string          `parquet:"sha3_uncles,optional"`
+	LogsBloom        string          `parquet:"logs_bloom,optional"`
+	TransactionsRoot string          `parquet:"transactions_root,optional"`
+	StateRoot        string          `parquet:"state_root,optional"`
+	ReceiptsRoot     string          `parquet:"receipts_root,optional"`
+	Miner            string          `parquet:"miner,optional"`
+	Difficulty       float64         `parquet:"difficulty,optional"`
+	TotalDifficulty  float64         `parquet:"total_difficulty,optional"`
+	Size             int64           `parquet:"size,optional"`
+	ExtraData        string          `parquet:"extra_data,optional"`
+	GasLimit         float64         `parquet:"gas_limit,optional"`
+	GasUsed          float64         `parquet:"gas_used,optional"`
+	TransactionCount int64           `parquet:"transaction_count,optional"`
+	BaseFeePerGas    float64         `parquet:"base_fee_per_gas,optional"`
+	WithdrawalsRoot  string          `parquet:"withdrawals_root,optional"`
+	Withdrawals      []EthWithdrawal `parquet:"withdrawals,list,optional"`
+}
+
+// EthWithdrawal represents a beacon chain withdrawal included in a block
+// This is a repeated group under "withdrawals" in the parquet schema
+type EthWithdrawal struct {
+	Index          string `parquet:"index,optional"`
+	ValidatorIndex string `parquet:"validator_index,optional"`
+	Address        string `parquet:"address,optional"`
+	Amount         string `parquet:"amount,optional"`
+}
+
+// EthTransaction represents an Ethereum transaction from parquet
+type EthTransaction struct {
+	Date                     string         `parquet:"date"`
+	Hash                     string         `parquet:"hash"`
+	Nonce                    int64          `parquet:"nonce,optional"`
+	TransactionIndex         int64          `parquet:"transaction_index"`
+	FromAddress              string         `parquet:"from_address,optional"`
+	ToAddress                string         `parquet:"to_address,optional"`
+	Value                    float64        `parquet:"value,optional"`
+	Gas                      float64        `parquet:"gas,optional"`
+	GasPrice                 float64        `parquet:"gas_price,optional"`
+	Input                    string         `parquet:"input,optional"`
+	ReceiptCumulativeGasUsed float64        `parquet:"receipt_cumulative_gas_used,optional"`
+	ReceiptGasUsed           float64        `parquet:"receipt_gas_used,optional"`
+	ReceiptContractAddress   string         `parquet:"receipt_contract_address,optional"`
+	ReceiptStatus            int64          `parquet:"receipt_status,optional"`
+	BlockTimestamp           Int96Timestamp `parquet:"block_timestamp,optional"`
+	BlockNumber              int64          `parquet:"block_number"`
+	BlockHash                string         `parquet:"block_hash"`
+	MaxFeePerGas             float64        `parquet:"max_fee_per_gas,optional"`
+	MaxPriorityFeePerGas     float64        `parquet:"max_priority_fee_per_gas,optional"`
+	TransactionType          int64          `parquet:"transaction_type,optional"`
+	ReceiptEffectiveGasPrice float64        `parquet:"receipt_effective_gas_price,optional"`
+}
+
+// EthLog represents an event log emitted by a transaction from parquet
+type EthLog struct {
+	Date             string         `parquet:"date"`
+	LogIndex         int64          `parquet:"log_index"`
+	TransactionHash  string         `parquet:"transaction_hash"`
+	TransactionIndex int64          `parquet:"transaction_index,optional"`
+	Address          string         `parquet:"address,optional"`
+	Data             string         `parquet:"data,optional"`
+	Topics           []string       `parquet:"topics,list,optional"`
+	BlockTimestamp   Int96Timestamp `parquet:"block_timestamp,optional"`
+	BlockNumber      int64          `parquet:"block_number"`
+	BlockHash        string         `parquet:"block_hash"`
+}
+
+// EthTokenTransfer represents an ERC-20/ERC-721 Transfer event from parquet
+type EthTokenTransfer struct {
+	Date            string         `parquet:"date"`
+	TokenAddress    string         `parquet:"token_address,optional"`
+	FromAddress     string         `parquet:"from_address,optional"`
+	ToAddress       string         `parquet:"to_address,optional"`
+	Value           float64        `parquet:"value,optional"`
+	TransactionHash string         `parquet:"transaction_hash"`
+	LogIndex        int64          `parquet:"log_index"`
+	BlockTimestamp  Int96Timestamp `parquet:"block_timestamp,optional"`
+	BlockNumber     int64          `parquet:"block_number"`
+	BlockHash       string         `parquet:"block_hash"`
+}
+
+// EthReceipt represents a transaction receipt from parquet
+type EthReceipt struct {
+	Date              string  `parquet:"date"`
+	TransactionHash   string  `parquet:"transaction_hash"`
+	TransactionIndex  int64   `parquet:"transaction_index,optional"`
+	BlockHash         string  `parquet:"block_hash"`
+	BlockNumber       int64   `parquet:"block_number"`
+	CumulativeGasUsed float64 `parquet:"cumulative_gas_used,optional"`
+	GasUsed           float64 `parquet:"gas_used,optional"`
+	ContractAddress   string  `parquet:"contract_address,optional"`
+	Root              string  `parquet:"root,optional"`
+	Status            int64   `parquet:"status,optional"`
+	EffectiveGasPrice float64 `parquet:"effective_gas_price,optional"`
+}
+
+// EthTrace represents an internal call, create, suicide or reward trace from parquet
+type EthTrace struct {
+	Date             string         `parquet:"date"`
+	TransactionHash  string         `parquet:"transaction_hash,optional"`
+	TransactionIndex int64          `parquet:"transaction_index,optional"`
+	FromAddress      string         `parquet:"from_address,optional"`
+	ToAddress        string         `parquet:"to_address,optional"`
+	Value            float64        `parquet:"value,optional"`
+	Input            string         `parquet:"input,optional"`
+	Output           string         `parquet:"output,optional"`
+	TraceType        string         `parquet:"trace_type,optional"`
+	CallType         string         `parquet:"call_type,optional"`
+	RewardType       string         `parquet:"reward_type,optional"`
+	Gas              float64        `parquet:"gas,optional"`
+	GasUsed          float64        `parquet:"gas_used,optional"`
+	Subtraces        int64          `parquet:"subtraces,optional"`
+	TraceAddress     string         `parquet:"trace_address,optional"`
+	Error            string         `parquet:"error,optional"`
+	Status           int64          `parquet:"status,optional"`
+	BlockTimestamp   Int96Timestamp `parquet:"block_timestamp,optional"`
+	BlockNumber      int64          `parquet:"block_number"`
+	BlockHash        string         `parquet:"block_hash"`
+	TraceID          string         `parquet:"trace_id,optional"`
+}
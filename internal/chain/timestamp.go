@@ -0,0 +1,149 @@
+package chain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+)
+
+// timeUnit returns the unit of an INT64 column with a TIMESTAMP logical type
+func timeUnit(t parquet.Type) (time.Duration, bool) {
+	if t.Kind() != parquet.Int64 {
+		return 0, false
+	}
+	lt := t.LogicalType()
+	if lt == nil || lt.Timestamp == nil {
+		return 0, false
+	}
+	switch unit := lt.Timestamp.Unit; {
+	case unit.Millis != nil:
+		return time.Millisecond, true
+	case unit.Micros != nil:
+		return time.Microsecond, true
+	case unit.Nanos != nil:
+		return time.Nanosecond, true
+	}
+	return 0, false
+}
+
+// int96FromUnixNano encodes nanoseconds since the Unix epoch as an int96
+// timestamp: nanoseconds of the day, then the Julian day
+func int96FromUnixNano(nsec int64) deprecated.Int96 {
+	const nsecPerDay = secPerDay * int64(time.Second)
+	days := nsec / nsecPerDay
+	nsec -= days * nsecPerDay
+	if nsec < 0 {
+		days--
+		nsec += nsecPerDay
+	}
+	return deprecated.Int96{uint32(nsec), uint32(nsec >> 32), uint32(days + jan011970)}
+}
+
+// ValueTime decodes a timestamp value of a column of type t: INT96, or INT64
+// in the unit of its TIMESTAMP logical type. INT64 values without a logical
+// type fall back to the unit their magnitude implies.
+func ValueTime(v parquet.Value, t parquet.Type) time.Time {
+	if unit, ok := timeUnit(t); ok && !v.IsNull() {
+		return time.Unix(0, v.Int64()*int64(unit)).UTC()
+	}
+	var ts Int96Timestamp
+	if err := ts.UnmarshalParquet(v); err != nil {
+		return time.Time{}
+	}
+	return ts.Time()
+}
+
+// NormalizeTimestamps returns rowGroup converted to schema, with its INT64
+// TIMESTAMP columns read as INT96 in the unit their logical type records.
+// Converting an INT64 column into the fixed_len_byte_array(12) of
+// Int96Timestamp copies the raw value and drops the unit, so the timestamps are
+// normalized before the rest of the conversion. Row groups without INT64
+// timestamps are returned as is.
+func NormalizeTimestamps(rowGroup parquet.RowGroup, schema *parquet.Schema) (parquet.RowGroup, error) {
+	source := rowGroup.Schema()
+	units := make(map[int]time.Duration)
+	for _, path := range source.Columns() {
+		leaf, ok := source.Lookup(path...)
+		if !ok {
+			continue
+		}
+		if unit, ok := timeUnit(leaf.Node.Type()); ok {
+			units[leaf.ColumnIndex] = unit
+		}
+	}
+	if len(units) == 0 {
+		return rowGroup, nil
+	}
+	column := 0
+	root := int96Group{Node: source, fields: int96Fields(source.Fields(), units, &column)}
+	conv, err := parquet.Convert(schema, parquet.NewSchema(source.Name(), root))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert schema: %w", err)
+	}
+	// The timestamps are converted in the same conversion as the rest, because a
+	// reader converting rowGroup again reads its column chunks directly
+	return parquet.ConvertRowGroup(rowGroup, int96Conversion{Conversion: conv, units: units}), nil
+}
+
+// int96Fields wraps fields so the leaves whose column index is in units have
+// the INT96 type. column is the index of the first leaf of fields.
+func int96Fields(fields []parquet.Field, units map[int]time.Duration, column *int) []parquet.Field {
+	wrapped := make([]parquet.Field, len(fields))
+	for i, field := range fields {
+		if field.Leaf() {
+			_, ok := units[*column]
+			wrapped[i] = int96Field{Field: field, int96: ok}
+			*column++
+			continue
+		}
+		wrapped[i] = int96Field{Field: field, fields: int96Fields(field.Fields(), units, column)}
+	}
+	return wrapped
+}
+
+// int96Group is the root of a schema with INT64 timestamp leaves read as INT96
+type int96Group struct {
+	parquet.Node
+	fields []parquet.Field
+}
+
+func (g int96Group) Fields() []parquet.Field { return g.fields }
+
+// int96Field is a field of a schema with INT64 timestamp leaves read as INT96
+type int96Field struct {
+	parquet.Field
+	int96  bool
+	fields []parquet.Field
+}
+
+func (f int96Field) Type() parquet.Type {
+	if f.int96 {
+		return parquet.Int96Type
+	}
+	return f.Field.Type()
+}
+
+func (f int96Field) Fields() []parquet.Field { return f.fields }
+
+// int96Conversion rewrites the INT64 timestamps of a row as INT96 before
+// applying the conversion to the target schema
+type int96Conversion struct {
+	parquet.Conversion
+	units map[int]time.Duration
+}
+
+func (c int96Conversion) Convert(rows []parquet.Row) (int, error) {
+	for _, row := range rows {
+		for i, v := range row {
+			unit, ok := c.units[v.Column()]
+			if !ok || v.IsNull() {
+				continue
+			}
+			ts := int96FromUnixNano(v.Int64() * int64(unit))
+			row[i] = parquet.Int96Value(ts).Level(v.RepetitionLevel(), v.DefinitionLevel(), v.Column())
+		}
+	}
+	return c.Conversion.Convert(rows)
+}
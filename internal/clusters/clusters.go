@@ -0,0 +1,76 @@
+// Package clusters groups Bitcoin addresses into entities with the
+// common-input-ownership heuristic: the addresses a transaction spends from
+// are assumed to share an owner, since one party signed for all of them.
+// Coinbase transactions spend nothing, and transactions the privacy
+// classifier labels as multi-party are skipped because their inputs
+// deliberately belong to different parties.
+package clusters
+
+import "github.com/siddon/web3insights/internal/privacy"
+
+// LinkedAddresses returns the distinct input addresses of tx that the
+// heuristic links, or nil if tx links none
+func LinkedAddresses(tx privacy.Transaction) []string {
+	if tx.Coinbase || len(tx.Inputs) < 2 {
+		return nil
+	}
+	seen := make(map[string]bool, len(tx.Inputs))
+	var addresses []string
+	for _, input := range tx.Inputs {
+		if input.Address != "" && !seen[input.Address] {
+			seen[input.Address] = true
+			addresses = append(addresses, input.Address)
+		}
+	}
+	if len(addresses) < 2 || privacy.Classify(tx).MultiParty() {
+		return nil
+	}
+	return addresses
+}
+
+// UnionFind is an in-memory union-find over dense integer ids, with union by
+// size and path halving
+type UnionFind struct {
+	parent []int
+	size   []int
+}
+
+// Add adds a singleton set and returns its id
+func (u *UnionFind) Add() int {
+	id := len(u.parent)
+	u.parent = append(u.parent, id)
+	u.size = append(u.size, 1)
+	return id
+}
+
+// Find returns the representative of the set holding id
+func (u *UnionFind) Find(id int) int {
+	for u.parent[id] != id {
+		u.parent[id] = u.parent[u.parent[id]]
+		id = u.parent[id]
+	}
+	return id
+}
+
+// Union merges the sets holding a and b
+func (u *UnionFind) Union(a, b int) {
+	a, b = u.Find(a), u.Find(b)
+	if a == b {
+		return
+	}
+	if u.size[a] < u.size[b] {
+		a, b = b, a
+	}
+	u.parent[b] = a
+	u.size[a] += u.size[b]
+}
+
+// Sets returns the ids of each set, keyed by representative
+func (u *UnionFind) Sets() map[int][]int {
+	sets := make(map[int][]int)
+	for id := range u.parent {
+		root := u.Find(id)
+		sets[root] = append(sets[root], id)
+	}
+	return sets
+}
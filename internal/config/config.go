@@ -18,10 +18,40 @@ type Config struct {
 	LogLevel   string
 	DryRun     bool
 	Chain      string
-	MaxFiles   int
 	MaxRetries int
 	OutDir     string
 
+	// Local parquet cache under OutDir
+	CacheMaxBytes int64
+	CachePolicy   string
+
+	// Local analytical lake written by compact
+	LakeDir string
+
+	// Schema drift accepted by sync: comma-separated added, missing, retyped,
+	// or all (empty fails on any drift)
+	AllowSchemaDrift string
+
+	// Parquet decoding in the loaders: row groups decoded in parallel, and
+	// comma-separated "<dataset>.<column>" columns left undecoded (loaded as
+	// zero values), e.g. "transactions.inputs.script_asm"
+	ReadConcurrency int
+	SkipColumns     string
+
+	// Column profile of each table: comma-separated full, lean or minimal, with
+	// "<table>=<profile>" entries overriding single tables (empty means full)
+	ColumnProfiles string
+
+	// Derivation of btc output addresses from script_hex: off, missing or canonical
+	DeriveAddresses string
+
+	// Comma-separated analyzers writing derived tables during sync, e.g.
+	// "op_returns", or "all" (empty runs none)
+	Analyzers string
+
+	// Mining pool database of the miners analyzer (empty uses the bundled copy)
+	PoolsFile string
+
 	// Batch sizes for database inserts
 	TransactionBatchSize int
 	BlockBatchSize       int
@@ -32,6 +62,12 @@ type Config struct {
 	AWSRegion      string
 	AWSS3Bucket    string
 	AWSS3BTCPrefix string
+	AWSS3ETHPrefix string
+
+	// Download shaping (0 means unlimited)
+	DownloadConcurrency    int
+	DownloadMaxBytesPerSec int64
+	S3MaxRequestsPerSec    int
 
 	// TiDB Cloud OpenAPI
 	TiDBDatabase    string
@@ -75,15 +111,43 @@ func LoadFromPath(configPath string) (*Config, error) {
 	if v := getEnv("WEB3INSIGHTS_CHAIN", ""); v != "" {
 		cfg.Chain = v
 	}
-	if isSet("WEB3INSIGHTS_MAX_FILES") {
-		cfg.MaxFiles = getEnvInt("WEB3INSIGHTS_MAX_FILES", cfg.MaxFiles)
-	}
 	if isSet("WEB3INSIGHTS_MAX_RETRIES") {
 		cfg.MaxRetries = getEnvInt("WEB3INSIGHTS_MAX_RETRIES", cfg.MaxRetries)
 	}
 	if v := getEnv("WEB3INSIGHTS_OUT_DIR", ""); v != "" {
 		cfg.OutDir = v
 	}
+	if v := getEnv("WEB3INSIGHTS_CACHE_MAX_BYTES", ""); v != "" {
+		cfg.CacheMaxBytes = parseSize(v, cfg.CacheMaxBytes)
+	}
+	if v := getEnv("WEB3INSIGHTS_CACHE_POLICY", ""); v != "" {
+		cfg.CachePolicy = v
+	}
+	if v := getEnv("WEB3INSIGHTS_LAKE_DIR", ""); v != "" {
+		cfg.LakeDir = v
+	}
+	if isSet("WEB3INSIGHTS_ALLOW_SCHEMA_DRIFT") {
+		cfg.AllowSchemaDrift = os.Getenv("WEB3INSIGHTS_ALLOW_SCHEMA_DRIFT")
+	}
+
+	if isSet("WEB3INSIGHTS_READ_CONCURRENCY") {
+		cfg.ReadConcurrency = getEnvInt("WEB3INSIGHTS_READ_CONCURRENCY", cfg.ReadConcurrency)
+	}
+	if isSet("WEB3INSIGHTS_SKIP_COLUMNS") {
+		cfg.SkipColumns = os.Getenv("WEB3INSIGHTS_SKIP_COLUMNS")
+	}
+	if isSet("WEB3INSIGHTS_COLUMN_PROFILES") {
+		cfg.ColumnProfiles = os.Getenv("WEB3INSIGHTS_COLUMN_PROFILES")
+	}
+	if isSet("WEB3INSIGHTS_DERIVE_ADDRESSES") {
+		cfg.DeriveAddresses = os.Getenv("WEB3INSIGHTS_DERIVE_ADDRESSES")
+	}
+	if isSet("WEB3INSIGHTS_ANALYZERS") {
+		cfg.Analyzers = os.Getenv("WEB3INSIGHTS_ANALYZERS")
+	}
+	if isSet("WEB3INSIGHTS_POOLS_FILE") {
+		cfg.PoolsFile = os.Getenv("WEB3INSIGHTS_POOLS_FILE")
+	}
 
 	if isSet("WEB3INSIGHTS_TRANSACTION_BATCH_SIZE") {
 		cfg.TransactionBatchSize = getEnvInt("WEB3INSIGHTS_TRANSACTION_BATCH_SIZE", cfg.TransactionBatchSize)
@@ -107,6 +171,18 @@ func LoadFromPath(configPath string) (*Config, error) {
 	if v := getEnv("WEB3INSIGHTS_AWS_BTC_PREFIX", ""); v != "" {
 		cfg.AWSS3BTCPrefix = v
 	}
+	if v := getEnv("WEB3INSIGHTS_AWS_ETH_PREFIX", ""); v != "" {
+		cfg.AWSS3ETHPrefix = v
+	}
+	if isSet("WEB3INSIGHTS_DOWNLOAD_CONCURRENCY") {
+		cfg.DownloadConcurrency = getEnvInt("WEB3INSIGHTS_DOWNLOAD_CONCURRENCY", cfg.DownloadConcurrency)
+	}
+	if v := getEnv("WEB3INSIGHTS_DOWNLOAD_MAX_BYTES_PER_SEC", ""); v != "" {
+		cfg.DownloadMaxBytesPerSec = parseSize(v, cfg.DownloadMaxBytesPerSec)
+	}
+	if isSet("WEB3INSIGHTS_S3_MAX_REQUESTS_PER_SEC") {
+		cfg.S3MaxRequestsPerSec = getEnvInt("WEB3INSIGHTS_S3_MAX_REQUESTS_PER_SEC", cfg.S3MaxRequestsPerSec)
+	}
 
 	if v := getEnv("TIDB_DATABASE", ""); v != "" {
 		cfg.TiDBDatabase = v
@@ -134,15 +210,18 @@ func LoadFromPath(configPath string) (*Config, error) {
 	if cfg.Chain == "" {
 		cfg.Chain = "bitcoin"
 	}
-	if cfg.MaxFiles == 0 {
-		cfg.MaxFiles = 10
-	}
 	if cfg.MaxRetries == 0 {
 		cfg.MaxRetries = 3
 	}
 	if cfg.OutDir == "" {
 		cfg.OutDir = "out"
 	}
+	if cfg.LakeDir == "" {
+		cfg.LakeDir = "lake"
+	}
+	if cfg.CachePolicy == "" {
+		cfg.CachePolicy = "lru"
+	}
 	if cfg.AWSRegion == "" {
 		cfg.AWSRegion = "us-east-2"
 	}
@@ -152,6 +231,15 @@ func LoadFromPath(configPath string) (*Config, error) {
 	if cfg.AWSS3BTCPrefix == "" {
 		cfg.AWSS3BTCPrefix = "v1.0/btc/"
 	}
+	if cfg.AWSS3ETHPrefix == "" {
+		cfg.AWSS3ETHPrefix = "v1.0/eth/"
+	}
+	if cfg.DownloadConcurrency <= 0 {
+		cfg.DownloadConcurrency = 1
+	}
+	if cfg.ReadConcurrency <= 0 {
+		cfg.ReadConcurrency = 4
+	}
 	if cfg.TiDBDatabase == "" {
 		cfg.TiDBDatabase = "web3insights"
 	}
@@ -171,6 +259,10 @@ func LoadFromPath(configPath string) (*Config, error) {
 		cfg.OutputBatchSize = 50
 	}
 
+	if err := ValidateCachePolicy(cfg.CachePolicy); err != nil {
+		return nil, err
+	}
+
 	if cfg.TiDBSQLHost == "" || cfg.TiDBSQLUser == "" {
 		// SQL connectivity is only required for DDL and fallback path,
 		// but we enforce it up front to keep behaviour predictable.
@@ -180,6 +272,16 @@ func LoadFromPath(configPath string) (*Config, error) {
 	return cfg, nil
 }
 
+// ValidateCachePolicy checks that policy is a cache eviction policy the cache
+// manager supports: lru or oldest
+func ValidateCachePolicy(policy string) error {
+	switch policy {
+	case "lru", "oldest":
+		return nil
+	}
+	return fmt.Errorf("invalid cache_policy: %s (expected lru or oldest)", policy)
+}
+
 func getEnv(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -273,12 +375,31 @@ func applyKeyValue(cfg *Config, key, value string) {
 		cfg.DryRun = parseBool(value, cfg.DryRun)
 	case "chain":
 		cfg.Chain = value
-	case "max_files":
-		cfg.MaxFiles = parseInt(value, cfg.MaxFiles)
 	case "max_retries":
 		cfg.MaxRetries = parseInt(value, cfg.MaxRetries)
 	case "out_dir":
 		cfg.OutDir = value
+	case "cache_max_bytes":
+		cfg.CacheMaxBytes = parseSize(value, cfg.CacheMaxBytes)
+	case "cache_policy":
+		cfg.CachePolicy = value
+	case "lake_dir":
+		cfg.LakeDir = value
+	case "allow_schema_drift":
+		cfg.AllowSchemaDrift = value
+
+	case "read_concurrency":
+		cfg.ReadConcurrency = parseInt(value, cfg.ReadConcurrency)
+	case "skip_columns":
+		cfg.SkipColumns = value
+	case "column_profiles":
+		cfg.ColumnProfiles = value
+	case "derive_addresses":
+		cfg.DeriveAddresses = value
+	case "analyzers":
+		cfg.Analyzers = value
+	case "pools_file":
+		cfg.PoolsFile = value
 
 	case "transaction_batch_size":
 		cfg.TransactionBatchSize = parseInt(value, cfg.TransactionBatchSize)
@@ -295,6 +416,14 @@ func applyKeyValue(cfg *Config, key, value string) {
 		cfg.AWSS3Bucket = value
 	case "aws_btc_prefix":
 		cfg.AWSS3BTCPrefix = value
+	case "aws_eth_prefix":
+		cfg.AWSS3ETHPrefix = value
+	case "download_concurrency":
+		cfg.DownloadConcurrency = parseInt(value, cfg.DownloadConcurrency)
+	case "download_max_bytes_per_sec":
+		cfg.DownloadMaxBytesPerSec = parseSize(value, cfg.DownloadMaxBytesPerSec)
+	case "s3_max_requests_per_sec":
+		cfg.S3MaxRequestsPerSec = parseInt(value, cfg.S3MaxRequestsPerSec)
 
 	case "tidb_database":
 		cfg.TiDBDatabase = value
@@ -320,6 +449,44 @@ func parseInt(v string, def int) int {
 	return i
 }
 
+func parseSize(v string, def int64) int64 {
+	if strings.TrimSpace(v) == "" {
+		return def
+	}
+	size, err := ParseSize(v)
+	if err != nil {
+		return def
+	}
+	return size
+}
+
+// ParseSize parses a byte size such as "500MB", "20G" or "1073741824".
+// Suffixes are binary multiples (K = 1024) and are case-insensitive.
+func ParseSize(v string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(v)), "B")
+	multiplier := int64(1)
+	if n := len(s); n > 0 {
+		switch s[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			s = strings.TrimSpace(s[:n-1])
+		}
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid size: %q", v)
+	}
+	return i * multiplier, nil
+}
+
 func parseBool(v string, def bool) bool {
 	switch strings.ToLower(v) {
 	case "1", "true", "yes", "y", "on":
@@ -0,0 +1,52 @@
+// Package consensus holds the Bitcoin consensus rules the analytics need:
+// the block subsidy schedule, block size limits and difficulty retargeting.
+package consensus
+
+// Block subsidy schedule
+const (
+	InitialSubsidy  = 50 * 100_000_000 // Satoshis paid by the genesis era coinbase
+	HalvingInterval = 210000           // Blocks between subsidy halvings
+)
+
+// MaxBlockWeight is the block weight limit in weight units since segwit
+const MaxBlockWeight = 4_000_000
+
+// Difficulty retargeting
+const (
+	RetargetInterval = 2016              // Blocks per difficulty epoch
+	TargetSpacing    = 10 * 60           // Seconds per block the difficulty aims for
+	TargetTimespan   = 14 * 24 * 60 * 60 // Seconds per epoch the difficulty aims for
+	MaxRetarget      = 4                 // Largest factor a retarget moves the difficulty by, either way
+)
+
+// HashesPerDifficulty is the expected number of hashes to mine a block of
+// difficulty 1
+const HashesPerDifficulty = 1 << 32
+
+// RetargetFactor returns the factor the difficulty is multiplied by after an
+// epoch whose blocks took actual seconds, clamped as the consensus rules do
+func RetargetFactor(actual int64) float64 {
+	actual = max(actual, TargetTimespan/MaxRetarget)
+	actual = min(actual, TargetTimespan*MaxRetarget)
+	return float64(TargetTimespan) / float64(actual)
+}
+
+// Subsidy returns the block subsidy in satoshis at height
+func Subsidy(height int64) int64 {
+	halvings := height / HalvingInterval
+	if height < 0 || halvings >= 64 {
+		return 0
+	}
+	return InitialSubsidy >> halvings
+}
+
+// ExpectedSupply returns the satoshis issued by the subsidies of blocks 0
+// through height if every block claimed its full subsidy
+func ExpectedSupply(height int64) int64 {
+	var supply int64
+	for era := int64(0); era*HalvingInterval <= height && era < 64; era++ {
+		blocks := min(height-era*HalvingInterval+1, HalvingInterval)
+		supply += blocks * (InitialSubsidy >> era)
+	}
+	return supply
+}
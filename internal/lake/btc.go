@@ -0,0 +1,178 @@
+package lake
+
+import (
+	"time"
+
+	"github.com/siddon/web3insights/internal/chain"
+)
+
+// Block is a row of the btc blocks table
+type Block struct {
+	RecordDate        int32     `parquet:"record_date,date"`
+	Hash              string    `parquet:"hash"`
+	Size              int64     `parquet:"size,optional"`
+	StrippedSize      int64     `parquet:"stripped_size,optional"`
+	Weight            int64     `parquet:"weight,optional"`
+	Number            int64     `parquet:"number"`
+	Version           int32     `parquet:"version,optional"`
+	MerkleRoot        string    `parquet:"merkle_root,optional"`
+	BlockTimestamp    time.Time `parquet:"block_timestamp,optional,timestamp(microsecond)"`
+	Nonce             int64     `parquet:"nonce,optional"`
+	Bits              string    `parquet:"bits,optional"`
+	CoinbaseParam     string    `parquet:"coinbase_param,optional"`
+	TransactionCount  int64     `parquet:"transaction_count,optional"`
+	Mediantime        time.Time `parquet:"mediantime,optional,timestamp(microsecond)"`
+	Difficulty        float64   `parquet:"difficulty,optional"`
+	Chainwork         string    `parquet:"chainwork,optional"`
+	Previousblockhash string    `parquet:"previousblockhash,optional"`
+}
+
+// Transaction is a row of the btc transactions table, without inputs and outputs
+type Transaction struct {
+	RecordDate     int32     `parquet:"record_date,date"`
+	Hash           string    `parquet:"hash"`
+	Size           int64     `parquet:"size,optional"`
+	VirtualSize    int64     `parquet:"virtual_size,optional"`
+	Version        int64     `parquet:"version,optional"`
+	LockTime       int64     `parquet:"lock_time,optional"`
+	BlockHash      string    `parquet:"block_hash"`
+	BlockNumber    int64     `parquet:"block_number"`
+	BlockTimestamp time.Time `parquet:"block_timestamp,optional,timestamp(microsecond)"`
+	TxIndex        int64     `parquet:"tx_index"`
+	InputCount     int64     `parquet:"input_count,optional"`
+	OutputCount    int64     `parquet:"output_count,optional"`
+	InputValue     float64   `parquet:"input_value,optional"`
+	OutputValue    float64   `parquet:"output_value,optional"`
+	IsCoinbase     bool      `parquet:"is_coinbase,optional"`
+	Fee            float64   `parquet:"fee,optional"`
+}
+
+// Input is a row of the btc transaction_inputs table. It also carries the
+// block number so the table can be sorted and pruned by block.
+type Input struct {
+	RecordDate           int32   `parquet:"record_date,date"`
+	BlockNumber          int64   `parquet:"block_number"`
+	TransactionHash      string  `parquet:"transaction_hash"`
+	InputIndex           int64   `parquet:"input_index"`
+	SpentTransactionHash string  `parquet:"spent_transaction_hash,optional"`
+	SpentOutputIndex     int64   `parquet:"spent_output_index,optional"`
+	ScriptAsm            string  `parquet:"script_asm,optional"`
+	ScriptHex            string  `parquet:"script_hex,optional"`
+	Sequence             int64   `parquet:"sequence,optional"`
+	RequiredSignatures   int64   `parquet:"required_signatures,optional"`
+	InputType            string  `parquet:"input_type,optional"`
+	Address              string  `parquet:"address,optional"`
+	SpentValue           float64 `parquet:"spent_value,optional"`
+}
+
+// Output is a row of the btc transaction_outputs table. It also carries the
+// block number so the table can be sorted and pruned by block.
+type Output struct {
+	RecordDate         int32   `parquet:"record_date,date"`
+	BlockNumber        int64   `parquet:"block_number"`
+	TransactionHash    string  `parquet:"transaction_hash"`
+	OutputIndex        int64   `parquet:"output_index"`
+	ScriptAsm          string  `parquet:"script_asm,optional"`
+	ScriptHex          string  `parquet:"script_hex,optional"`
+	RequiredSignatures int64   `parquet:"required_signatures,optional"`
+	OutputType         string  `parquet:"output_type,optional"`
+	Address            string  `parquet:"address,optional"`
+	OutputAmount       float64 `parquet:"output_amount,optional"`
+}
+
+// epochDays converts a dataset date partition value to days since the unix epoch
+func epochDays(date string) int32 {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0
+	}
+	return int32(t.Unix() / 86400)
+}
+
+func newBlock(b chain.BtcBlock) Block {
+	return Block{
+		RecordDate:        epochDays(b.Date),
+		Hash:              b.Hash,
+		Size:              b.Size,
+		StrippedSize:      b.StrippedSize,
+		Weight:            b.Weight,
+		Number:            b.Number,
+		Version:           b.Version,
+		MerkleRoot:        b.MerkleRoot,
+		BlockTimestamp:    b.Timestamp.Time(),
+		Nonce:             b.Nonce,
+		Bits:              b.Bits,
+		CoinbaseParam:     b.CoinbaseParam,
+		TransactionCount:  b.TransactionCount,
+		Mediantime:        b.Mediantime.Time(),
+		Difficulty:        b.Difficulty,
+		Chainwork:         b.Chainwork,
+		Previousblockhash: b.Previousblockhash,
+	}
+}
+
+func newTransaction(tx chain.BtcTransaction) Transaction {
+	return Transaction{
+		RecordDate:     epochDays(tx.Date),
+		Hash:           tx.Hash,
+		Size:           tx.Size,
+		VirtualSize:    tx.VirtualSize,
+		Version:        tx.Version,
+		LockTime:       tx.LockTime,
+		BlockHash:      tx.BlockHash,
+		BlockNumber:    tx.BlockNumber,
+		BlockTimestamp: tx.BlockTimestamp.Time(),
+		TxIndex:        tx.Index,
+		InputCount:     tx.InputCount,
+		OutputCount:    tx.OutputCount,
+		InputValue:     tx.InputValue,
+		OutputValue:    tx.OutputValue,
+		IsCoinbase:     tx.IsCoinbase,
+		Fee:            tx.Fee,
+	}
+}
+
+// newInputs flattens the inputs of a transaction the same way the TiDB loader does
+func newInputs(tx chain.BtcTransaction) []Input {
+	date := epochDays(tx.Date)
+	inputs := make([]Input, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		inputs[i] = Input{
+			RecordDate:           date,
+			BlockNumber:          tx.BlockNumber,
+			TransactionHash:      tx.Hash,
+			InputIndex:           int64(i),
+			SpentTransactionHash: input.SpentTransactionHash,
+			SpentOutputIndex:     input.SpentOutputIndex,
+			ScriptAsm:            input.ScriptAsm,
+			ScriptHex:            input.ScriptHex,
+			Sequence:             input.Sequence,
+			RequiredSignatures:   input.RequiredSignatures,
+			InputType:            input.Type,
+			Address:              input.Address,
+			SpentValue:           input.Value,
+		}
+	}
+	return inputs
+}
+
+// newOutputs flattens the outputs of a transaction the same way the TiDB loader does
+func newOutputs(tx chain.BtcTransaction) []Output {
+	date := epochDays(tx.Date)
+	outputs := make([]Output, len(tx.Outputs))
+	for i, output := range tx.Outputs {
+		outputs[i] = Output{
+			RecordDate:         date,
+			BlockNumber:        tx.BlockNumber,
+			TransactionHash:    tx.Hash,
+			OutputIndex:        int64(i),
+			ScriptAsm:          output.ScriptAsm,
+			ScriptHex:          output.ScriptHex,
+			RequiredSignatures: output.RequiredSignatures,
+			OutputType:         output.Type,
+			Address:            output.Address,
+			OutputAmount:       output.Value,
+		}
+	}
+	return outputs
+}
@@ -0,0 +1,261 @@
+// Package lake converts the daily AWS parquet files into a local analytical lake.
+//
+// Nested datasets are flattened into one table per entity the same way the TiDB
+// loader does (transactions, transaction_inputs, transaction_outputs). Each date
+// becomes one zstd-compressed file sorted by block number, stored in Hive-style
+// monthly partitions:
+//
+//	<root>/<chain>/<table>/month=YYYY-MM/YYYY-MM-DD.parquet
+//
+// so the lake can be queried directly by DuckDB or Spark without a database.
+package lake
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/chain"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/registry"
+)
+
+// sortRowCount is the number of rows sorted in memory before a sorted run is
+// spilled to a temporary file and merged at the end
+const sortRowCount = 100_000
+
+// Result describes one table file written (or skipped) for a date
+type Result struct {
+	Table   string
+	Path    string
+	Rows    int64
+	Skipped bool // The file already existed and -force was not set
+}
+
+// Supported reports whether the lake layout is defined for a chain
+func Supported(c *registry.Chain) bool {
+	return c.Name == "btc"
+}
+
+// TablePath returns the file of one table for one date
+func TablePath(root, chainName, table, date string) string {
+	month := date
+	if len(date) >= 7 {
+		month = date[:7]
+	}
+	return filepath.Join(root, chainName, table, "month="+month, date+".parquet")
+}
+
+// Compact converts the downloaded files of a date into lake tables under root.
+// Existing table files are kept unless force is set.
+func Compact(cfg *config.Config, c *registry.Chain, root, date string, force bool) ([]Result, error) {
+	if !Supported(c) {
+		return nil, fmt.Errorf("compact does not support chain %s (supported: btc)", c.Name)
+	}
+	blocks, err := compactBtcBlocks(cfg, c, root, date, force)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := compactBtcTransactions(cfg, c, root, date, force)
+	if err != nil {
+		return nil, err
+	}
+	return append(blocks, transactions...), nil
+}
+
+func compactBtcBlocks(cfg *config.Config, c *registry.Chain, root, date string, force bool) ([]Result, error) {
+	path := TablePath(root, c.Name, "blocks", date)
+	if exists(path) && !force {
+		return []Result{{Table: "blocks", Path: path, Skipped: true}}, nil
+	}
+
+	dataset, _ := c.Dataset("blocks")
+	w, err := newTableWriter[Block](path, parquet.Ascending("number"))
+	if err != nil {
+		return nil, err
+	}
+	err = readDir(c.DateDir(cfg, dataset.Name, date), dataset, func(row any) error {
+		return w.write(newBlock(row.(chain.BtcBlock)))
+	})
+	if err != nil {
+		w.abort()
+		return nil, err
+	}
+	if err := w.commit(); err != nil {
+		return nil, err
+	}
+	return []Result{{Table: "blocks", Path: path, Rows: w.rows}}, nil
+}
+
+func compactBtcTransactions(cfg *config.Config, c *registry.Chain, root, date string, force bool) ([]Result, error) {
+	txPath := TablePath(root, c.Name, "transactions", date)
+	inputPath := TablePath(root, c.Name, "transaction_inputs", date)
+	outputPath := TablePath(root, c.Name, "transaction_outputs", date)
+	if exists(txPath) && exists(inputPath) && exists(outputPath) && !force {
+		return []Result{
+			{Table: "transactions", Path: txPath, Skipped: true},
+			{Table: "transaction_inputs", Path: inputPath, Skipped: true},
+			{Table: "transaction_outputs", Path: outputPath, Skipped: true},
+		}, nil
+	}
+
+	txs, err := newTableWriter[Transaction](txPath, parquet.Ascending("block_number"), parquet.Ascending("tx_index"))
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := newTableWriter[Input](inputPath,
+		parquet.Ascending("block_number"), parquet.Ascending("transaction_hash"), parquet.Ascending("input_index"))
+	if err != nil {
+		txs.abort()
+		return nil, err
+	}
+	outputs, err := newTableWriter[Output](outputPath,
+		parquet.Ascending("block_number"), parquet.Ascending("transaction_hash"), parquet.Ascending("output_index"))
+	if err != nil {
+		txs.abort()
+		inputs.abort()
+		return nil, err
+	}
+	abort := func() {
+		txs.abort()
+		inputs.abort()
+		outputs.abort()
+	}
+
+	dataset, _ := c.Dataset("transactions")
+	err = readDir(c.DateDir(cfg, dataset.Name, date), dataset, func(row any) error {
+		tx := row.(chain.BtcTransaction)
+		if err := txs.write(newTransaction(tx)); err != nil {
+			return err
+		}
+		if err := inputs.write(newInputs(tx)...); err != nil {
+			return err
+		}
+		return outputs.write(newOutputs(tx)...)
+	})
+	if err != nil {
+		abort()
+		return nil, err
+	}
+	for _, w := range []interface{ commit() error }{txs, inputs, outputs} {
+		if err := w.commit(); err != nil {
+			abort()
+			return nil, err
+		}
+	}
+
+	return []Result{
+		{Table: "transactions", Path: txPath, Rows: txs.rows},
+		{Table: "transaction_inputs", Path: inputPath, Rows: inputs.rows},
+		{Table: "transaction_outputs", Path: outputPath, Rows: outputs.rows},
+	}, nil
+}
+
+// readDir reads the rows of every parquet file in dir, in file name order
+func readDir(dir string, dataset registry.Dataset, fn func(row any) error) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no %s files in %s (run download first)", dataset.Name, dir)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := readFile(path, dataset, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(path string, dataset registry.Dataset, fn func(row any) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	parquetFile, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file %s: %w", path, err)
+	}
+	if err := dataset.ReadFile(parquetFile, fn); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// tableWriter writes one sorted, zstd-compressed table file. Rows go to a
+// temporary file that replaces the final path only once complete.
+type tableWriter[T any] struct {
+	file   *os.File
+	writer *parquet.SortingWriter[T]
+	path   string
+	rows   int64
+}
+
+func newTableWriter[T any](path string, sorting ...parquet.SortingColumn) (*tableWriter[T], error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	writer := parquet.NewSortingWriter[T](file, sortRowCount,
+		parquet.Compression(&parquet.Zstd),
+		parquet.SortingWriterConfig(
+			parquet.SortingColumns(sorting...),
+			// Spill sorted runs to disk so a day of inputs doesn't have to fit in memory
+			parquet.SortingBuffers(parquet.NewFileBufferPool("", "web3insights-compact-*")),
+		),
+	)
+	return &tableWriter[T]{file: file, writer: writer, path: path}, nil
+}
+
+func (w *tableWriter[T]) write(rows ...T) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if _, err := w.writer.Write(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.path, err)
+	}
+	w.rows += int64(len(rows))
+	return nil
+}
+
+// commit finishes the file and moves it into place
+func (w *tableWriter[T]) commit() error {
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish %s: %w", w.path, err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", w.path, err)
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", w.path, err)
+	}
+	return nil
+}
+
+// abort removes the temporary file
+func (w *tableWriter[T]) abort() {
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,204 @@
+// Package opreturn extracts the payloads of OP_RETURN outputs and tags the
+// protocol that wrote them from known prefixes.
+//
+// Counterparty payloads are ARC4-encrypted with the txid of the transaction's
+// first spent output, so tagging them needs that txid; the other protocols are
+// recognized from the payload alone.
+package opreturn
+
+import (
+	"bytes"
+	"crypto/rc4"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/siddon/web3insights/internal/script"
+)
+
+// Protocol names the protocol an OP_RETURN payload belongs to
+type Protocol string
+
+const (
+	Unknown           Protocol = "unknown"
+	WitnessCommitment Protocol = "witness_commitment" // Segwit commitment of coinbase transactions
+	Runes             Protocol = "runes"              // Runestones: OP_RETURN OP_13 <data>
+	Omni              Protocol = "omni"
+	Counterparty      Protocol = "counterparty"
+	Stamps            Protocol = "stamps" // Counterparty issuances carrying a "stamp:" description
+	VeriBlock         Protocol = "veriblock"
+	RSK               Protocol = "rsk" // RSK merge-mining tag of coinbase transactions
+	OpenAssets        Protocol = "open_assets"
+	Stacks            Protocol = "stacks"
+	Blockstack        Protocol = "blockstack"
+	Babylon           Protocol = "babylon"
+	CoreDAO           Protocol = "coredao"
+	DocProof          Protocol = "docproof"
+	EternityWall      Protocol = "eternity_wall"
+	Factom            Protocol = "factom"
+)
+
+// prefixes maps payload prefixes to protocols. Longer prefixes are listed
+// before shorter ones that they extend.
+var prefixes = []struct {
+	prefix   []byte
+	protocol Protocol
+}{
+	{[]byte("RSKBLOCK:"), RSK},
+	{[]byte("CNTRPRTY"), Counterparty}, // Unencrypted messages of early Counterparty versions
+	{[]byte("DOCPROOF"), DocProof},
+	{[]byte("Factom!!"), Factom},
+	{[]byte("omni"), Omni},
+	{[]byte("bbn1"), Babylon},
+	{[]byte("CORE"), CoreDAO},
+	{[]byte("OA\x01\x00"), OpenAssets},
+	{[]byte("EW "), EternityWall},
+	{[]byte("X2"), Stacks},
+	{[]byte("id"), Blockstack},
+}
+
+var (
+	witnessCommitmentHeader = []byte{0xaa, 0x21, 0xa9, 0xed}
+	counterpartyPrefix      = []byte("CNTRPRTY")
+	stampPrefix             = []byte("stamp:")
+)
+
+// veriBlockPayloadSize is the size of a VeriBlock proof-of-proof publication:
+// a 64-byte VeriBlock header followed by a 16-byte miner id
+const veriBlockPayloadSize = 80
+
+// Offsets of the VeriBlock header fields checked before tagging a publication:
+// height (4 bytes), version (2), previous block (12), two keystones (9 each),
+// merkle root (16), timestamp (4), difficulty (4) and nonce (4), big-endian
+const (
+	veriBlockVersionOffset    = 4
+	veriBlockTimestampOffset  = 52
+	veriBlockDifficultyOffset = 56
+)
+
+// VeriBlock header timestamps fall between the mainnet launch (March 2019) and
+// an upper bound far enough out that it never rejects a real publication
+const (
+	minVeriBlockTimestamp = 1551398400 // 2019-03-01
+	maxVeriBlockTimestamp = 2524608000 // 2050-01-01
+)
+
+// Payload is the data carried by an OP_RETURN output
+type Payload struct {
+	Data     []byte // Data pushes after OP_RETURN, concatenated
+	Pushes   int    // Number of data pushes
+	Protocol Protocol
+}
+
+// Parse extracts the payload of an OP_RETURN script. firstInputTxid is the txid
+// of the output spent by the transaction's first input, used to decrypt
+// Counterparty messages; it may be empty. ok is false for scripts that don't
+// start with OP_RETURN.
+func Parse(scriptBytes []byte, firstInputTxid string) (Payload, bool) {
+	if len(scriptBytes) == 0 || script.Opcode(scriptBytes[0]) != script.OP_RETURN {
+		return Payload{}, false
+	}
+
+	// Truncated and non-push scripts still carry the data pushed before the problem
+	instructions, _ := script.Disassemble(scriptBytes[1:])
+	var p Payload
+	for _, instruction := range instructions {
+		if !instruction.Op.IsPush() {
+			continue
+		}
+		p.Data = append(p.Data, instruction.Data...)
+		p.Pushes++
+	}
+
+	runestone := len(instructions) > 0 && instructions[0].Op == script.OP_13
+	p.Protocol = detect(p.Data, runestone, firstInputTxid)
+	return p, true
+}
+
+// ParseHex decodes script_hex and extracts its OP_RETURN payload
+func ParseHex(scriptHex, firstInputTxid string) (Payload, bool, error) {
+	scriptBytes, err := hex.DecodeString(scriptHex)
+	if err != nil {
+		return Payload{}, false, fmt.Errorf("failed to decode script hex: %w", err)
+	}
+	p, ok := Parse(scriptBytes, firstInputTxid)
+	return p, ok, nil
+}
+
+// Text returns the payload as a string if it is valid UTF-8 without control
+// characters other than whitespace
+func (p Payload) Text() (string, bool) {
+	if len(p.Data) == 0 || !utf8.Valid(p.Data) {
+		return "", false
+	}
+	for _, r := range string(p.Data) {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return string(p.Data), true
+}
+
+// detect tags a payload with the protocol whose prefix or layout it matches
+func detect(data []byte, runestone bool, firstInputTxid string) Protocol {
+	if runestone {
+		return Runes
+	}
+	if len(data) == 36 && bytes.HasPrefix(data, witnessCommitmentHeader) {
+		return WitnessCommitment
+	}
+	if protocol, ok := counterparty(data, firstInputTxid); ok {
+		return protocol
+	}
+	for _, p := range prefixes {
+		if bytes.HasPrefix(data, p.prefix) {
+			return p.protocol
+		}
+	}
+	if veriBlock(data) {
+		return VeriBlock
+	}
+	return Unknown
+}
+
+// veriBlock reports whether data is a VeriBlock publication. 80 bytes is also
+// the standard OP_RETURN relay limit, so the header fields must be plausible:
+// a known version, a positive height, a timestamp from the VeriBlock era and
+// nonzero difficulty bits.
+func veriBlock(data []byte) bool {
+	if len(data) != veriBlockPayloadSize {
+		return false
+	}
+	height := binary.BigEndian.Uint32(data)
+	version := binary.BigEndian.Uint16(data[veriBlockVersionOffset:])
+	timestamp := binary.BigEndian.Uint32(data[veriBlockTimestampOffset:])
+	difficulty := binary.BigEndian.Uint32(data[veriBlockDifficultyOffset:])
+	return height > 0 && height < 1<<31 &&
+		version >= 1 && version <= 3 &&
+		timestamp >= minVeriBlockTimestamp && timestamp < maxVeriBlockTimestamp &&
+		difficulty != 0
+}
+
+// counterparty decrypts a payload with the first input's txid and checks for
+// the Counterparty prefix
+func counterparty(data []byte, firstInputTxid string) (Protocol, bool) {
+	key, err := hex.DecodeString(firstInputTxid)
+	if err != nil || len(key) == 0 || len(data) < len(counterpartyPrefix) {
+		return "", false
+	}
+	cipher, err := rc4.NewCipher(key)
+	if err != nil {
+		return "", false
+	}
+	message := make([]byte, len(data))
+	cipher.XORKeyStream(message, data)
+	if !bytes.HasPrefix(message, counterpartyPrefix) {
+		return "", false
+	}
+	if bytes.Contains(bytes.ToLower(message), stampPrefix) {
+		return Stamps, true
+	}
+	return Counterparty, true
+}
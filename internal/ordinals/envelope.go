@@ -0,0 +1,159 @@
+// Package ordinals finds ordinal inscription envelopes in taproot script-path
+// spends.
+//
+// An inscription is revealed by spending a taproot output through a tapscript
+// that contains an envelope:
+//
+//	OP_FALSE OP_IF "ord" <tag> <value> ... OP_0 <body> ... OP_ENDIF
+//
+// The tapscript is the second-to-last witness element of the input, followed
+// by the control block and an optional annex. Witness data is therefore
+// required; scripts alone don't carry inscriptions.
+package ordinals
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/siddon/web3insights/internal/script"
+)
+
+// Envelope field tags, as pushed before their values
+const (
+	TagContentType     = 1
+	TagPointer         = 2
+	TagParent          = 3
+	TagMetadata        = 5
+	TagMetaprotocol    = 7
+	TagContentEncoding = 9
+	TagDelegate        = 11
+)
+
+var protocolID = []byte("ord")
+
+// annexTag is the first byte of an optional last witness element (BIP-341)
+const annexTag = 0x50
+
+// Inscription is the content of one envelope
+type Inscription struct {
+	ContentType     string
+	ContentEncoding string
+	Metaprotocol    string
+	Body            []byte
+	Fields          map[int][]byte // Every field by tag; unknown tags are kept too
+}
+
+// Tapscript returns the script of a taproot script-path spend from its witness
+// stack, or false for key-path spends and non-taproot witnesses
+func Tapscript(witness [][]byte) ([]byte, bool) {
+	if len(witness) >= 2 && len(witness[len(witness)-1]) > 0 && witness[len(witness)-1][0] == annexTag {
+		witness = witness[:len(witness)-1]
+	}
+	if len(witness) < 2 {
+		return nil, false
+	}
+	// The control block is the leaf version byte and internal key, then 32-byte path hashes
+	control := witness[len(witness)-1]
+	if len(control) < 33 || (len(control)-33)%32 != 0 || control[0]&0xfe != 0xc0 {
+		return nil, false
+	}
+	return witness[len(witness)-2], true
+}
+
+// TapscriptHex is Tapscript for a witness stack of hex strings
+func TapscriptHex(witness []string) ([]byte, bool, error) {
+	stack := make([][]byte, len(witness))
+	for i, element := range witness {
+		data, err := hex.DecodeString(element)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode witness element %d: %w", i, err)
+		}
+		stack[i] = data
+	}
+	tapscript, ok := Tapscript(stack)
+	return tapscript, ok, nil
+}
+
+// ParseEnvelopes returns the inscriptions of every well-formed envelope in a
+// tapscript, in script order. Envelopes containing opcodes other than pushes
+// are ignored, as ord does.
+func ParseEnvelopes(tapscript []byte) []Inscription {
+	// A truncated script may still hold complete envelopes before the problem
+	instructions, _ := script.Disassemble(tapscript)
+
+	var inscriptions []Inscription
+	for i := 0; i+2 < len(instructions); i++ {
+		if instructions[i].Op != script.OP_0 || instructions[i+1].Op != script.OP_IF ||
+			!bytes.Equal(pushedData(instructions[i+2]), protocolID) {
+			continue
+		}
+		inscription, end, ok := parseEnvelope(instructions[i+3:])
+		if ok {
+			inscriptions = append(inscriptions, inscription)
+		}
+		i += 2 + end
+	}
+	return inscriptions
+}
+
+// parseEnvelope parses the fields and body following "ord" up to OP_ENDIF. It
+// returns the number of instructions consumed.
+func parseEnvelope(instructions []script.Instruction) (Inscription, int, bool) {
+	inscription := Inscription{Fields: make(map[int][]byte)}
+	inBody := false
+	for i := 0; i < len(instructions); i++ {
+		instruction := instructions[i]
+		switch {
+		case instruction.Op == script.OP_ENDIF:
+			inscription.finish()
+			return inscription, i + 1, true
+		case !isPush(instruction):
+			return Inscription{}, i + 1, false
+		case inBody:
+			inscription.Body = append(inscription.Body, pushedData(instruction)...)
+		case instruction.Op == script.OP_0:
+			inBody = true
+		default:
+			if i+1 >= len(instructions) || !isPush(instructions[i+1]) {
+				return Inscription{}, i + 1, false
+			}
+			tag, value := pushedData(instruction), pushedData(instructions[i+1])
+			i++
+			if len(tag) != 1 {
+				continue
+			}
+			// Long metadata is split across repeated fields; other fields keep their first value
+			if _, seen := inscription.Fields[int(tag[0])]; seen && tag[0] != TagMetadata {
+				continue
+			}
+			inscription.Fields[int(tag[0])] = append(inscription.Fields[int(tag[0])], value...)
+		}
+	}
+	// No OP_ENDIF: the envelope never closes
+	return Inscription{}, len(instructions), false
+}
+
+// isPush reports whether an instruction pushes data or a small number
+func isPush(instruction script.Instruction) bool {
+	return instruction.Op.IsPush() || instruction.Op.IsSmallInt() || instruction.Op == script.OP_1NEGATE
+}
+
+// pushedData returns the bytes an instruction pushes, treating OP_1 to OP_16
+// as single-byte pushes like ord does
+func pushedData(instruction script.Instruction) []byte {
+	if instruction.Op != script.OP_0 && instruction.Op.IsSmallInt() {
+		return []byte{byte(instruction.Op.SmallInt())}
+	}
+	if instruction.Op == script.OP_1NEGATE {
+		return []byte{0x81}
+	}
+	return instruction.Data
+}
+
+// finish fills the string fields from the raw fields
+func (i *Inscription) finish() {
+	i.ContentType = string(i.Fields[TagContentType])
+	i.ContentEncoding = string(i.Fields[TagContentEncoding])
+	i.Metaprotocol = string(i.Fields[TagMetaprotocol])
+}
@@ -0,0 +1,137 @@
+// Package pools attributes blocks to mining pools from their coinbase.
+//
+// Pools are identified from a JSON database in the format of the community
+// Blockchain-Known-Pools lists: coinbase tags matched against the coinbase
+// input script, and payout addresses matched against the coinbase outputs.
+// A copy is bundled; a newer file can be used instead without rebuilding.
+package pools
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed pools.json
+var bundled []byte
+
+// Unknown is the pool name of blocks that match no entry
+const Unknown = "Unknown"
+
+// Ways a block was attributed
+const (
+	MatchAddress = "address"
+	MatchTag     = "tag"
+)
+
+// Pool is a mining pool
+type Pool struct {
+	Name string `json:"name"`
+	Link string `json:"link"`
+}
+
+// Database is a set of coinbase tags and payout addresses with their pools
+type Database struct {
+	tags      []tag // Longest first, so specific tags win over their prefixes
+	addresses map[string]Pool
+}
+
+type tag struct {
+	text []byte
+	pool Pool
+}
+
+// Match is the attribution of a block
+type Match struct {
+	Pool    Pool
+	Method  string // MatchAddress, MatchTag, or empty for Unknown
+	Matched string // The payout address or coinbase tag that matched
+}
+
+// Load reads the database at path, or the bundled one if path is empty
+func Load(path string) (*Database, error) {
+	if path == "" {
+		return Parse(bundled)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pool database: %w", err)
+	}
+	db, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pool database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Parse parses a pool database
+func Parse(data []byte) (*Database, error) {
+	var file struct {
+		CoinbaseTags    map[string]Pool `json:"coinbase_tags"`
+		PayoutAddresses map[string]Pool `json:"payout_addresses"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	db := &Database{addresses: make(map[string]Pool, len(file.PayoutAddresses))}
+	for text, pool := range file.CoinbaseTags {
+		if text != "" && pool.Name != "" {
+			db.tags = append(db.tags, tag{text: []byte(text), pool: pool})
+		}
+	}
+	sort.Slice(db.tags, func(i, j int) bool {
+		if len(db.tags[i].text) != len(db.tags[j].text) {
+			return len(db.tags[i].text) > len(db.tags[j].text)
+		}
+		return bytes.Compare(db.tags[i].text, db.tags[j].text) < 0
+	})
+	for addr, pool := range file.PayoutAddresses {
+		if pool.Name != "" {
+			db.addresses[addr] = pool
+		}
+	}
+	return db, nil
+}
+
+// Identify attributes a block from its coinbase input script and the
+// addresses its coinbase pays. Payout addresses take precedence, since
+// coinbase tags are free text any miner can copy.
+func (db *Database) Identify(coinbase []byte, payouts []string) Match {
+	for _, addr := range payouts {
+		if pool, ok := db.addresses[addr]; ok {
+			return Match{Pool: pool, Method: MatchAddress, Matched: addr}
+		}
+	}
+	for _, t := range db.tags {
+		if bytes.Contains(coinbase, t.text) {
+			return Match{Pool: t.pool, Method: MatchTag, Matched: string(t.text)}
+		}
+	}
+	return Match{Pool: Pool{Name: Unknown}}
+}
+
+// Text returns the printable runs of a coinbase script, which hold the
+// pool's tag, separated by spaces
+func Text(coinbase []byte) string {
+	var runs []string
+	var run strings.Builder
+	for _, r := range strings.ToValidUTF8(string(coinbase), "\x00") {
+		if unicode.IsPrint(r) && r != unicode.ReplacementChar {
+			run.WriteRune(r)
+			continue
+		}
+		if run.Len() >= 3 {
+			runs = append(runs, run.String())
+		}
+		run.Reset()
+	}
+	if run.Len() >= 3 {
+		runs = append(runs, run.String())
+	}
+	return strings.Join(runs, " ")
+}
@@ -0,0 +1,212 @@
+// Package privacy flags transactions shaped like CoinJoins and payjoins, whose
+// inputs belong to more than one party. Labels come from the amounts and
+// counts of inputs and outputs alone, so each carries a confidence: Whirlpool
+// and Wasabi rounds are unmistakable, while a payjoin looks like an ordinary
+// payment that spent one input too many.
+package privacy
+
+import (
+	"github.com/siddon/web3insights/internal/address"
+	"github.com/siddon/web3insights/internal/script"
+)
+
+// Labels, from the most specific pattern to the least
+const (
+	LabelWhirlpool    = "whirlpool"             // Samourai/Sparrow Whirlpool mix: 5 inputs to 5 outputs of a pool denomination
+	LabelWasabi2      = "wasabi2"               // WabiSabi round: 50+ inputs and outputs, mostly standard denominations
+	LabelWasabi       = "wasabi"                // Wasabi 1 round: 10+ equal outputs near 0.1 BTC
+	LabelUniform      = "uniform_denominations" // Many inputs of one denomination split into equal outputs
+	LabelJoinMarket   = "joinmarket"            // 3+ equal outputs, each with at most one change output
+	LabelEqualOutputs = "equal_outputs"         // Equal outputs with at least as many input addresses
+	LabelPayjoin      = "payjoin"               // Two outputs funded by an input the payment didn't need
+)
+
+// MultiPartyConfidence is the confidence from which a label is taken to mean
+// the inputs have more than one owner. Bare equal outputs and payjoin
+// candidates fall below it.
+const MultiPartyConfidence = 0.5
+
+// Coin is an input or output of a transaction
+type Coin struct {
+	Address string
+	Value   int64 // Satoshis
+}
+
+// Transaction is the part of a transaction the classifier reads
+type Transaction struct {
+	Coinbase bool
+	Inputs   []Coin
+	Outputs  []Coin
+}
+
+// Label is the privacy pattern a transaction matches
+type Label struct {
+	Name         string
+	Confidence   float64 // 0 to 1
+	EqualOutputs int     // Outputs of the most common value
+	Denomination int64   // The most common output value in satoshis
+}
+
+// MultiParty reports whether the label is confident enough that the inputs
+// have more than one owner
+func (l *Label) MultiParty() bool {
+	return l != nil && l.Confidence >= MultiPartyConfidence
+}
+
+// whirlpoolPools are the Whirlpool pool denominations in satoshis
+var whirlpoolPools = map[int64]bool{100_000: true, 1_000_000: true, 5_000_000: true, 50_000_000: true}
+
+// minStandardDenomination is the smallest WabiSabi output denomination in satoshis
+const minStandardDenomination = 5000
+
+// standardDenominations are the WabiSabi output denominations: powers of 2 and
+// 3, twice powers of 3, and 1, 2 and 5 times powers of 10, in satoshis
+var standardDenominations = func() map[int64]bool {
+	const maxSats = 21_000_000 * 100_000_000
+	denominations := make(map[int64]bool)
+	add := func(base int64, multipliers ...int64) {
+		for power := int64(1); power <= maxSats; power *= base {
+			for _, m := range multipliers {
+				if d := power * m; d >= minStandardDenomination && d <= maxSats {
+					denominations[d] = true
+				}
+			}
+		}
+	}
+	add(2, 1)
+	add(3, 1, 2)
+	add(10, 1, 2, 5)
+	return denominations
+}()
+
+// Classify returns the privacy pattern tx matches, or nil if it matches none
+func Classify(tx Transaction) *Label {
+	if tx.Coinbase || len(tx.Inputs) < 2 {
+		return nil
+	}
+	inputAddresses := distinctAddresses(tx.Inputs)
+	denomination, equal := mostCommonValue(tx.Outputs)
+	label := func(name string, confidence float64) *Label {
+		return &Label{Name: name, Confidence: confidence, EqualOutputs: equal, Denomination: denomination}
+	}
+
+	switch {
+	case isWhirlpool(tx, denomination, equal):
+		return label(LabelWhirlpool, 0.95)
+	case len(tx.Inputs) >= 50 && len(tx.Outputs) >= 50 && standardShare(tx.Outputs) >= 0.5:
+		return label(LabelWasabi2, 0.9)
+	case equal >= 10 && denomination >= 5_000_000 && denomination <= 20_000_000:
+		return label(LabelWasabi, 0.85)
+	case equal >= 2 && isUniform(tx.Inputs):
+		return label(LabelUniform, 0.7)
+	case equal >= 3 && len(tx.Outputs) <= 2*equal && inputAddresses >= equal:
+		return label(LabelJoinMarket, min(0.5+0.05*float64(equal), 0.9))
+	case equal >= 2 && inputAddresses >= equal:
+		// Batch payouts of one amount from a multi-address wallet look the same
+		return label(LabelEqualOutputs, 0.3)
+	case isPayjoin(tx, inputAddresses):
+		return label(LabelPayjoin, 0.3)
+	}
+	return nil
+}
+
+// isWhirlpool reports whether tx is a Whirlpool mix: 5 inputs of at least the
+// pool denomination to 5 outputs of exactly it
+func isWhirlpool(tx Transaction, denomination int64, equal int) bool {
+	if len(tx.Inputs) != 5 || len(tx.Outputs) != 5 || equal != 5 || !whirlpoolPools[denomination] {
+		return false
+	}
+	for _, input := range tx.Inputs {
+		if input.Value < denomination {
+			return false
+		}
+	}
+	return true
+}
+
+// isUniform reports whether at least 10 inputs were spent and at least half
+// of them share one value above dust, as when mixed coins are remixed
+func isUniform(inputs []Coin) bool {
+	if len(inputs) < 10 {
+		return false
+	}
+	value, count := mostCommonValue(inputs)
+	return value >= 10_000 && 2*count >= len(inputs)
+}
+
+// isPayjoin reports whether tx has the shape of a payjoin: two outputs, inputs
+// from at least two addresses of the same script type as the outputs, and no
+// output smaller than the smallest input. An ordinary wallet would have paid
+// without that input, which the payjoin receiver contributed.
+func isPayjoin(tx Transaction, inputAddresses int) bool {
+	if len(tx.Outputs) != 2 || inputAddresses < 2 {
+		return false
+	}
+	minInput := tx.Inputs[0].Value
+	for _, input := range tx.Inputs[1:] {
+		minInput = min(minInput, input.Value)
+	}
+	if minInput <= 0 || min(tx.Outputs[0].Value, tx.Outputs[1].Value) < minInput {
+		return false
+	}
+	class, ok := scriptClass(tx.Inputs[0].Address)
+	if !ok {
+		return false
+	}
+	for _, coins := range [][]Coin{tx.Inputs[1:], tx.Outputs} {
+		for _, coin := range coins {
+			if c, ok := scriptClass(coin.Address); !ok || c != class {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scriptClass returns the script type of an address
+func scriptClass(addr string) (script.Class, bool) {
+	decoded, err := address.Decode(addr)
+	if err != nil {
+		return "", false
+	}
+	return decoded.Class, true
+}
+
+// standardShare returns the fraction of outputs paying a WabiSabi standard
+// denomination
+func standardShare(outputs []Coin) float64 {
+	standard := 0
+	for _, output := range outputs {
+		if standardDenominations[output.Value] {
+			standard++
+		}
+	}
+	return float64(standard) / float64(len(outputs))
+}
+
+// mostCommonValue returns the most common positive value of coins and how many
+// coins have it, preferring the larger value on ties
+func mostCommonValue(coins []Coin) (value int64, count int) {
+	counts := make(map[int64]int, len(coins))
+	for _, coin := range coins {
+		if coin.Value <= 0 {
+			continue
+		}
+		counts[coin.Value]++
+		if n := counts[coin.Value]; n > count || (n == count && coin.Value > value) {
+			value, count = coin.Value, n
+		}
+	}
+	return value, count
+}
+
+// distinctAddresses returns the number of distinct non-empty addresses of coins
+func distinctAddresses(coins []Coin) int {
+	seen := make(map[string]bool, len(coins))
+	for _, coin := range coins {
+		if coin.Address != "" {
+			seen[coin.Address] = true
+		}
+	}
+	return len(seen)
+}
@@ -0,0 +1,45 @@
+package registry
+
+import (
+	"github.com/siddon/web3insights/internal/chain"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/schema"
+	"github.com/siddon/web3insights/internal/tidb"
+)
+
+func init() {
+	Register(&Chain{
+		Name:    "btc",
+		Aliases: []string{"bitcoin"},
+		Prefix:  func(cfg *config.Config) string { return cfg.AWSS3BTCPrefix },
+		Datasets: []Dataset{
+			NewDataset[chain.BtcBlock]("blocks",
+				[]tidb.Table{tidb.BtcBlocksTable},
+				tidb.LoadBtcBlocksWithProgressAndRow),
+			// Transactions are flattened into one row per transaction, input and output,
+			// plus the rows of the enabled analyzers
+			NewDataset[chain.BtcTransaction]("transactions",
+				[]tidb.Table{tidb.BtcTransactionsTable, tidb.BtcTransactionInputsTable, tidb.BtcTransactionOutputsTable,
+					tidb.BtcOpReturnsTable, tidb.BtcInscriptionsTable},
+				tidb.LoadBtcTransactionsWithProgressAndRow),
+		},
+		DDL:          schema.BTC,
+		Derive:       tidb.DeriveBtcDate,
+		DeriveTables: []string{"btc_daily_stats"},
+	})
+
+	Register(&Chain{
+		Name:    "eth",
+		Aliases: []string{"ethereum"},
+		Prefix:  func(cfg *config.Config) string { return cfg.AWSS3ETHPrefix },
+		Datasets: []Dataset{
+			FlatDataset("blocks", tidb.EthBlocks),
+			FlatDataset("transactions", tidb.EthTransactions),
+			FlatDataset("logs", tidb.EthLogs),
+			FlatDataset("token_transfers", tidb.EthTokenTransfers),
+			FlatDataset("receipts", tidb.EthReceipts),
+			FlatDataset("traces", tidb.EthTraces),
+		},
+		DDL: schema.ETH,
+	})
+}
@@ -0,0 +1,194 @@
+// Package registry describes the chains the pipeline can download, sync and parse.
+//
+// Each chain registers a descriptor with its S3 prefix, datasets, Go row types,
+// target tables and schema DDL, so the commands can work on any registered chain
+// without chain-specific code paths.
+package registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/awsdata"
+	"github.com/siddon/web3insights/internal/chain"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/schema"
+	"github.com/siddon/web3insights/internal/tidb"
+)
+
+// ReadFunc decodes the rows of a parquet row group in order and calls fn for each one
+type ReadFunc func(rowGroup parquet.RowGroup, fn func(row any) error) error
+
+// Dataset describes one dataset published for a chain
+type Dataset struct {
+	Name   string          // S3 dataset name and local directory, e.g. "blocks"
+	Schema *parquet.Schema // Parquet schema of the Go row type
+	Tables []tidb.Table    // Tables written by Load, with their column lists
+	Load   tidb.LoaderFunc // Loads one parquet file into Tables
+	Read   ReadFunc        // Decodes the rows of one row group into the Go row type
+}
+
+// NewDataset describes a dataset whose rows decode into T
+func NewDataset[T any](name string, tables []tidb.Table, load tidb.LoaderFunc) Dataset {
+	var zero T
+	return Dataset{
+		Name:   name,
+		Schema: parquet.SchemaOf(zero),
+		Tables: tables,
+		Load:   load,
+		Read:   readRows[T],
+	}
+}
+
+// FlatDataset describes a dataset that maps each row to exactly one table row
+func FlatDataset[T any](name string, mapping tidb.RowMapping[T]) Dataset {
+	return NewDataset[T](name, []tidb.Table{mapping.Table}, mapping.Load)
+}
+
+// ReadFile reads every row of file in order, one row group at a time
+func (d Dataset) ReadFile(file *parquet.File, fn func(row any) error) error {
+	for _, rowGroup := range file.RowGroups() {
+		if err := d.Read(rowGroup, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRows reads every row of rowGroup as T
+func readRows[T any](rowGroup parquet.RowGroup, fn func(row any) error) error {
+	var zero T
+	schema := parquet.SchemaOf(zero)
+	rowGroup, err := chain.NormalizeTimestamps(rowGroup, schema)
+	if err != nil {
+		return err
+	}
+	reader := parquet.NewGenericRowGroupReader[T](rowGroup, schema)
+	defer reader.Close()
+
+	rows := make([]T, 100) // Read in batches
+	for {
+		n, err := reader.Read(rows)
+		for i := 0; i < n; i++ {
+			if err := fn(rows[i]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// Chain describes a blockchain published in the AWS Public Blockchain dataset
+type Chain struct {
+	Name     string                          // Canonical name and local directory under OutDir, e.g. "btc"
+	Aliases  []string                        // Other names accepted by -chain, e.g. "bitcoin"
+	Prefix   func(cfg *config.Config) string // S3 prefix of the chain, e.g. "v1.0/btc/"
+	Datasets []Dataset                       // Datasets in load order
+	DDL      string                          // CREATE TABLE statements for Tables
+	Derive   tidb.DateDeriverFunc            // Derives tables once every dataset of a date is loaded, or nil
+	// Tables Derive writes whatever the analyzers, created on first use so
+	// upgrading doesn't need -create-tables
+	DeriveTables []string
+}
+
+// DatasetNames returns the dataset names in load order
+func (c *Chain) DatasetNames() []string {
+	names := make([]string, len(c.Datasets))
+	for i, dataset := range c.Datasets {
+		names[i] = dataset.Name
+	}
+	return names
+}
+
+// Dataset returns the dataset with the given name
+func (c *Chain) Dataset(name string) (Dataset, bool) {
+	for _, dataset := range c.Datasets {
+		if dataset.Name == name {
+			return dataset, true
+		}
+	}
+	return Dataset{}, false
+}
+
+// Tables returns the tables written by every dataset, in load order
+func (c *Chain) Tables() []tidb.Table {
+	var tables []tidb.Table
+	for _, dataset := range c.Datasets {
+		tables = append(tables, dataset.Tables...)
+	}
+	return tables
+}
+
+// SchemaDDL returns the chain's DDL without the columns omitted by profiles
+func (c *Chain) SchemaDDL(profiles tidb.Profiles) string {
+	return schema.OmitColumns(c.DDL, profiles.Omitted(c.Tables()...))
+}
+
+// DeriveDDL returns the CREATE TABLE statements of DeriveTables
+func (c *Chain) DeriveDDL(profiles tidb.Profiles) string {
+	return schema.Select(c.SchemaDDL(profiles), c.DeriveTables...)
+}
+
+// Dir returns the local directory holding this chain's datasets
+func (c *Chain) Dir(cfg *config.Config) string {
+	return filepath.Join(cfg.OutDir, c.Name)
+}
+
+// DateDir returns the local directory of one dataset for one date
+func (c *Chain) DateDir(cfg *config.Config, dataset, date string) string {
+	return filepath.Join(cfg.OutDir, c.Name, dataset, date)
+}
+
+// Download fetches every dataset of the chain for a date
+func (c *Chain) Download(ctx context.Context, cfg *config.Config, date string) error {
+	return awsdata.Download(ctx, cfg, c.Prefix(cfg), c.Name, c.DatasetNames(), date)
+}
+
+// Catalog lists the dates available upstream for every dataset of the chain
+func (c *Chain) Catalog(ctx context.Context, cfg *config.Config, start, end string) ([]awsdata.CatalogEntry, error) {
+	return awsdata.ListCatalog(ctx, cfg, c.Prefix(cfg), c.DatasetNames(), start, end)
+}
+
+var chains = make(map[string]*Chain)
+
+// Register adds a chain under its name and aliases. It panics on duplicates,
+// since registration happens at init time.
+func Register(c *Chain) {
+	for _, name := range append([]string{c.Name}, c.Aliases...) {
+		if _, ok := chains[name]; ok {
+			panic(fmt.Sprintf("registry: chain %q registered twice", name))
+		}
+		chains[name] = c
+	}
+}
+
+// Lookup returns the chain registered under name or one of its aliases
+func Lookup(name string) (*Chain, error) {
+	if c, ok := chains[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("unsupported chain: %s (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns all registered chain names and aliases, sorted
+func Names() []string {
+	names := make([]string, 0, len(chains))
+	for name := range chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
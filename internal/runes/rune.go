@@ -0,0 +1,195 @@
+package runes
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// FirstHeight is the height at which runestones are first indexed
+const FirstHeight = 840000
+
+// Name unlocking: names of up to 13 letters are available at FirstHeight, and
+// the minimum length drops by one every unlockInterval blocks
+const (
+	halvingInterval = 210000
+	unlockInterval  = halvingInterval / 12
+)
+
+// maxU128 is the largest 128-bit integer
+var maxU128 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// reservedRune is the first reserved name, AAAAAAAAAAAAAAAAAAAAAAAAAAA;
+// reserved names are assigned to etchings without an explicit name
+var reservedRune, _ = new(big.Int).SetString("6402364363415443603228541259936211926", 10)
+
+// steps[n] is the first name of n+1 letters
+var steps = func() [28]*big.Int {
+	var s [28]*big.Int
+	s[0] = big.NewInt(0)
+	power := big.NewInt(1)
+	for i := 1; i < len(s); i++ {
+		power = new(big.Int).Mul(power, big.NewInt(26))
+		s[i] = new(big.Int).Add(s[i-1], power)
+	}
+	return s
+}()
+
+// ID identifies a rune by the block and transaction index of its etching
+type ID struct {
+	Block uint64
+	Tx    uint32
+}
+
+// String formats the id as BLOCK:TX
+func (id ID) String() string {
+	return fmt.Sprintf("%d:%d", id.Block, id.Tx)
+}
+
+// IsZero reports whether id is 0:0, which edicts use for the rune etched by the
+// same transaction
+func (id ID) IsZero() bool {
+	return id.Block == 0 && id.Tx == 0
+}
+
+// ParseID parses BLOCK:TX
+func ParseID(s string) (ID, error) {
+	block, tx, ok := strings.Cut(s, ":")
+	if !ok {
+		return ID{}, fmt.Errorf("invalid rune id %q", s)
+	}
+	b, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return ID{}, fmt.Errorf("invalid rune id %q: %w", s, err)
+	}
+	t, err := strconv.ParseUint(tx, 10, 32)
+	if err != nil {
+		return ID{}, fmt.Errorf("invalid rune id %q: %w", s, err)
+	}
+	return ID{Block: b, Tx: uint32(t)}, nil
+}
+
+// next applies an edict's delta-encoded id to the previous id
+func (id ID) next(block, tx *big.Int) (ID, bool) {
+	if !block.IsUint64() || !tx.IsUint64() || tx.Uint64() > uint64(^uint32(0)) {
+		return ID{}, false
+	}
+	b := id.Block + block.Uint64()
+	if b < id.Block {
+		return ID{}, false
+	}
+	t := uint64(tx.Uint64())
+	if block.Sign() == 0 {
+		t += uint64(id.Tx)
+		if t > uint64(^uint32(0)) {
+			return ID{}, false
+		}
+	}
+	next := ID{Block: b, Tx: uint32(t)}
+	if next.Block == 0 && next.Tx > 0 {
+		return ID{}, false
+	}
+	return next, true
+}
+
+// Rune is a rune name as the 128-bit integer it is encoded as
+type Rune struct{ *big.Int }
+
+// String returns the name in letters: 0 is A, 25 is Z, 26 is AA
+func (r Rune) String() string {
+	if r.Int == nil {
+		return ""
+	}
+	n := new(big.Int).Add(r.Int, big.NewInt(1))
+	var letters []byte
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.Sub(n, big.NewInt(1))
+		n.DivMod(n, big.NewInt(26), mod)
+		letters = append(letters, byte('A'+mod.Int64()))
+	}
+	for i, j := 0, len(letters)-1; i < j; i, j = i+1, j-1 {
+		letters[i], letters[j] = letters[j], letters[i]
+	}
+	return string(letters)
+}
+
+// ParseRune parses a rune name in letters, ignoring spacers
+func ParseRune(s string) (Rune, error) {
+	n := new(big.Int)
+	letters := 0
+	for _, c := range s {
+		if c == '•' || c == '.' {
+			continue
+		}
+		if c < 'A' || c > 'Z' {
+			return Rune{}, fmt.Errorf("invalid rune name %q", s)
+		}
+		if letters > 0 {
+			n.Add(n, big.NewInt(1))
+		}
+		n.Mul(n, big.NewInt(26))
+		n.Add(n, big.NewInt(int64(c-'A')))
+		letters++
+	}
+	if letters == 0 || n.Cmp(maxU128) > 0 {
+		return Rune{}, fmt.Errorf("invalid rune name %q", s)
+	}
+	return Rune{n}, nil
+}
+
+// Spaced returns the name with a bullet after every letter whose spacer bit is set
+func (r Rune) Spaced(spacers uint32) string {
+	name := r.String()
+	var b strings.Builder
+	for i, c := range name {
+		b.WriteRune(c)
+		if i < len(name)-1 && spacers&(1<<i) != 0 {
+			b.WriteString("•")
+		}
+	}
+	return b.String()
+}
+
+// IsReserved reports whether the name is in the reserved range
+func (r Rune) IsReserved() bool {
+	return r.Cmp(reservedRune) >= 0
+}
+
+// ReservedRune returns the name assigned to an unnamed etching at block and tx
+func ReservedRune(block uint64, tx uint32) Rune {
+	n := new(big.Int).Lsh(new(big.Int).SetUint64(block), 32)
+	n.Or(n, new(big.Int).SetUint64(uint64(tx)))
+	return Rune{n.Add(n, reservedRune)}
+}
+
+// MinimumAtHeight returns the smallest name that may be etched at height
+func MinimumAtHeight(height uint64) Rune {
+	offset := height + 1
+	switch {
+	case offset < FirstHeight:
+		return Rune{steps[12]}
+	case offset >= FirstHeight+halvingInterval:
+		return Rune{big.NewInt(0)}
+	}
+	progress := offset - FirstHeight
+	length := 12 - progress/unlockInterval
+	start, end := steps[length], steps[length-1]
+	// start - (start-end)*remainder/unlockInterval
+	n := new(big.Int).Sub(start, end)
+	n.Mul(n, new(big.Int).SetUint64(progress%unlockInterval))
+	n.Quo(n, big.NewInt(unlockInterval))
+	return Rune{n.Sub(start, n)}
+}
+
+// Commitment returns the bytes an etching's tapscript must push to commit to
+// the name: its little-endian encoding without trailing zero bytes
+func (r Rune) Commitment() []byte {
+	be := r.Bytes()
+	commitment := make([]byte, len(be))
+	for i, b := range be {
+		commitment[len(be)-1-i] = b
+	}
+	return commitment
+}
@@ -0,0 +1,368 @@
+// Package runes deciphers Runes protocol messages (runestones).
+//
+// A runestone is the first output whose script starts with OP_RETURN OP_13.
+// Its data pushes are concatenated into a sequence of LEB128 integers holding
+// tagged fields and, after the body tag, delta-encoded edicts. Malformed
+// runestones are cenotaphs: they are still recognized, but burn the runes
+// their transaction spends. Deciphering follows the ord reference
+// implementation.
+package runes
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/siddon/web3insights/internal/script"
+)
+
+// Tags of runestone fields. Unknown even tags make a cenotaph; unknown odd
+// tags are ignored.
+const (
+	tagBody         = 0
+	tagFlags        = 2
+	tagRune         = 4
+	tagPremine      = 6
+	tagCap          = 8
+	tagAmount       = 10
+	tagHeightStart  = 12
+	tagHeightEnd    = 14
+	tagOffsetStart  = 16
+	tagOffsetEnd    = 18
+	tagMint         = 20
+	tagPointer      = 22
+	tagDivisibility = 1
+	tagSpacers      = 3
+	tagSymbol       = 5
+)
+
+// Bits of the flags field
+const (
+	flagEtching = 0
+	flagTerms   = 1
+	flagTurbo   = 2
+)
+
+const (
+	maxDivisibility = 38
+	maxSpacers      = 0b00000111_11111111_11111111_11111111
+)
+
+// Flaws that make a runestone a cenotaph
+const (
+	FlawOpcode           = "opcode"
+	FlawVarint           = "varint"
+	FlawTrailingIntegers = "trailing_integers"
+	FlawTruncatedField   = "truncated_field"
+	FlawEdictRuneID      = "edict_rune_id"
+	FlawEdictOutput      = "edict_output"
+	FlawSupplyOverflow   = "supply_overflow"
+	FlawUnrecognizedFlag = "unrecognized_flag"
+	FlawUnrecognizedEven = "unrecognized_even_tag"
+	FlawInvalidScript    = "invalid_script"
+)
+
+// Edict moves an amount of a rune to an output. Output equal to the number of
+// outputs splits the amount across every non-OP_RETURN output.
+type Edict struct {
+	ID     ID
+	Amount *big.Int
+	Output uint32
+}
+
+// Terms are the open-mint terms of an etching
+type Terms struct {
+	Amount      *big.Int // Amount per mint
+	Cap         *big.Int // Maximum number of mints
+	HeightStart *uint64
+	HeightEnd   *uint64
+	OffsetStart *uint64 // Relative to the etching block
+	OffsetEnd   *uint64
+}
+
+// Etching creates a rune. Rune is nil for etchings that take a reserved name.
+type Etching struct {
+	Divisibility uint8
+	Premine      *big.Int
+	Rune         *Rune
+	Spacers      uint32
+	Symbol       rune // 0 when unset
+	Terms        *Terms
+	Turbo        bool
+}
+
+// Runestone is a deciphered runestone or cenotaph
+type Runestone struct {
+	Edicts   []Edict
+	Etching  *Etching // For cenotaphs only Rune is set
+	Mint     *ID
+	Pointer  *uint32
+	Cenotaph bool
+	Flaw     string // Why the runestone is a cenotaph
+}
+
+// Decipher finds and decodes the runestone of a transaction from its output
+// scripts. ok is false if no output carries one.
+func Decipher(outputs [][]byte) (*Runestone, bool) {
+	payload, flaw, ok := runestonePayload(outputs)
+	if !ok {
+		return nil, false
+	}
+	if flaw != "" {
+		return &Runestone{Cenotaph: true, Flaw: flaw}, true
+	}
+	integers, err := decodeIntegers(payload)
+	if err != nil {
+		return &Runestone{Cenotaph: true, Flaw: FlawVarint}, true
+	}
+
+	m := newMessage(integers, len(outputs))
+	flags := big.NewInt(0)
+	m.take(tagFlags, 1, func(v []*big.Int) bool { flags.Set(v[0]); return true })
+
+	var etching *Etching
+	if takeFlag(flags, flagEtching) {
+		etching = &Etching{}
+		m.take(tagDivisibility, 1, func(v []*big.Int) bool {
+			if !v[0].IsUint64() || v[0].Uint64() > maxDivisibility {
+				return false
+			}
+			etching.Divisibility = uint8(v[0].Uint64())
+			return true
+		})
+		m.take(tagPremine, 1, func(v []*big.Int) bool { etching.Premine = v[0]; return true })
+		m.take(tagRune, 1, func(v []*big.Int) bool { etching.Rune = &Rune{v[0]}; return true })
+		m.take(tagSpacers, 1, func(v []*big.Int) bool {
+			if !v[0].IsUint64() || v[0].Uint64() > maxSpacers {
+				return false
+			}
+			etching.Spacers = uint32(v[0].Uint64())
+			return true
+		})
+		m.take(tagSymbol, 1, func(v []*big.Int) bool {
+			if !v[0].IsUint64() || v[0].Uint64() > 0x10ffff || (v[0].Uint64() >= 0xd800 && v[0].Uint64() <= 0xdfff) {
+				return false
+			}
+			etching.Symbol = rune(v[0].Uint64())
+			return true
+		})
+		if takeFlag(flags, flagTerms) {
+			terms := &Terms{}
+			m.take(tagCap, 1, func(v []*big.Int) bool { terms.Cap = v[0]; return true })
+			m.take(tagHeightStart, 1, takeUint64(&terms.HeightStart))
+			m.take(tagHeightEnd, 1, takeUint64(&terms.HeightEnd))
+			m.take(tagAmount, 1, func(v []*big.Int) bool { terms.Amount = v[0]; return true })
+			m.take(tagOffsetStart, 1, takeUint64(&terms.OffsetStart))
+			m.take(tagOffsetEnd, 1, takeUint64(&terms.OffsetEnd))
+			etching.Terms = terms
+		}
+		etching.Turbo = takeFlag(flags, flagTurbo)
+	}
+
+	var mint *ID
+	m.take(tagMint, 2, func(v []*big.Int) bool {
+		if !v[0].IsUint64() || !v[1].IsUint64() || v[1].Uint64() > uint64(^uint32(0)) {
+			return false
+		}
+		id := ID{Block: v[0].Uint64(), Tx: uint32(v[1].Uint64())}
+		if id.Block == 0 && id.Tx > 0 {
+			return false
+		}
+		mint = &id
+		return true
+	})
+	var pointer *uint32
+	m.take(tagPointer, 1, func(v []*big.Int) bool {
+		if !v[0].IsUint64() || v[0].Uint64() >= uint64(len(outputs)) {
+			return false
+		}
+		p := uint32(v[0].Uint64())
+		pointer = &p
+		return true
+	})
+
+	flaw = m.flaw
+	if flaw == "" && etching != nil && etching.supply() == nil {
+		flaw = FlawSupplyOverflow
+	}
+	if flaw == "" && flags.Sign() != 0 {
+		flaw = FlawUnrecognizedFlag
+	}
+	if flaw == "" && m.hasEvenTag() {
+		flaw = FlawUnrecognizedEven
+	}
+	if flaw != "" {
+		cenotaph := &Runestone{Cenotaph: true, Flaw: flaw, Mint: mint}
+		if etching != nil && etching.Rune != nil {
+			cenotaph.Etching = &Etching{Rune: etching.Rune}
+		}
+		return cenotaph, true
+	}
+	return &Runestone{Edicts: m.edicts, Etching: etching, Mint: mint, Pointer: pointer}, true
+}
+
+// supply returns premine + cap * amount, or nil if it overflows 128 bits
+func (e *Etching) supply() *big.Int {
+	supply := new(big.Int)
+	if e.Premine != nil {
+		supply.Set(e.Premine)
+	}
+	if e.Terms != nil && e.Terms.Cap != nil && e.Terms.Amount != nil {
+		supply.Add(supply, new(big.Int).Mul(e.Terms.Cap, e.Terms.Amount))
+	}
+	if supply.Cmp(maxU128) > 0 {
+		return nil
+	}
+	return supply
+}
+
+// runestonePayload concatenates the data pushes of the first OP_RETURN OP_13
+// output. A flaw is returned for scripts that contain other opcodes or end
+// inside a push.
+func runestonePayload(outputs [][]byte) ([]byte, string, bool) {
+	for _, output := range outputs {
+		if len(output) < 2 || script.Opcode(output[0]) != script.OP_RETURN || script.Opcode(output[1]) != script.OP_13 {
+			continue
+		}
+		instructions, err := script.Disassemble(output[2:])
+		var payload []byte
+		for _, instruction := range instructions {
+			if !instruction.Op.IsPush() {
+				return nil, FlawOpcode, true
+			}
+			payload = append(payload, instruction.Data...)
+		}
+		if err != nil {
+			return nil, FlawInvalidScript, true
+		}
+		return payload, "", true
+	}
+	return nil, "", false
+}
+
+// decodeIntegers decodes a payload of LEB128 integers of at most 128 bits
+func decodeIntegers(payload []byte) ([]*big.Int, error) {
+	var integers []*big.Int
+	for len(payload) > 0 {
+		n, size, err := decodeVarint(payload)
+		if err != nil {
+			return nil, err
+		}
+		integers = append(integers, n)
+		payload = payload[size:]
+	}
+	return integers, nil
+}
+
+func decodeVarint(buf []byte) (*big.Int, int, error) {
+	n := new(big.Int)
+	for i, b := range buf {
+		if i > 18 {
+			return nil, 0, errors.New("overlong varint")
+		}
+		value := uint64(b & 0x7f)
+		if i == 18 && value&0b0111_1100 != 0 {
+			return nil, 0, errors.New("varint overflows 128 bits")
+		}
+		n.Or(n, new(big.Int).Lsh(new(big.Int).SetUint64(value), uint(7*i)))
+		if b&0x80 == 0 {
+			return n, i + 1, nil
+		}
+	}
+	return nil, 0, errors.New("unterminated varint")
+}
+
+// message holds the fields and edicts of a runestone while it is deciphered
+type message struct {
+	fields map[uint64][]*big.Int
+	tags   []uint64 // Tags in first-seen order, so flaws don't depend on map order
+	edicts []Edict
+	flaw   string
+}
+
+func newMessage(integers []*big.Int, outputs int) *message {
+	m := &message{fields: make(map[uint64][]*big.Int)}
+	for i := 0; i < len(integers); i += 2 {
+		tag := integers[i]
+		if tag.Sign() == 0 {
+			m.decodeEdicts(integers[i+1:], outputs)
+			return m
+		}
+		if i+1 >= len(integers) {
+			m.flaw = FlawTruncatedField
+			return m
+		}
+		// Tags beyond 64 bits can't be recognized; keep their parity
+		key := tag.Uint64()
+		if !tag.IsUint64() {
+			key = uint64(tag.Bit(0)) | 1<<63
+		}
+		if _, ok := m.fields[key]; !ok {
+			m.tags = append(m.tags, key)
+		}
+		m.fields[key] = append(m.fields[key], integers[i+1])
+	}
+	return m
+}
+
+func (m *message) decodeEdicts(integers []*big.Int, outputs int) {
+	var id ID
+	for i := 0; i < len(integers); i += 4 {
+		if i+4 > len(integers) {
+			m.flaw = FlawTrailingIntegers
+			return
+		}
+		next, ok := id.next(integers[i], integers[i+1])
+		if !ok {
+			m.flaw = FlawEdictRuneID
+			return
+		}
+		output := integers[i+3]
+		if !output.IsUint64() || output.Uint64() > uint64(outputs) {
+			m.flaw = FlawEdictOutput
+			return
+		}
+		id = next
+		m.edicts = append(m.edicts, Edict{ID: id, Amount: integers[i+2], Output: uint32(output.Uint64())})
+	}
+}
+
+// take passes the first n values of a tag to with and consumes them if with
+// accepts them. Rejected values stay, so rejected even tags make a cenotaph.
+func (m *message) take(tag uint64, n int, with func([]*big.Int) bool) {
+	values := m.fields[tag]
+	if len(values) < n || !with(values[:n]) {
+		return
+	}
+	if len(values) == n {
+		delete(m.fields, tag)
+	} else {
+		m.fields[tag] = values[n:]
+	}
+}
+
+func (m *message) hasEvenTag() bool {
+	for _, tag := range m.tags {
+		if _, ok := m.fields[tag]; ok && tag%2 == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// takeFlag clears a flag bit and reports whether it was set
+func takeFlag(flags *big.Int, bit int) bool {
+	set := flags.Bit(bit) == 1
+	flags.SetBit(flags, bit, 0)
+	return set
+}
+
+func takeUint64(dst **uint64) func([]*big.Int) bool {
+	return func(v []*big.Int) bool {
+		if !v[0].IsUint64() {
+			return false
+		}
+		n := v[0].Uint64()
+		*dst = &n
+		return true
+	}
+}
@@ -25,7 +25,8 @@ CREATE TABLE IF NOT EXISTS `btc_blocks` (
   `difficulty` DOUBLE NULL COMMENT 'Block difficulty',
   `chainwork` VARCHAR(128) NULL COMMENT 'Total work done by the chain',
   `previousblockhash` VARCHAR(80) NULL COMMENT 'Hash of the previous block',
-  PRIMARY KEY (`record_date`, `hash`)
+  PRIMARY KEY (`record_date`, `hash`),
+  KEY `idx_number` (`number`)
 )
 PARTITION BY RANGE COLUMNS(`record_date`)
 INTERVAL (1 MONTH)
@@ -114,3 +115,501 @@ FIRST PARTITION LESS THAN ('2009-01-01')
 LAST PARTITION LESS THAN ('2109-01-01')
 MAXVALUE PARTITION;
 
+-- BTC Daily Stats Table
+-- Written by sync after each date, and by the rollup command for a date range: one row per
+-- date computed from btc_blocks, btc_transactions and the input and output tables, so
+-- dashboards read precomputed rows instead of scanning them
+
+CREATE TABLE IF NOT EXISTS `btc_daily_stats` (
+  `record_date` DATE NOT NULL COMMENT 'The date (YYYY-MM-DD)',
+  `block_count` BIGINT NOT NULL COMMENT 'Number of blocks',
+  `first_block` BIGINT NULL COMMENT 'Lowest block number of the date',
+  `last_block` BIGINT NULL COMMENT 'Highest block number of the date',
+  `total_transactions` BIGINT NOT NULL COMMENT 'Sum of the transaction_count of the blocks',
+  `avg_difficulty` DOUBLE NULL COMMENT 'Average block difficulty',
+  `avg_block_size` DOUBLE NULL COMMENT 'Average block size in bytes',
+  `avg_block_weight` DOUBLE NULL COMMENT 'Average block weight in weight units',
+  `transaction_count` BIGINT NOT NULL COMMENT 'Number of rows loaded into btc_transactions',
+  `total_volume` DOUBLE NOT NULL COMMENT 'Sum of the output_value of the transactions in BTC',
+  `total_fees` DOUBLE NOT NULL COMMENT 'Sum of the transaction fees in BTC',
+  `avg_fee` DOUBLE NOT NULL COMMENT 'Average transaction fee in BTC',
+  `input_count` BIGINT NOT NULL COMMENT 'Number of transaction inputs',
+  `output_count` BIGINT NOT NULL COMMENT 'Number of transaction outputs',
+  `active_addresses` BIGINT NOT NULL COMMENT 'Distinct addresses that sent or received',
+  `sending_addresses` BIGINT NOT NULL COMMENT 'Distinct addresses of the spent outputs',
+  `receiving_addresses` BIGINT NOT NULL COMMENT 'Distinct addresses of the created outputs',
+  PRIMARY KEY (`record_date`)
+);
+
+-- BTC OP_RETURN Payloads Table
+-- Written by the op_returns analyzer: one row per output whose script starts with OP_RETURN
+-- protocol is tagged from known payload prefixes (omni, counterparty, runes, ...) or 'unknown'
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_op_returns` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the transaction this output belongs to',
+  `output_index` BIGINT NOT NULL COMMENT 'The index of the OP_RETURN output within the transaction',
+  `block_number` BIGINT NOT NULL COMMENT 'Number of the block which contains the transaction',
+  `protocol` VARCHAR(32) NOT NULL COMMENT 'Protocol tagged from the payload prefix, or unknown',
+  `data` MEDIUMBLOB NULL COMMENT 'Data pushes after OP_RETURN, concatenated',
+  `data_size` BIGINT NULL COMMENT 'Size of data in bytes',
+  `data_text` TEXT NULL COMMENT 'data as text when it is valid UTF-8 without control characters',
+  `push_count` INT NULL COMMENT 'Number of data pushes after OP_RETURN',
+  `output_amount` DOUBLE NULL COMMENT 'The value in BTC attached to (and burned by) this output',
+  PRIMARY KEY (`record_date`, `transaction_hash`, `output_index`),
+  KEY `idx_protocol` (`protocol`, `record_date`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- BTC Inscriptions Table
+-- Written by the inscriptions analyzer: one row per ordinal inscription envelope
+-- (OP_FALSE OP_IF "ord" ... OP_ENDIF) in the tapscript of a taproot script-path spend
+-- Needs the inputs.witness column, which the AWS dataset doesn't publish
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_inscriptions` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `inscription_id` VARCHAR(96) NOT NULL COMMENT 'Inscription id: <transaction_hash>i<n>, numbering the envelopes of the transaction',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the transaction revealing the inscription',
+  `input_index` BIGINT NOT NULL COMMENT 'The index of the input whose witness holds the envelope',
+  `envelope_index` BIGINT NOT NULL COMMENT 'The index of the envelope within the input tapscript',
+  `block_number` BIGINT NOT NULL COMMENT 'Number of the block which contains the transaction',
+  `content_type` VARCHAR(255) NULL COMMENT 'MIME type from the content type field (tag 1)',
+  `content_encoding` VARCHAR(64) NULL COMMENT 'Content encoding field (tag 9), e.g. br',
+  `metaprotocol` VARCHAR(255) NULL COMMENT 'Metaprotocol field (tag 7)',
+  `content_size` BIGINT NULL COMMENT 'Size of the inscription body in bytes',
+  PRIMARY KEY (`record_date`, `inscription_id`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- BTC Block Miners Table
+-- Written by the miners analyzer: the mining pool of each block, matched first on the
+-- coinbase payout addresses, then on tags in the coinbase input script (pool_name Unknown
+-- if neither matches). Rows are replaced on resync, so an updated pools_file relabels blocks
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_block_miners` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `block_number` BIGINT NOT NULL COMMENT 'The number of the block',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the block',
+  `pool_name` VARCHAR(128) NOT NULL COMMENT 'The mining pool, or Unknown',
+  `pool_link` VARCHAR(255) NULL COMMENT 'The website of the mining pool',
+  `match_method` VARCHAR(16) NULL COMMENT 'How the pool was matched: address or tag (empty for Unknown)',
+  `matched` VARCHAR(255) NULL COMMENT 'The payout address or coinbase tag that matched',
+  `coinbase_text` TEXT NULL COMMENT 'Printable runs of the coinbase input script',
+  `payout_address` VARCHAR(128) NULL COMMENT 'The first address paid by the coinbase transaction',
+  `coinbase_value` DOUBLE NULL COMMENT 'Total value in BTC of the coinbase outputs (subsidy plus fees)',
+  `difficulty` DOUBLE NULL COMMENT 'The difficulty of the block',
+  PRIMARY KEY (`record_date`, `block_number`),
+  KEY `idx_pool_name` (`pool_name`, `record_date`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- BTC Pool Daily Shares Table
+-- Written by the miners analyzer: each pool's blocks on a date, recomputed from btc_block_miners
+-- estimated_hashrate is the work of the pool's blocks (difficulty * 2^32 hashes each) over 86400 seconds
+
+CREATE TABLE IF NOT EXISTS `btc_pool_daily_shares` (
+  `record_date` DATE NOT NULL COMMENT 'The date (YYYY-MM-DD)',
+  `pool_name` VARCHAR(128) NOT NULL COMMENT 'The mining pool, or Unknown',
+  `block_count` BIGINT NOT NULL COMMENT 'Number of blocks the pool mined on the date',
+  `block_share` DOUBLE NOT NULL COMMENT 'Fraction of the blocks of the date the pool mined',
+  `estimated_hashrate` DOUBLE NULL COMMENT 'Estimated hashrate of the pool in hashes per second',
+  PRIMARY KEY (`record_date`, `pool_name`)
+);
+
+-- BTC Block Stats Table
+-- Written by the block_stats analyzer: fee rate distribution (nearest-rank percentiles of the
+-- non-coinbase transactions, in sat/vB), fees against the subsidy, and fullness against the
+-- 4,000,000 weight unit limit of each block. Fee rate columns are NULL for coinbase-only blocks
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_block_stats` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `block_number` BIGINT NOT NULL COMMENT 'The number of the block',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the block',
+  `tx_count` BIGINT NOT NULL COMMENT 'Number of non-coinbase transactions',
+  `total_fees` DOUBLE NOT NULL COMMENT 'Total fees in BTC',
+  `subsidy` DOUBLE NOT NULL COMMENT 'Block subsidy in BTC',
+  `fee_share` DOUBLE NULL COMMENT 'Fraction of the miner reward (subsidy plus fees) paid by fees',
+  `total_vsize` BIGINT NOT NULL COMMENT 'Total virtual size of the non-coinbase transactions in vbytes',
+  `weight` BIGINT NULL COMMENT 'Block weight in weight units',
+  `fullness` DOUBLE NULL COMMENT 'weight divided by the 4,000,000 weight unit limit',
+  `min_fee_rate` DOUBLE NULL COMMENT 'Lowest fee rate in sat/vB',
+  `p10_fee_rate` DOUBLE NULL COMMENT '10th percentile fee rate in sat/vB',
+  `p25_fee_rate` DOUBLE NULL COMMENT '25th percentile fee rate in sat/vB',
+  `median_fee_rate` DOUBLE NULL COMMENT 'Median fee rate in sat/vB',
+  `p75_fee_rate` DOUBLE NULL COMMENT '75th percentile fee rate in sat/vB',
+  `p90_fee_rate` DOUBLE NULL COMMENT '90th percentile fee rate in sat/vB',
+  `max_fee_rate` DOUBLE NULL COMMENT 'Highest fee rate in sat/vB',
+  `avg_fee_rate` DOUBLE NULL COMMENT 'Mean of the transaction fee rates in sat/vB',
+  PRIMARY KEY (`record_date`, `block_number`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- BTC Block Stats Daily Table
+-- Written by the block_stats analyzer: btc_block_stats summed over a date, with the
+-- percentiles taken over every non-coinbase transaction of the date
+
+CREATE TABLE IF NOT EXISTS `btc_block_stats_daily` (
+  `record_date` DATE NOT NULL COMMENT 'The date (YYYY-MM-DD)',
+  `block_count` BIGINT NOT NULL COMMENT 'Number of blocks on the date',
+  `tx_count` BIGINT NOT NULL COMMENT 'Number of non-coinbase transactions',
+  `total_fees` DOUBLE NOT NULL COMMENT 'Total fees in BTC',
+  `total_subsidy` DOUBLE NOT NULL COMMENT 'Total block subsidy in BTC',
+  `fee_share` DOUBLE NULL COMMENT 'Fraction of the miner reward (subsidy plus fees) paid by fees',
+  `total_vsize` BIGINT NOT NULL COMMENT 'Total virtual size of the non-coinbase transactions in vbytes',
+  `total_weight` BIGINT NULL COMMENT 'Total block weight in weight units',
+  `avg_fullness` DOUBLE NULL COMMENT 'Average block weight divided by the 4,000,000 weight unit limit',
+  `min_fee_rate` DOUBLE NULL COMMENT 'Lowest fee rate in sat/vB',
+  `p10_fee_rate` DOUBLE NULL COMMENT '10th percentile fee rate in sat/vB',
+  `p25_fee_rate` DOUBLE NULL COMMENT '25th percentile fee rate in sat/vB',
+  `median_fee_rate` DOUBLE NULL COMMENT 'Median fee rate in sat/vB',
+  `p75_fee_rate` DOUBLE NULL COMMENT '75th percentile fee rate in sat/vB',
+  `p90_fee_rate` DOUBLE NULL COMMENT '90th percentile fee rate in sat/vB',
+  `max_fee_rate` DOUBLE NULL COMMENT 'Highest fee rate in sat/vB',
+  `avg_fee_rate` DOUBLE NULL COMMENT 'Mean of the transaction fee rates in sat/vB',
+  PRIMARY KEY (`record_date`)
+);
+
+-- BTC Supply Table
+-- Written by the supply analyzer: the subsidy each block was due from the halving schedule
+-- against what its coinbase claimed (output value minus the block's fees), and the
+-- circulating supply after it. Unclaimed subsidy is never issued, so supply trails
+-- expected_supply by the sum of unclaimed. supply_exact is FALSE when the blocks before the
+-- first tracked one were assumed to claim their full subsidy
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_supply` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `block_number` BIGINT NOT NULL COMMENT 'The number of the block',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the block',
+  `expected_subsidy` DECIMAL(16,8) NOT NULL COMMENT 'Subsidy in BTC due from the halving schedule',
+  `claimed_subsidy` DECIMAL(16,8) NOT NULL COMMENT 'Coinbase output value minus the block fees in BTC',
+  `fees` DECIMAL(16,8) NOT NULL COMMENT 'Fees of the block transactions in BTC',
+  `unclaimed` DECIMAL(16,8) NOT NULL COMMENT 'Subsidy in BTC the coinbase did not claim',
+  `under_claimed` BOOLEAN NOT NULL COMMENT 'Whether the coinbase claimed less than the subsidy',
+  `supply` DECIMAL(16,8) NOT NULL COMMENT 'Circulating supply in BTC after the block',
+  `expected_supply` DECIMAL(16,8) NOT NULL COMMENT 'Supply in BTC if every block had claimed its full subsidy',
+  `supply_exact` BOOLEAN NOT NULL COMMENT 'FALSE if untracked earlier blocks were assumed to claim their full subsidy',
+  PRIMARY KEY (`record_date`, `block_number`),
+  KEY `idx_block_number` (`block_number`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- BTC Privacy Labels Table
+-- Written by the privacy analyzer: transactions shaped like a CoinJoin or payjoin, whose inputs
+-- belong to more than one party. label is whirlpool, wasabi2, wasabi, uniform_denominations,
+-- joinmarket, equal_outputs or payjoin. Labels come from amounts and counts alone, so each has a
+-- confidence; from 0.5 the clusters analyzer treats the inputs as having more than one owner
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_privacy_labels` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `block_number` BIGINT NOT NULL COMMENT 'The number of the block containing the transaction',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the transaction',
+  `label` VARCHAR(32) NOT NULL COMMENT 'The privacy pattern the transaction matches',
+  `confidence` DOUBLE NOT NULL COMMENT 'Confidence in the label from 0 to 1',
+  `input_count` BIGINT NOT NULL COMMENT 'The number of inputs',
+  `output_count` BIGINT NOT NULL COMMENT 'The number of outputs',
+  `equal_outputs` BIGINT NOT NULL COMMENT 'The number of outputs of the most common value',
+  `denomination` DECIMAL(16,8) NOT NULL COMMENT 'The most common output value in BTC',
+  PRIMARY KEY (`record_date`, `transaction_hash`),
+  KEY `idx_label` (`label`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- BTC Address Clusters Table
+-- Written by the clusters analyzer: the entity cluster of every address spent alongside
+-- another address, by the common-input-ownership heuristic. Coinbase transactions and those
+-- the privacy classifier labels with a confidence of at least 0.5 are skipped. When a date
+-- links clusters together, the smaller ones are relabeled into the largest. Addresses not
+-- in the table are clusters of their own
+
+CREATE TABLE IF NOT EXISTS `btc_address_clusters` (
+  `address` VARCHAR(128) NOT NULL COMMENT 'The address',
+  `cluster_id` BIGINT NOT NULL COMMENT 'The cluster the address belongs to',
+  PRIMARY KEY (`address`),
+  KEY `idx_cluster_id` (`cluster_id`)
+);
+
+-- BTC Cluster Stats Table
+-- Written by the clusters analyzer: the size and activity span of each cluster in
+-- btc_address_clusters
+
+CREATE TABLE IF NOT EXISTS `btc_cluster_stats` (
+  `cluster_id` BIGINT NOT NULL COMMENT 'The cluster',
+  `address_count` BIGINT NOT NULL COMMENT 'Number of addresses in the cluster',
+  `first_date` DATE NOT NULL COMMENT 'The first date an address of the cluster was linked',
+  `last_date` DATE NOT NULL COMMENT 'The last date an address of the cluster was linked',
+  PRIMARY KEY (`cluster_id`),
+  KEY `idx_address_count` (`address_count`)
+);
+
+-- BTC Hashrate Table
+-- Written by the metrics command (difficulty): network hashrate estimated at each block from
+-- the work of the last 144 and 1008 blocks (difficulty * 2^32 hashes each) over the time since
+-- the block before them, and whether the block's chainwork is above the previous block's
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_hashrate` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `block_number` BIGINT NOT NULL COMMENT 'The number of the block',
+  `block_timestamp` TIMESTAMP NULL COMMENT 'Block creation timestamp specified in block header',
+  `difficulty` DOUBLE NULL COMMENT 'Block difficulty',
+  `hashrate_144` DOUBLE NULL COMMENT 'Estimated hashes per second over the last 144 blocks (about a day)',
+  `hashrate_1008` DOUBLE NULL COMMENT 'Estimated hashes per second over the last 1008 blocks (about a week)',
+  `chainwork` VARCHAR(128) NULL COMMENT 'Total work done by the chain',
+  `chainwork_increasing` BOOLEAN NULL COMMENT 'Whether chainwork is above the previous block; NULL if that block is not loaded',
+  PRIMARY KEY (`record_date`, `block_number`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- BTC Difficulty Epochs Table
+-- Written by the metrics command (difficulty): one row per 2016-block retarget epoch. The actual
+-- timespan runs from the first block's timestamp to the last's, as the retarget rule measures it;
+-- expected_adjustment_pct is the adjustment the rule makes from it (clamped to 4x either way),
+-- and adjustment_pct the change to the next epoch's difficulty as loaded
+
+CREATE TABLE IF NOT EXISTS `btc_difficulty_epochs` (
+  `epoch` BIGINT NOT NULL COMMENT 'Epoch number: block number divided by 2016',
+  `start_height` BIGINT NOT NULL COMMENT 'First loaded block of the epoch',
+  `end_height` BIGINT NOT NULL COMMENT 'Last loaded block of the epoch',
+  `block_count` BIGINT NOT NULL COMMENT 'Number of loaded blocks of the epoch',
+  `complete` BOOLEAN NOT NULL COMMENT 'Whether all 2016 blocks are loaded',
+  `start_time` TIMESTAMP NULL COMMENT 'Timestamp of the first loaded block',
+  `end_time` TIMESTAMP NULL COMMENT 'Timestamp of the last loaded block',
+  `difficulty` DOUBLE NULL COMMENT 'Difficulty of the epoch',
+  `bits` VARCHAR(32) NULL COMMENT 'Difficulty threshold of the epoch',
+  `actual_timespan` BIGINT NULL COMMENT 'Seconds from the first to the last loaded block',
+  `target_timespan` BIGINT NOT NULL COMMENT 'Seconds the epoch should take: 1209600 (two weeks)',
+  `avg_block_interval` DOUBLE NULL COMMENT 'Average seconds between the loaded blocks',
+  `expected_adjustment_pct` DOUBLE NULL COMMENT 'Adjustment in percent the retarget rule makes; NULL until complete',
+  `next_difficulty` DOUBLE NULL COMMENT 'Difficulty of the next epoch, once its first block is loaded',
+  `adjustment_pct` DOUBLE NULL COMMENT 'Change in percent from difficulty to next_difficulty',
+  PRIMARY KEY (`epoch`)
+);
+
+-- BTC UTXO Ages Table
+-- Written by the metrics command (coin_days): the creation date and value of every output
+-- created since the first computed date that is unspent as of the last one. Outputs spent on
+-- the last computed date are kept until the next date is computed, so it can be recomputed
+
+CREATE TABLE IF NOT EXISTS `btc_utxo_ages` (
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the transaction creating the output',
+  `output_index` BIGINT NOT NULL COMMENT 'The index of the output within the transaction',
+  `created_date` DATE NOT NULL COMMENT 'The date the output was created',
+  `value` DOUBLE NOT NULL COMMENT 'The value of the output in BTC',
+  `spent_date` DATE NULL COMMENT 'The date the output was spent, NULL while unspent',
+  PRIMARY KEY (`transaction_hash`, `output_index`),
+  KEY `idx_spent_date` (`spent_date`)
+);
+
+-- BTC Coin Days Table
+-- Written by the metrics command (coin_days): per date, the value spent times the days since it
+-- was created (coin days destroyed), dormancy (coin days destroyed per BTC spent) and the
+-- average age of the spent outputs. Ages are in whole days between record dates. Spends of
+-- outputs created before tracked_since can't be aged and are counted in untracked_inputs
+
+CREATE TABLE IF NOT EXISTS `btc_coin_days` (
+  `record_date` DATE NOT NULL COMMENT 'The date (YYYY-MM-DD)',
+  `tracked_since` DATE NOT NULL COMMENT 'The first date whose outputs are tracked',
+  `spent_count` BIGINT NOT NULL COMMENT 'Number of tracked outputs spent',
+  `spent_value` DOUBLE NOT NULL COMMENT 'Value of the tracked outputs spent in BTC',
+  `coin_days_destroyed` DOUBLE NOT NULL COMMENT 'Sum of value times age in days of the spent outputs',
+  `dormancy` DOUBLE NULL COMMENT 'coin_days_destroyed divided by spent_value',
+  `avg_spent_age_days` DOUBLE NULL COMMENT 'Average age in days of the spent outputs',
+  `untracked_inputs` BIGINT NOT NULL COMMENT 'Inputs spending outputs created before tracked_since',
+  `utxo_count` BIGINT NOT NULL COMMENT 'Number of tracked unspent outputs at the end of the date',
+  `unspent_value` DOUBLE NOT NULL COMMENT 'Value of the tracked unspent outputs in BTC',
+  PRIMARY KEY (`record_date`)
+);
+
+-- BTC HODL Waves Table
+-- Written by the metrics command (coin_days): the tracked unspent value at the end of each date
+-- by age band: <1d, 1d-1w, 1w-1m, 1m-6m, 6m-1y, 1y-2y, 2y-5y and 5y+
+
+CREATE TABLE IF NOT EXISTS `btc_hodl_waves` (
+  `record_date` DATE NOT NULL COMMENT 'The date (YYYY-MM-DD)',
+  `age_bucket` VARCHAR(16) NOT NULL COMMENT 'The age band',
+  `min_age_days` INT NOT NULL COMMENT 'The youngest age in days of the band, for ordering',
+  `utxo_count` BIGINT NOT NULL COMMENT 'Number of unspent outputs in the band',
+  `value` DOUBLE NOT NULL COMMENT 'Unspent value in the band in BTC',
+  `value_share` DOUBLE NULL COMMENT 'Fraction of the tracked unspent value in the band',
+  PRIMARY KEY (`record_date`, `age_bucket`)
+);
+
+-- BTC Metrics Progress Table
+-- Written by the metrics command: the dates computed by metrics that must run in date order
+
+CREATE TABLE IF NOT EXISTS `btc_metrics_progress` (
+  `metric` VARCHAR(32) NOT NULL COMMENT 'The metric name',
+  `first_date` DATE NOT NULL COMMENT 'The first date computed',
+  `last_date` DATE NOT NULL COMMENT 'The last date computed',
+  PRIMARY KEY (`metric`)
+);
+
+-- BTC Token Events Table
+-- Written by the index command: Runes and BRC-20 events in block, transaction and event order
+-- Every event credits amount to to_address and debits it from from_address, except
+-- inscribe_transfer, which reserves amount of from_address for a BRC-20 transfer inscription
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2009-01 to 2109-01
+
+CREATE TABLE IF NOT EXISTS `btc_token_events` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `block_number` BIGINT NOT NULL COMMENT 'Number of the block which contains the transaction',
+  `tx_index` BIGINT NOT NULL COMMENT 'The index of the transaction within the block',
+  `event_index` BIGINT NOT NULL COMMENT 'The index of the event within the transaction',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the transaction',
+  `protocol` VARCHAR(16) NOT NULL COMMENT 'runes or brc20',
+  `event_type` VARCHAR(32) NOT NULL COMMENT 'etch, mint, send, receive, burn, deploy, inscribe_transfer or transfer',
+  `token` VARCHAR(64) NOT NULL COMMENT 'Rune id (BLOCK:TX) or lowercased BRC-20 tick',
+  `from_address` VARCHAR(128) NOT NULL COMMENT 'Address debited, or empty',
+  `to_address` VARCHAR(128) NOT NULL COMMENT 'Address credited, or empty',
+  `amount` DECIMAL(65,18) NOT NULL COMMENT 'Rune units (before divisibility) or BRC-20 amount',
+  `output_index` BIGINT NULL COMMENT 'The output the event refers to',
+  PRIMARY KEY (`record_date`, `block_number`, `tx_index`, `event_index`),
+  KEY `idx_token` (`protocol`, `token`, `block_number`),
+  KEY `idx_block` (`block_number`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2009-01-01')
+LAST PARTITION LESS THAN ('2109-01-01')
+MAXVALUE PARTITION;
+
+-- The token indexer state tables below aren't time series, so they aren't partitioned.
+-- Rows carry the height that created (and spent) them so the indexer can roll back to a height.
+
+-- BTC Token Balances Table
+-- Token balance of each address, maintained by the index command from btc_token_events
+
+CREATE TABLE IF NOT EXISTS `btc_token_balances` (
+  `protocol` VARCHAR(16) NOT NULL COMMENT 'runes or brc20',
+  `token` VARCHAR(64) NOT NULL COMMENT 'Rune id (BLOCK:TX) or lowercased BRC-20 tick',
+  `address` VARCHAR(128) NOT NULL COMMENT 'Holder address',
+  `balance` DECIMAL(65,18) NOT NULL COMMENT 'Total balance',
+  `transferable` DECIMAL(65,18) NOT NULL COMMENT 'Part of a BRC-20 balance reserved by unsent transfer inscriptions',
+  PRIMARY KEY (`protocol`, `token`, `address`)
+);
+
+-- BTC Runes Table
+-- One row per etched rune
+
+CREATE TABLE IF NOT EXISTS `btc_runes` (
+  `rune_id` VARCHAR(32) NOT NULL COMMENT 'Rune id: BLOCK:TX of the etching',
+  `rune` VARCHAR(32) NOT NULL COMMENT 'Rune name',
+  `spaced_rune` VARCHAR(96) NOT NULL COMMENT 'Rune name with spacers',
+  `block_number` BIGINT NOT NULL COMMENT 'Block of the etching',
+  `tx_index` BIGINT NOT NULL COMMENT 'Index of the etching transaction within its block',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the etching transaction',
+  `divisibility` INT NOT NULL COMMENT 'Number of decimal places of an amount',
+  `symbol` VARCHAR(8) NULL COMMENT 'Currency symbol',
+  `premine` DECIMAL(65,0) NOT NULL COMMENT 'Units allocated by the etching',
+  `terms_amount` DECIMAL(65,0) NULL COMMENT 'Units per mint',
+  `terms_cap` DECIMAL(65,0) NULL COMMENT 'Maximum number of mints',
+  `terms_height_start` BIGINT UNSIGNED NULL COMMENT 'First height mints are allowed at',
+  `terms_height_end` BIGINT UNSIGNED NULL COMMENT 'Height mints are allowed before',
+  `terms_offset_start` BIGINT UNSIGNED NULL COMMENT 'First height mints are allowed at, relative to the etching block',
+  `terms_offset_end` BIGINT UNSIGNED NULL COMMENT 'Height mints are allowed before, relative to the etching block',
+  `turbo` BOOLEAN NOT NULL COMMENT 'Opted into future protocol changes',
+  `cenotaph` BOOLEAN NOT NULL COMMENT 'Etched by a cenotaph: no premine and not mintable',
+  PRIMARY KEY (`rune_id`),
+  UNIQUE KEY `idx_rune` (`rune`),
+  KEY `idx_block` (`block_number`)
+);
+
+-- BTC Rune Outpoints Table
+-- Rune balances held by transaction outputs; spent_height is set once the output is spent
+
+CREATE TABLE IF NOT EXISTS `btc_rune_outpoints` (
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the transaction creating the output',
+  `output_index` BIGINT NOT NULL COMMENT 'The index of the output',
+  `rune_id` VARCHAR(32) NOT NULL COMMENT 'Rune id (BLOCK:TX)',
+  `address` VARCHAR(128) NOT NULL COMMENT 'Address of the output, or empty',
+  `amount` DECIMAL(65,0) NOT NULL COMMENT 'Rune units held by the output',
+  `created_height` BIGINT NOT NULL COMMENT 'Block creating the output',
+  `spent_height` BIGINT NULL COMMENT 'Block spending the output',
+  PRIMARY KEY (`transaction_hash`, `output_index`, `rune_id`),
+  KEY `idx_created` (`created_height`),
+  KEY `idx_spent` (`spent_height`)
+);
+
+-- BTC BRC-20 Tokens Table
+-- One row per deployed BRC-20 token; minted supply is the sum of its mint events
+
+CREATE TABLE IF NOT EXISTS `btc_brc20_tokens` (
+  `tick` VARCHAR(16) NOT NULL COMMENT 'Lowercased tick',
+  `max_supply` DECIMAL(65,18) NOT NULL COMMENT 'Maximum supply',
+  `mint_limit` DECIMAL(65,18) NOT NULL COMMENT 'Maximum amount per mint',
+  `decimals` INT NOT NULL COMMENT 'Number of decimal places of an amount',
+  `inscription_id` VARCHAR(96) NOT NULL COMMENT 'Deploy inscription id',
+  `deployer` VARCHAR(128) NOT NULL COMMENT 'Address receiving the deploy inscription',
+  `block_number` BIGINT NOT NULL COMMENT 'Block of the deploy',
+  PRIMARY KEY (`tick`),
+  KEY `idx_block` (`block_number`)
+);
+
+-- BTC BRC-20 Transfers Table
+-- Valid BRC-20 transfer inscriptions; spent_height is set once the inscription is sent
+
+CREATE TABLE IF NOT EXISTS `btc_brc20_transfers` (
+  `inscription_id` VARCHAR(96) NOT NULL COMMENT 'Transfer inscription id',
+  `tick` VARCHAR(16) NOT NULL COMMENT 'Lowercased tick',
+  `amount` DECIMAL(65,18) NOT NULL COMMENT 'Amount transferred',
+  `from_address` VARCHAR(128) NOT NULL COMMENT 'Address the amount is reserved from',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'The hash of the transaction holding the inscription',
+  `output_index` BIGINT NOT NULL COMMENT 'The output holding the inscription',
+  `sat_offset` BIGINT NOT NULL COMMENT 'Offset of the inscribed satoshi within the output',
+  `created_height` BIGINT NOT NULL COMMENT 'Block revealing the inscription',
+  `spent_height` BIGINT NULL COMMENT 'Block sending the inscription',
+  PRIMARY KEY (`inscription_id`),
+  KEY `idx_created` (`created_height`),
+  KEY `idx_spent` (`spent_height`)
+);
+
+-- BTC Token Progress Table
+-- Last block indexed by the token indexer
+
+CREATE TABLE IF NOT EXISTS `btc_token_progress` (
+  `indexer` VARCHAR(32) NOT NULL COMMENT 'Indexer name',
+  `height` BIGINT NOT NULL COMMENT 'Last indexed block',
+  PRIMARY KEY (`indexer`)
+);
@@ -0,0 +1,227 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ChangeKind classifies a difference between a file schema and the expected schema
+type ChangeKind string
+
+const (
+	// Added is a column present in the file but unknown to the Go row type;
+	// parquet-go silently drops it
+	Added ChangeKind = "added"
+	// Missing is a column of the Go row type absent from the file; it decodes
+	// as zero values
+	Missing ChangeKind = "missing"
+	// Retyped is a column whose physical type or structure changed
+	Retyped ChangeKind = "retyped"
+)
+
+// ChangeKinds lists every kind of schema change
+var ChangeKinds = []ChangeKind{Added, Missing, Retyped}
+
+// Change is one column that differs between a file schema and the expected schema
+type Change struct {
+	Kind     ChangeKind
+	Column   string // Dotted column path, e.g. "outputs.list.element.address"
+	Expected string // Expected type, empty for added columns
+	Actual   string // Type in the file, empty for missing columns
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("added column %s (%s)", c.Column, c.Actual)
+	case Missing:
+		return fmt.Sprintf("missing column %s (%s)", c.Column, c.Expected)
+	default:
+		return fmt.Sprintf("retyped column %s (expected %s, got %s)", c.Column, c.Expected, c.Actual)
+	}
+}
+
+// DriftError reports the schema changes of a file that are not allowed
+type DriftError struct {
+	File    string
+	Changes []Change
+}
+
+func (e *DriftError) Error() string {
+	lines := make([]string, len(e.Changes))
+	for i, change := range e.Changes {
+		lines[i] = "  " + change.String()
+	}
+	return fmt.Sprintf("schema drift in %s:\n%s", e.File, strings.Join(lines, "\n"))
+}
+
+// Diff compares the schema of a file with the expected schema of the Go row type
+func Diff(expected, actual parquet.Node) []Change {
+	var changes []Change
+	diffGroup(nil, expected, actual, &changes)
+	return changes
+}
+
+func diffGroup(path []string, expected, actual parquet.Node, changes *[]Change) {
+	actualFields := make(map[string]parquet.Field)
+	for _, field := range actual.Fields() {
+		actualFields[field.Name()] = field
+	}
+	expectedNames := make(map[string]bool)
+
+	for _, field := range expected.Fields() {
+		expectedNames[field.Name()] = true
+		fieldPath := append(append([]string(nil), path...), field.Name())
+		other, ok := actualFields[field.Name()]
+		if !ok {
+			*changes = append(*changes, Change{Kind: Missing, Column: strings.Join(fieldPath, "."), Expected: nodeType(field)})
+			continue
+		}
+		diffNode(fieldPath, field, other, changes)
+	}
+
+	for _, field := range actual.Fields() {
+		if !expectedNames[field.Name()] {
+			fieldPath := append(append([]string(nil), path...), field.Name())
+			*changes = append(*changes, Change{Kind: Added, Column: strings.Join(fieldPath, "."), Actual: nodeType(field)})
+		}
+	}
+}
+
+func diffNode(path []string, expected, actual parquet.Node, changes *[]Change) {
+	retyped := Change{Kind: Retyped, Column: strings.Join(path, "."), Expected: nodeType(expected), Actual: nodeType(actual)}
+	if expected.Leaf() != actual.Leaf() || expected.Repeated() != actual.Repeated() {
+		*changes = append(*changes, retyped)
+		return
+	}
+	if !expected.Leaf() {
+		diffGroup(path, expected, actual, changes)
+		return
+	}
+	if !compatibleTypes(expected.Type(), actual.Type()) {
+		*changes = append(*changes, retyped)
+	}
+}
+
+// compatibleTypes reports whether values of the actual leaf type decode into
+// the expected one. Optionality and STRING annotations are ignored since they
+// don't change the decoded values.
+func compatibleTypes(expected, actual parquet.Type) bool {
+	if isTimestamp(expected) {
+		return isTimestamp(actual)
+	}
+	if expected.Kind() != actual.Kind() {
+		return false
+	}
+	if expected.Kind() == parquet.FixedLenByteArray {
+		return expected.Length() == actual.Length()
+	}
+	return true
+}
+
+// isTimestamp reports whether a leaf holds timestamps that chain.Int96Timestamp
+// can decode: INT96, INT64 with a TIMESTAMP logical type, or the
+// fixed_len_byte_array(12) the Go schema declares for Int96Timestamp
+func isTimestamp(t parquet.Type) bool {
+	switch t.Kind() {
+	case parquet.Int96:
+		return true
+	case parquet.Int64:
+		lt := t.LogicalType()
+		return lt != nil && lt.Timestamp != nil
+	case parquet.FixedLenByteArray:
+		return t.Length() == 12
+	}
+	return false
+}
+
+// nodeType describes a node for error messages, e.g. "optional int64 (TIMESTAMP(...))"
+func nodeType(node parquet.Node) string {
+	var b strings.Builder
+	switch {
+	case node.Repeated():
+		b.WriteString("repeated ")
+	case node.Optional():
+		b.WriteString("optional ")
+	}
+	if !node.Leaf() {
+		b.WriteString("group")
+		return b.String()
+	}
+	t := node.Type()
+	if t.Kind() == parquet.FixedLenByteArray {
+		fmt.Fprintf(&b, "fixed_len_byte_array(%d)", t.Length())
+	} else {
+		b.WriteString(strings.ToLower(t.Kind().String()))
+	}
+	if lt := t.LogicalType(); lt != nil {
+		fmt.Fprintf(&b, " (%s)", lt)
+	}
+	return b.String()
+}
+
+// Filter drops the changes whose kind is allowed
+func Filter(changes []Change, allowed []ChangeKind) []Change {
+	var kept []Change
+	for _, change := range changes {
+		allow := false
+		for _, kind := range allowed {
+			if change.Kind == kind {
+				allow = true
+				break
+			}
+		}
+		if !allow {
+			kept = append(kept, change)
+		}
+	}
+	return kept
+}
+
+// ParseAllowed parses a comma-separated list of allowed change kinds;
+// "all" allows every kind and "" or "none" allows none
+func ParseAllowed(v string) ([]ChangeKind, error) {
+	var allowed []ChangeKind
+	for _, name := range strings.Split(v, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "", "none":
+		case "all":
+			return ChangeKinds, nil
+		case string(Added), string(Missing), string(Retyped):
+			allowed = append(allowed, ChangeKind(name))
+		default:
+			return nil, fmt.Errorf("unknown schema change kind: %s (expected added, missing, retyped, all or none)", name)
+		}
+	}
+	return allowed, nil
+}
+
+// CheckFile compares the schema of a parquet file with expected and returns a
+// *DriftError listing the changes that are not allowed. Allowed changes are
+// returned so callers can report them.
+func CheckFile(path string, expected *parquet.Schema, allowed []ChangeKind) ([]Change, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	parquetFile, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	changes := Diff(expected, parquetFile.Schema())
+	if rejected := Filter(changes, allowed); len(rejected) > 0 {
+		return changes, &DriftError{File: path, Changes: rejected}
+	}
+	return changes, nil
+}
@@ -0,0 +1,180 @@
+-- ETH Blocks Table
+-- Schema based on AWS blockchain data and ethereum-etl project
+-- References:
+-- - https://raw.githubusercontent.com/aws-solutions-library-samples/guidance-for-digital-assets-on-aws/main/analytics/consumer/schema/eth.md
+-- - https://ethereum-etl.readthedocs.io/en/latest/schema/
+--
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+-- Partitions automatically created from 2015-07 to 2115-07
+
+CREATE TABLE IF NOT EXISTS `eth_blocks` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the block',
+  `number` BIGINT NOT NULL COMMENT 'The block number',
+  `block_timestamp` TIMESTAMP NULL COMMENT 'The timestamp for when the block was collated',
+  `parent_hash` VARCHAR(80) NULL COMMENT 'Hash of the parent block',
+  `nonce` VARCHAR(32) NULL COMMENT 'Hash of the generated proof-of-work',
+  `sha3_uncles` VARCHAR(80) NULL COMMENT 'SHA3 of the uncles data in the block',
+  `logs_bloom` TEXT NULL COMMENT 'The bloom filter for the logs of the block',
+  `transactions_root` VARCHAR(80) NULL COMMENT 'The root of the transaction trie of the block',
+  `state_root` VARCHAR(80) NULL COMMENT 'The root of the final state trie of the block',
+  `receipts_root` VARCHAR(80) NULL COMMENT 'The root of the receipts trie of the block',
+  `miner` VARCHAR(64) NULL COMMENT 'The address of the beneficiary to whom the mining rewards were given',
+  `difficulty` DOUBLE NULL COMMENT 'Integer of the difficulty for this block',
+  `total_difficulty` DOUBLE NULL COMMENT 'Integer of the total difficulty of the chain until this block',
+  `size` BIGINT NULL COMMENT 'The size of this block in bytes',
+  `extra_data` TEXT NULL COMMENT 'The extra data field of this block',
+  `gas_limit` DOUBLE NULL COMMENT 'The maximum gas allowed in this block',
+  `gas_used` DOUBLE NULL COMMENT 'The total used gas by all transactions in this block',
+  `transaction_count` BIGINT NULL COMMENT 'The number of transactions in the block',
+  `base_fee_per_gas` DOUBLE NULL COMMENT 'Protocol base fee per gas, which can move up or down (EIP-1559)',
+  `withdrawals_root` VARCHAR(80) NULL COMMENT 'The root of the withdrawal trie of the block',
+  `withdrawal_count` BIGINT NULL COMMENT 'The number of beacon chain withdrawals in the block',
+  PRIMARY KEY (`record_date`, `hash`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2015-07-01')
+LAST PARTITION LESS THAN ('2115-07-01')
+MAXVALUE PARTITION;
+
+-- ETH Transactions Table
+-- Schema based on AWS blockchain data and ethereum-etl project
+-- Receipt fields are denormalized into the transaction row by the dataset
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+
+CREATE TABLE IF NOT EXISTS `eth_transactions` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the transaction',
+  `nonce` BIGINT NULL COMMENT 'The number of transactions made by the sender prior to this one',
+  `transaction_index` BIGINT NOT NULL COMMENT 'Integer of the transactions index position in the block',
+  `from_address` VARCHAR(64) NULL COMMENT 'Address of the sender',
+  `to_address` VARCHAR(64) NULL COMMENT 'Address of the receiver, null when it is a contract creation transaction',
+  `value` DOUBLE NULL COMMENT 'Value transferred in wei',
+  `gas` DOUBLE NULL COMMENT 'Gas provided by the sender',
+  `gas_price` DOUBLE NULL COMMENT 'Gas price provided by the sender in wei',
+  `input` MEDIUMTEXT NULL COMMENT 'The data sent along with the transaction',
+  `receipt_cumulative_gas_used` DOUBLE NULL COMMENT 'The total amount of gas used when this transaction was executed in the block',
+  `receipt_gas_used` DOUBLE NULL COMMENT 'The amount of gas used by this specific transaction alone',
+  `receipt_contract_address` VARCHAR(64) NULL COMMENT 'The contract address created, if the transaction was a contract creation',
+  `receipt_status` BIGINT NULL COMMENT 'Either 1 (success) or 0 (failure) (post Byzantium)',
+  `block_timestamp` TIMESTAMP NULL COMMENT 'Timestamp of the block where this transaction was in',
+  `block_number` BIGINT NOT NULL COMMENT 'Block number where this transaction was in',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the block where this transaction was in',
+  `max_fee_per_gas` DOUBLE NULL COMMENT 'Total fee that covers both base and priority fees (EIP-1559)',
+  `max_priority_fee_per_gas` DOUBLE NULL COMMENT 'Fee given to miners to incentivize them to include the transaction (EIP-1559)',
+  `transaction_type` BIGINT NULL COMMENT 'Transaction type',
+  `receipt_effective_gas_price` DOUBLE NULL COMMENT 'The actual value per gas deducted from the senders account',
+  PRIMARY KEY (`record_date`, `hash`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2015-07-01')
+LAST PARTITION LESS THAN ('2115-07-01')
+MAXVALUE PARTITION;
+
+-- ETH Logs Table
+-- Event logs emitted by transactions; the topics array is spread over topic0..topic3
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+
+CREATE TABLE IF NOT EXISTS `eth_logs` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `block_number` BIGINT NOT NULL COMMENT 'The block number where this log was in',
+  `log_index` BIGINT NOT NULL COMMENT 'Integer of the log index position in the block',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the transactions this log was created from',
+  `transaction_index` BIGINT NULL COMMENT 'Integer of the transactions index position log was created from',
+  `address` VARCHAR(64) NULL COMMENT 'Address from which this log originated',
+  `data` MEDIUMTEXT NULL COMMENT 'Contains one or more 32 Bytes non-indexed arguments of the log',
+  `topic0` VARCHAR(80) NULL COMMENT 'Event signature hash',
+  `topic1` VARCHAR(80) NULL COMMENT 'First indexed event argument',
+  `topic2` VARCHAR(80) NULL COMMENT 'Second indexed event argument',
+  `topic3` VARCHAR(80) NULL COMMENT 'Third indexed event argument',
+  `block_timestamp` TIMESTAMP NULL COMMENT 'Timestamp of the block where this log was in',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the block where this log was in',
+  PRIMARY KEY (`record_date`, `block_number`, `log_index`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2015-07-01')
+LAST PARTITION LESS THAN ('2115-07-01')
+MAXVALUE PARTITION;
+
+-- ETH Token Transfers Table
+-- ERC-20 and ERC-721 Transfer events decoded by the dataset
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+
+CREATE TABLE IF NOT EXISTS `eth_token_transfers` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'Transaction hash',
+  `log_index` BIGINT NOT NULL COMMENT 'Log index in the transaction receipt',
+  `token_address` VARCHAR(64) NULL COMMENT 'ERC20/ERC721 token address',
+  `from_address` VARCHAR(64) NULL COMMENT 'Address of the sender',
+  `to_address` VARCHAR(64) NULL COMMENT 'Address of the receiver',
+  `value` DOUBLE NULL COMMENT 'Amount of tokens transferred (ERC20) / id of the token transferred (ERC721)',
+  `block_timestamp` TIMESTAMP NULL COMMENT 'Timestamp of the block where this transfer was in',
+  `block_number` BIGINT NOT NULL COMMENT 'Block number where this transfer was in',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the block where this transfer was in',
+  PRIMARY KEY (`record_date`, `transaction_hash`, `log_index`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2015-07-01')
+LAST PARTITION LESS THAN ('2115-07-01')
+MAXVALUE PARTITION;
+
+-- ETH Receipts Table
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+
+CREATE TABLE IF NOT EXISTS `eth_receipts` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `transaction_hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the transaction',
+  `transaction_index` BIGINT NULL COMMENT 'Integer of the transactions index position in the block',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the block where this transaction was in',
+  `block_number` BIGINT NOT NULL COMMENT 'Block number where this transaction was in',
+  `cumulative_gas_used` DOUBLE NULL COMMENT 'The total amount of gas used when this transaction was executed in the block',
+  `gas_used` DOUBLE NULL COMMENT 'The amount of gas used by this specific transaction alone',
+  `contract_address` VARCHAR(64) NULL COMMENT 'The contract address created, if the transaction was a contract creation',
+  `root` VARCHAR(80) NULL COMMENT '32 bytes of post-transaction stateroot (pre Byzantium)',
+  `status` BIGINT NULL COMMENT 'Either 1 (success) or 0 (failure) (post Byzantium)',
+  `effective_gas_price` DOUBLE NULL COMMENT 'The actual value per gas deducted from the senders account',
+  PRIMARY KEY (`record_date`, `transaction_hash`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2015-07-01')
+LAST PARTITION LESS THAN ('2115-07-01')
+MAXVALUE PARTITION;
+
+-- ETH Traces Table
+-- Internal calls, contract creations, self-destructs and block rewards
+-- Partitioned by date (YYYY-MM-DD) using TiDB automated monthly partitioning
+
+CREATE TABLE IF NOT EXISTS `eth_traces` (
+  `record_date` DATE NOT NULL COMMENT 'Partition column (YYYY-MM-DD)',
+  `trace_id` VARCHAR(255) NOT NULL COMMENT 'Unique identifier of the trace',
+  `transaction_hash` VARCHAR(80) NULL COMMENT 'Transaction hash where this trace was in',
+  `transaction_index` BIGINT NULL COMMENT 'Integer of the transactions index position in the block',
+  `from_address` VARCHAR(64) NULL COMMENT 'Address of the sender, null when trace_type is genesis or reward',
+  `to_address` VARCHAR(64) NULL COMMENT 'Address of the receiver if trace_type is call, address of new contract or null if trace_type is create, beneficiary address if trace_type is suicide, miner address if trace_type is reward',
+  `value` DOUBLE NULL COMMENT 'Value transferred in wei',
+  `input` MEDIUMTEXT NULL COMMENT 'The data sent along with the message call',
+  `output` MEDIUMTEXT NULL COMMENT 'The output of the message call, bytecode of contract when trace_type is create',
+  `trace_type` VARCHAR(16) NULL COMMENT 'One of call, create, suicide, reward, genesis, daofork',
+  `call_type` VARCHAR(16) NULL COMMENT 'One of call, callcode, delegatecall, staticcall',
+  `reward_type` VARCHAR(16) NULL COMMENT 'One of block, uncle',
+  `gas` DOUBLE NULL COMMENT 'Gas provided with the message call',
+  `gas_used` DOUBLE NULL COMMENT 'Gas used by the message call',
+  `subtraces` BIGINT NULL COMMENT 'Number of subtraces',
+  `trace_address` VARCHAR(255) NULL COMMENT 'Comma separated list of trace address in call tree',
+  `error` TEXT NULL COMMENT 'Error if message call failed',
+  `status` BIGINT NULL COMMENT 'Either 1 (success) or 0 (failure, due to any operation that can cause the call itself or any top-level call to revert)',
+  `block_timestamp` TIMESTAMP NULL COMMENT 'Timestamp of the block where this trace was in',
+  `block_number` BIGINT NOT NULL COMMENT 'Block number where this trace was in',
+  `block_hash` VARCHAR(80) NOT NULL COMMENT 'Hash of the block where this trace was in',
+  PRIMARY KEY (`record_date`, `trace_id`)
+)
+PARTITION BY RANGE COLUMNS(`record_date`)
+INTERVAL (1 MONTH)
+FIRST PARTITION LESS THAN ('2015-07-01')
+LAST PARTITION LESS THAN ('2115-07-01')
+MAXVALUE PARTITION;
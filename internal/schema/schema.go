@@ -0,0 +1,84 @@
+// Package schema embeds the TiDB DDL for each supported chain and checks
+// downloaded parquet files for drift from the Go row types.
+package schema
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed btc.sql
+var BTC string
+
+//go:embed eth.sql
+var ETH string
+
+// Statements splits a DDL file into individual statements, dropping comments
+// and blank lines so each statement can be executed on its own.
+func Statements(ddl string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(ddl, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// Select returns the CREATE TABLE statements of the named tables in a DDL
+// file, in file order
+func Select(ddl string, tables ...string) string {
+	var selected []string
+	for _, statement := range Statements(ddl) {
+		for _, table := range tables {
+			if strings.HasPrefix(statement, "CREATE TABLE IF NOT EXISTS `"+table+"`") {
+				selected = append(selected, statement+";")
+			}
+		}
+	}
+	return strings.Join(selected, "\n\n")
+}
+
+// OmitColumns removes the definitions of omitted columns from the CREATE TABLE
+// statements of a DDL file. omitted maps table names to column names.
+func OmitColumns(ddl string, omitted map[string][]string) string {
+	var lines []string
+	var drop map[string]bool
+	for _, line := range strings.Split(ddl, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "CREATE TABLE") {
+			drop = make(map[string]bool)
+			if fields := strings.Fields(trimmed); len(fields) >= 2 {
+				table := strings.Trim(fields[len(fields)-2], "`")
+				for _, column := range omitted[table] {
+					drop[column] = true
+				}
+			}
+		}
+		if strings.HasPrefix(trimmed, "`") {
+			if column, _, ok := strings.Cut(trimmed[1:], "`"); ok && drop[column] {
+				continue
+			}
+		}
+		// Keep the column list valid if its last definition was dropped
+		if strings.HasPrefix(trimmed, ")") && len(lines) > 0 {
+			lines[len(lines)-1] = strings.TrimSuffix(lines[len(lines)-1], ",")
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			drop = nil
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,120 @@
+// Package script decodes Bitcoin output scripts.
+//
+// It disassembles script_hex into instructions and classifies the standard
+// templates (P2PK, P2PKH, P2SH, P2WPKH, P2WSH, P2TR, bare multisig and
+// OP_RETURN), extracting their public keys, hashes, m-of-n and witness version.
+// Class names follow Bitcoin Core, which is what the dataset's type field uses.
+package script
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTruncated is returned when a push runs past the end of the script
+var ErrTruncated = errors.New("script push runs past the end of the script")
+
+// Instruction is one opcode of a script with the data it pushes, if any
+type Instruction struct {
+	Op   Opcode
+	Data []byte
+}
+
+// String formats the instruction like Bitcoin Core's asm: pushes of up to four
+// bytes as numbers, longer pushes as hex and other opcodes by name
+func (i Instruction) String() string {
+	if !i.Op.IsPush() {
+		return i.Op.String()
+	}
+	if len(i.Data) <= 4 {
+		return fmt.Sprint(scriptNum(i.Data))
+	}
+	return hex.EncodeToString(i.Data)
+}
+
+// Disassemble splits a script into instructions. On a truncated push it returns
+// the instructions decoded so far together with ErrTruncated.
+func Disassemble(script []byte) ([]Instruction, error) {
+	var instructions []Instruction
+	for pos := 0; pos < len(script); {
+		op := Opcode(script[pos])
+		pos++
+
+		var size int
+		switch {
+		case op > 0 && op < OP_PUSHDATA1:
+			size = int(op)
+		case op == OP_PUSHDATA1:
+			if pos+1 > len(script) {
+				return instructions, ErrTruncated
+			}
+			size = int(script[pos])
+			pos++
+		case op == OP_PUSHDATA2:
+			if pos+2 > len(script) {
+				return instructions, ErrTruncated
+			}
+			size = int(binary.LittleEndian.Uint16(script[pos:]))
+			pos += 2
+		case op == OP_PUSHDATA4:
+			if pos+4 > len(script) {
+				return instructions, ErrTruncated
+			}
+			size = int(binary.LittleEndian.Uint32(script[pos:]))
+			pos += 4
+		}
+		if size > len(script)-pos {
+			return instructions, ErrTruncated
+		}
+
+		instruction := Instruction{Op: op}
+		if op.IsPush() {
+			instruction.Data = script[pos : pos+size]
+		}
+		instructions = append(instructions, instruction)
+		pos += size
+	}
+	return instructions, nil
+}
+
+// DisassembleHex decodes script_hex and disassembles it
+func DisassembleHex(scriptHex string) ([]Instruction, error) {
+	script, err := hex.DecodeString(scriptHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode script hex: %w", err)
+	}
+	return Disassemble(script)
+}
+
+// Asm formats a script the way Bitcoin Core's asm field does, ending with
+// "[error]" if the script is truncated
+func Asm(script []byte) string {
+	instructions, err := Disassemble(script)
+	parts := make([]string, 0, len(instructions)+1)
+	for _, instruction := range instructions {
+		parts = append(parts, instruction.String())
+	}
+	if err != nil {
+		parts = append(parts, "[error]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// scriptNum decodes a little-endian sign-magnitude script number
+func scriptNum(data []byte) int64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var n int64
+	for i, b := range data {
+		n |= int64(b) << (8 * i)
+	}
+	last := data[len(data)-1]
+	if last&0x80 != 0 {
+		return -(n &^ (int64(0x80) << (8 * (len(data) - 1))))
+	}
+	return n
+}
@@ -0,0 +1,163 @@
+package script
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// Class is a standard script template, named like Bitcoin Core's script types
+type Class string
+
+const (
+	NonStandard         Class = "nonstandard"
+	PubKey              Class = "pubkey"                // P2PK
+	PubKeyHash          Class = "pubkeyhash"            // P2PKH
+	ScriptHash          Class = "scripthash"            // P2SH
+	MultiSig            Class = "multisig"              // Bare m-of-n multisig
+	NullData            Class = "nulldata"              // OP_RETURN
+	WitnessV0KeyHash    Class = "witness_v0_keyhash"    // P2WPKH
+	WitnessV0ScriptHash Class = "witness_v0_scripthash" // P2WSH
+	WitnessV1Taproot    Class = "witness_v1_taproot"    // P2TR
+	WitnessUnknown      Class = "witness_unknown"       // Witness program of a future version
+)
+
+// Template is a classified script with the values its template carries
+type Template struct {
+	Class          Class
+	PubKeys        [][]byte // P2PK key, or the keys of a multisig
+	Hash           []byte   // Pubkey or script hash of P2PKH, P2SH, P2WPKH and P2WSH, or the P2TR output key
+	Required       int      // m of an m-of-n multisig
+	WitnessVersion int      // Witness version, -1 for non-witness scripts
+	WitnessProgram []byte   // Witness program of witness scripts
+	Data           [][]byte // Pushes after OP_RETURN
+}
+
+// Classify matches a script against the standard templates
+func Classify(script []byte) Template {
+	t := Template{Class: NonStandard, WitnessVersion: -1}
+
+	// Fixed layouts first, as Bitcoin Core does
+	switch {
+	case len(script) == 23 && Opcode(script[0]) == OP_HASH160 && script[1] == 20 && Opcode(script[22]) == OP_EQUAL:
+		t.Class, t.Hash = ScriptHash, script[2:22]
+		return t
+	case isWitnessProgram(script):
+		version, program := Opcode(script[0]).SmallInt(), script[2:]
+		t.WitnessVersion, t.WitnessProgram = version, program
+		switch {
+		case version == 0 && len(program) == 20:
+			t.Class, t.Hash = WitnessV0KeyHash, program
+		case version == 0 && len(program) == 32:
+			t.Class, t.Hash = WitnessV0ScriptHash, program
+		case version == 0:
+			// Version 0 programs of any other size are invalid
+			t.WitnessVersion, t.WitnessProgram = -1, nil
+		case version == 1 && len(program) == 32:
+			t.Class, t.Hash = WitnessV1Taproot, program
+		default:
+			t.Class = WitnessUnknown
+		}
+		return t
+	}
+
+	instructions, err := Disassemble(script)
+	if err != nil {
+		return t
+	}
+
+	switch {
+	case len(instructions) > 0 && instructions[0].Op == OP_RETURN && pushOnly(instructions[1:]):
+		t.Class = NullData
+		for _, instruction := range instructions[1:] {
+			t.Data = append(t.Data, instruction.Data)
+		}
+	case len(instructions) == 2 && isPubKey(instructions[0]) && instructions[1].Op == OP_CHECKSIG:
+		t.Class, t.PubKeys = PubKey, [][]byte{instructions[0].Data}
+	case len(instructions) == 5 && instructions[0].Op == OP_DUP && instructions[1].Op == OP_HASH160 &&
+		len(instructions[2].Data) == 20 && instructions[2].Op == 20 &&
+		instructions[3].Op == OP_EQUALVERIFY && instructions[4].Op == OP_CHECKSIG:
+		t.Class, t.Hash = PubKeyHash, instructions[2].Data
+	default:
+		if keys, required, ok := matchMultiSig(instructions); ok {
+			t.Class, t.PubKeys, t.Required = MultiSig, keys, required
+		}
+	}
+	return t
+}
+
+// ClassifyHex decodes script_hex and classifies it
+func ClassifyHex(scriptHex string) (Template, error) {
+	script, err := hex.DecodeString(scriptHex)
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to decode script hex: %w", err)
+	}
+	return Classify(script), nil
+}
+
+// MatchesType reports whether the template agrees with the type the dataset
+// reports for the script
+func (t Template) MatchesType(datasetType string) bool {
+	return string(t.Class) == datasetType
+}
+
+// isWitnessProgram reports whether a script is a version byte followed by a
+// single push of 2 to 40 bytes
+func isWitnessProgram(script []byte) bool {
+	if len(script) < 4 || len(script) > 42 {
+		return false
+	}
+	version := Opcode(script[0])
+	if version != OP_0 && (version < OP_1 || version > OP_16) {
+		return false
+	}
+	return int(script[1])+2 == len(script)
+}
+
+// matchMultiSig matches OP_m <pubkey>... OP_n OP_CHECKMULTISIG
+func matchMultiSig(instructions []Instruction) ([][]byte, int, bool) {
+	if len(instructions) < 4 || instructions[len(instructions)-1].Op != OP_CHECKMULTISIG {
+		return nil, 0, false
+	}
+	first, last := instructions[0].Op, instructions[len(instructions)-2].Op
+	if first == OP_0 || !first.IsSmallInt() || last == OP_0 || !last.IsSmallInt() {
+		return nil, 0, false
+	}
+	required, total := first.SmallInt(), last.SmallInt()
+	keys := instructions[1 : len(instructions)-2]
+	if len(keys) != total || required > total {
+		return nil, 0, false
+	}
+	pubKeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		if !isPubKey(key) {
+			return nil, 0, false
+		}
+		pubKeys[i] = key.Data
+	}
+	return pubKeys, required, true
+}
+
+// isPubKey reports whether an instruction pushes a compressed or uncompressed public key
+func isPubKey(instruction Instruction) bool {
+	data := instruction.Data
+	if !instruction.Op.IsPush() || int(instruction.Op) != len(data) {
+		return false
+	}
+	switch len(data) {
+	case 33:
+		return data[0] == 0x02 || data[0] == 0x03
+	case 65:
+		return data[0] == 0x04 || data[0] == 0x06 || data[0] == 0x07
+	}
+	return false
+}
+
+// pushOnly reports whether every instruction pushes data or a small number
+func pushOnly(instructions []Instruction) bool {
+	for _, instruction := range instructions {
+		if instruction.Op > OP_16 {
+			return false
+		}
+	}
+	return true
+}
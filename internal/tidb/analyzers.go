@@ -0,0 +1,51 @@
+package tidb
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Analyzers derive extra tables from the loaded rows, either while a file loads
+// or once every dataset of a date is loaded. Each one is opt-in, since its
+// tables must exist before sync writes to them.
+const (
+	AnalyzerOpReturns    = "op_returns"   // OP_RETURN payloads and protocol tags into btc_op_returns
+	AnalyzerInscriptions = "inscriptions" // Ordinal inscription envelopes in input witnesses into btc_inscriptions
+	AnalyzerMiners       = "miners"       // Mining pool of each block into btc_block_miners and btc_pool_daily_shares
+	AnalyzerBlockStats   = "block_stats"  // Fee rate distribution and fullness of each block into btc_block_stats and btc_block_stats_daily
+	AnalyzerSupply       = "supply"       // Subsidy claimed by each block and the circulating supply into btc_supply
+	AnalyzerPrivacy      = "privacy"      // CoinJoin and payjoin transactions with a confidence into btc_privacy_labels
+	AnalyzerClusters     = "clusters"     // Addresses spent together into entity clusters in btc_address_clusters and btc_cluster_stats
+)
+
+var analyzerNames = []string{AnalyzerOpReturns, AnalyzerInscriptions, AnalyzerMiners, AnalyzerBlockStats, AnalyzerSupply,
+	AnalyzerPrivacy, AnalyzerClusters}
+
+// Analyzers is the set of enabled analyzers
+type Analyzers map[string]bool
+
+// ParseAnalyzers parses a comma-separated list of analyzer names, or "all"
+func ParseAnalyzers(v string) (Analyzers, error) {
+	analyzers := make(Analyzers)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case name == "all":
+			for _, name := range analyzerNames {
+				analyzers[name] = true
+			}
+		case slices.Contains(analyzerNames, name):
+			analyzers[name] = true
+		default:
+			return nil, fmt.Errorf("unknown analyzer: %s (expected %s or all)", name, strings.Join(analyzerNames, ", "))
+		}
+	}
+	return analyzers, nil
+}
+
+// Enabled reports whether the named analyzer runs
+func (a Analyzers) Enabled(name string) bool {
+	return a[name]
+}
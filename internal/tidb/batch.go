@@ -0,0 +1,76 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// batchInserter buffers rows of one table and inserts them in full batches
+// with a prepared statement, and the rest with a direct insert
+type batchInserter[T any] struct {
+	db          *sql.DB
+	table       Table
+	batchSize   int
+	extractArgs extractArgsFunc[T]
+	stmt        *sql.Stmt
+	pending     []T
+}
+
+// newBatchInserter prepares the batch statement of table
+func newBatchInserter[T any](db *sql.DB, table Table, batchSize int, extractArgs extractArgsFunc[T]) (*batchInserter[T], error) {
+	batchSQL := table.insertSQL() + buildValuesSQL(batchSize, len(table.Columns))
+	stmt, err := retryWithBackoff(func() (*sql.Stmt, error) {
+		return db.Prepare(batchSQL)
+	}, "prepare "+table.Name+" statement")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare %s statement: %w", table.Name, err)
+	}
+	return &batchInserter[T]{
+		db:          db,
+		table:       table,
+		batchSize:   batchSize,
+		extractArgs: extractArgs,
+		stmt:        stmt,
+		pending:     make([]T, 0, batchSize),
+	}, nil
+}
+
+// add buffers rows for insertion
+func (b *batchInserter[T]) add(rows ...T) {
+	b.pending = append(b.pending, rows...)
+}
+
+// flushBatches inserts every full batch and returns the number of rows inserted
+func (b *batchInserter[T]) flushBatches() (int, error) {
+	inserted := 0
+	for len(b.pending) >= b.batchSize {
+		if err := batchInsertWithStmt(b.stmt, b.pending[:b.batchSize], b.extractArgs); err != nil {
+			return inserted, fmt.Errorf("failed to insert %s batch: %w", b.table.Name, err)
+		}
+		b.pending = b.pending[b.batchSize:]
+		inserted += b.batchSize
+	}
+	return inserted, nil
+}
+
+// flush inserts every buffered row and returns the number of rows inserted
+func (b *batchInserter[T]) flush() (int, error) {
+	inserted, err := b.flushBatches()
+	if err != nil {
+		return inserted, err
+	}
+	if len(b.pending) == 0 {
+		return inserted, nil
+	}
+	if err := directInsert(b.db, b.table.insertSQL(), b.pending, b.extractArgs, len(b.table.Columns)); err != nil {
+		return inserted, fmt.Errorf("failed to insert remaining %s: %w", b.table.Name, err)
+	}
+	inserted += len(b.pending)
+	b.pending = b.pending[:0]
+	return inserted, nil
+}
+
+// Close releases the prepared statement
+func (b *batchInserter[T]) Close() error {
+	return b.stmt.Close()
+}
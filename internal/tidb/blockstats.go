@@ -0,0 +1,208 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/consensus"
+)
+
+// statsBlock is the part of a block row the block_stats analyzer reads
+type statsBlock struct {
+	Date   string `parquet:"date"`
+	Hash   string `parquet:"hash"`
+	Number int64  `parquet:"number"`
+	Weight int64  `parquet:"weight,optional"`
+}
+
+// statsTransaction is the part of a transaction row the block_stats analyzer reads
+type statsTransaction struct {
+	BlockNumber int64   `parquet:"block_number"`
+	IsCoinbase  bool    `parquet:"is_coinbase,optional"`
+	VirtualSize int64   `parquet:"virtual_size,optional"`
+	Fee         float64 `parquet:"fee,optional"`
+}
+
+// feeRates summarizes the fee rates of a set of transactions in sat/vB
+type feeRates struct {
+	count                                int
+	min, p10, p25, median, p75, p90, max float64
+	mean                                 float64
+}
+
+// summarizeFeeRates sorts rates and summarizes them with nearest-rank percentiles
+func summarizeFeeRates(rates []float64) feeRates {
+	if len(rates) == 0 {
+		return feeRates{}
+	}
+	sort.Float64s(rates)
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p / 100 * float64(len(rates))))
+		return rates[max(rank, 1)-1]
+	}
+	var sum float64
+	for _, rate := range rates {
+		sum += rate
+	}
+	return feeRates{
+		count:  len(rates),
+		min:    rates[0],
+		p10:    percentile(10),
+		p25:    percentile(25),
+		median: percentile(50),
+		p75:    percentile(75),
+		p90:    percentile(90),
+		max:    rates[len(rates)-1],
+		mean:   sum / float64(len(rates)),
+	}
+}
+
+// args returns the fee rate columns, NULL when there are no transactions
+func (f feeRates) args() []interface{} {
+	if f.count == 0 {
+		return []interface{}{nil, nil, nil, nil, nil, nil, nil, nil}
+	}
+	return []interface{}{f.min, f.p10, f.p25, f.median, f.p75, f.p90, f.max, f.mean}
+}
+
+// blockStatsRow represents a row to insert into btc_block_stats, or into
+// btc_block_stats_daily when it sums the blocks of a date
+type blockStatsRow struct {
+	recordDate  time.Time
+	blockNumber int64
+	blockHash   string
+	blockCount  int64
+	txCount     int64 // Non-coinbase transactions
+	fees        int64 // Satoshis
+	subsidy     int64 // Satoshis
+	vsize       int64
+	weight      int64
+	rates       feeRates
+}
+
+// feeShare returns the fraction of the miner reward paid by fees
+func (r blockStatsRow) feeShare() interface{} {
+	if r.fees+r.subsidy == 0 {
+		return nil
+	}
+	return float64(r.fees) / float64(r.fees+r.subsidy)
+}
+
+// fullness returns the average weight of the blocks against the weight limit
+func (r blockStatsRow) fullness() interface{} {
+	if r.blockCount == 0 {
+		return nil
+	}
+	return float64(r.weight) / float64(r.blockCount) / consensus.MaxBlockWeight
+}
+
+func extractBlockStatsArgs(row blockStatsRow) []interface{} {
+	args := []interface{}{
+		row.recordDate, row.blockNumber, row.blockHash, row.txCount, btcValue(row.fees), btcValue(row.subsidy),
+		row.feeShare(), row.vsize, row.weight, row.fullness(),
+	}
+	return append(args, row.rates.args()...)
+}
+
+func extractDailyBlockStatsArgs(row blockStatsRow) []interface{} {
+	args := []interface{}{
+		row.recordDate, row.blockCount, row.txCount, btcValue(row.fees), btcValue(row.subsidy),
+		row.feeShare(), row.vsize, row.weight, row.fullness(),
+	}
+	return append(args, row.rates.args()...)
+}
+
+// btcValue converts satoshis to BTC
+func btcValue(sats int64) float64 {
+	return float64(sats) / 1e8
+}
+
+// deriveBlockStats computes the fee and fullness statistics of each block of
+// a date into btc_block_stats, and of the whole date into btc_block_stats_daily
+func deriveBlockStats(db *sql.DB, cfg *config.Config, blocksDir, transactionsDir string) error {
+	blocks, err := readDirRows[statsBlock](cfg, blocksDir, nil)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	transactions, err := readDirRows[statsTransaction](cfg, transactionsDir, nil)
+	if err != nil {
+		return err
+	}
+	byBlock := make(map[int64][]statsTransaction, len(blocks))
+	for _, tx := range transactions {
+		byBlock[tx.BlockNumber] = append(byBlock[tx.BlockNumber], tx)
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Number < blocks[j].Number })
+	rows := make([]blockStatsRow, 0, len(blocks))
+	var daily blockStatsRow
+	var dailyRates []float64
+	for _, block := range blocks {
+		recordDate, err := time.Parse("2006-01-02", block.Date)
+		if err != nil {
+			return fmt.Errorf("invalid date %q in block %d: %w", block.Date, block.Number, err)
+		}
+		row := blockStatsRow{
+			recordDate:  recordDate,
+			blockNumber: block.Number,
+			blockHash:   block.Hash,
+			blockCount:  1,
+			subsidy:     consensus.Subsidy(block.Number),
+			weight:      block.Weight,
+		}
+		var rates []float64
+		var txWeight int64
+		for _, tx := range byBlock[block.Number] {
+			txWeight += tx.VirtualSize * 4
+			if tx.IsCoinbase {
+				continue
+			}
+			fee := satoshis(tx.Fee)
+			row.txCount++
+			row.fees += fee
+			row.vsize += tx.VirtualSize
+			if tx.VirtualSize > 0 {
+				rates = append(rates, float64(fee)/float64(tx.VirtualSize))
+			}
+		}
+		// Files without block weights get it from the transactions' virtual sizes
+		if row.weight == 0 {
+			row.weight = txWeight
+		}
+		dailyRates = append(dailyRates, rates...)
+		row.rates = summarizeFeeRates(rates)
+		rows = append(rows, row)
+
+		daily.recordDate = recordDate
+		daily.blockCount++
+		daily.txCount += row.txCount
+		daily.fees += row.fees
+		daily.subsidy += row.subsidy
+		daily.vsize += row.vsize
+		daily.weight += row.weight
+	}
+	daily.rates = summarizeFeeRates(dailyRates)
+
+	inserter, err := newBatchInserter(db, BtcBlockStatsTable, cfg.BlockBatchSize, extractBlockStatsArgs)
+	if err != nil {
+		return err
+	}
+	defer inserter.Close()
+	inserter.add(rows...)
+	if _, err := inserter.flush(); err != nil {
+		return err
+	}
+	if err := directInsert(db, BtcBlockStatsDailyTable.insertSQL(), []blockStatsRow{daily}, extractDailyBlockStatsArgs,
+		len(BtcBlockStatsDailyTable.Columns)); err != nil {
+		return fmt.Errorf("failed to insert daily block stats: %w", err)
+	}
+	fmt.Printf("Computed fee statistics of %d blocks (median fee rate %.1f sat/vB)\n", len(rows), daily.rates.median)
+	return nil
+}
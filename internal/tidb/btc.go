@@ -6,12 +6,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/address"
 	"github.com/siddon/web3insights/internal/chain"
 	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/opreturn"
 )
 
 // inputRow represents a row to insert into btc_transaction_inputs
@@ -43,6 +46,19 @@ type outputRow struct {
 	outputAmount    float64
 }
 
+// opReturnRow represents a row to insert into btc_op_returns
+type opReturnRow struct {
+	recordDate      time.Time
+	transactionHash string
+	outputIndex     int64
+	blockNumber     int64
+	protocol        string
+	data            []byte
+	dataText        interface{} // NULL unless the payload is readable text
+	pushCount       int
+	outputAmount    float64
+}
+
 // buildValuesSQL builds a VALUES clause with the specified number of rows and placeholders per row
 func buildValuesSQL(rowCount, placeholderCount int) string {
 	if rowCount == 0 {
@@ -129,7 +145,7 @@ func LoadBtcBlocksWithProgress(db *sql.DB, filePath string, cfg *config.Config,
 
 // LoadBtcBlocksWithProgressAndRow reads a block parquet file and inserts with row
 func LoadBtcBlocksWithProgressAndRow(db *sql.DB, filePath string, cfg *config.Config, onProgress ProgressCallback, startRow int64) error {
-	return insertBlocksFromFile(db, filePath, cfg.BlockBatchSize, onProgress, startRow)
+	return insertBlocksFromFile(db, cfg, filePath, onProgress, startRow)
 }
 
 // LoadBtcTransactions reads a transaction parquet file and inserts into btc_transactions, btc_transaction_inputs, and btc_transaction_outputs tables
@@ -144,7 +160,7 @@ func LoadBtcTransactionsWithProgress(db *sql.DB, filePath string, cfg *config.Co
 
 // LoadBtcTransactionsWithProgressAndRow reads a transaction parquet file and inserts with row
 func LoadBtcTransactionsWithProgressAndRow(db *sql.DB, filePath string, cfg *config.Config, onProgress ProgressCallback, startRow int64) error {
-	return insertTransactionsFromFile(db, filePath, cfg.TransactionBatchSize, cfg.InputBatchSize, cfg.OutputBatchSize, onProgress, startRow)
+	return insertTransactionsFromFile(db, cfg, filePath, onProgress, startRow)
 }
 
 // extractBlockArgs extracts SQL arguments from a BtcBlock
@@ -263,113 +279,59 @@ func extractOutputArgs(output outputRow) []interface{} {
 	}
 }
 
-// insertBlocksFromFile reads a block parquet file and inserts into btc_blocks table
-func insertBlocksFromFile(db *sql.DB, filePath string, batchSize int, onProgress ProgressCallback, startRow int64) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+// extractOpReturnArgs extracts SQL arguments from an opReturnRow
+func extractOpReturnArgs(opReturn opReturnRow) []interface{} {
+	return []interface{}{
+		opReturn.recordDate,
+		opReturn.transactionHash,
+		opReturn.outputIndex,
+		opReturn.blockNumber,
+		opReturn.protocol,
+		opReturn.data,
+		len(opReturn.data),
+		opReturn.dataText,
+		opReturn.pushCount,
+		opReturn.outputAmount,
 	}
-	defer file.Close()
+}
 
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to get file info: %w", err)
-	}
+// insertBlocksFromFile reads a block parquet file and inserts into btc_blocks table
+func insertBlocksFromFile(db *sql.DB, cfg *config.Config, filePath string, onProgress ProgressCallback, startRow int64) error {
+	return insertRowsFromFile(db, cfg, filePath, "blocks", "blocks", BtcBlocksTable, cfg.BlockBatchSize, extractBlockArgs, onProgress, startRow)
+}
 
-	parquetFile, err := parquet.OpenFile(file, fileInfo.Size())
+// insertTransactionsFromFile reads a transaction parquet file and inserts into btc_transactions, btc_transaction_inputs, and btc_transaction_outputs tables,
+// and into the tables of the enabled analyzers
+func insertTransactionsFromFile(db *sql.DB, cfg *config.Config, filePath string, onProgress ProgressCallback, startRow int64) error {
+	batchSize, inputBatchSize, outputBatchSize := cfg.TransactionBatchSize, cfg.InputBatchSize, cfg.OutputBatchSize
+
+	profiles, err := ParseProfiles(cfg.ColumnProfiles)
 	if err != nil {
-		return fmt.Errorf("failed to open parquet file: %w", err)
+		return err
 	}
-
-	schema := parquet.SchemaOf(chain.BtcBlock{})
-	reader := parquet.NewGenericReader[chain.BtcBlock](parquetFile, schema)
-	defer reader.Close()
-
-	// Get total number of rows in the file
-	numRows := parquetFile.NumRows()
-
-	// Seek to start row if resuming
-	if startRow > 0 {
-		if err := reader.SeekToRow(startRow); err != nil {
-			return fmt.Errorf("failed to seek to row %d: %w", startRow, err)
-		}
-		fmt.Printf("Resuming from row %d/%d in %s\n", startRow, numRows, filepath.Base(filePath))
+	deriveAddresses, err := ParseDeriveAddresses(cfg.DeriveAddresses)
+	if err != nil {
+		return err
 	}
-
-	baseSQL := "INSERT IGNORE INTO btc_blocks (" +
-		"record_date, hash, size, stripped_size, weight, number, version, merkle_root," +
-		"block_timestamp, nonce, bits, coinbase_param, transaction_count, mediantime," +
-		"difficulty, chainwork, previousblockhash" +
-		") VALUES "
-
-	// Prepare statement once for reuse
-	valuesSQL := buildValuesSQL(batchSize, 17)
-	batchSQL := baseSQL + valuesSQL
-
-	stmt, err := retryWithBackoff(func() (*sql.Stmt, error) {
-		return db.Prepare(batchSQL)
-	}, "prepare block statement")
+	analyzers, err := ParseAnalyzers(cfg.Analyzers)
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return err
 	}
-	defer stmt.Close()
-
-	pendingBlocks := make([]chain.BtcBlock, 0, batchSize)
-
-	var totalRows int64 = startRow
-
-	for {
-		n, err := reader.Read(pendingBlocks[:batchSize])
-
-		if err != nil && err != io.EOF {
-			return fmt.Errorf("failed to read parquet file: %w", err)
-		}
-
-		pendingBlocks = pendingBlocks[:n]
-
-		if n < batchSize || err == io.EOF {
-			break
-		}
-
-		batch := pendingBlocks[:batchSize]
-
-		if err := batchInsertWithStmt(stmt, batch, extractBlockArgs); err != nil {
-			return fmt.Errorf("failed to insert block batch: %w", err)
-		}
-
-		totalRows += int64(len(batch))
-
-		fmt.Printf("Inserted %d blocks from %s (total: %d/%d)\n", len(batch), filepath.Base(filePath), totalRows, numRows)
-
-		// Call progress callback after each batch
-		if onProgress != nil {
-			if err := onProgress(filePath, totalRows, numRows); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: progress callback failed: %v\n", err)
-			}
-		}
+	skip := profiles.SkippedSources(BtcTransactionsTable, BtcTransactionInputsTable, BtcTransactionOutputsTable)
+	if deriveAddresses != DeriveAddressesOff {
+		// Addresses are derived from the output scripts even when they aren't stored
+		skip = slices.DeleteFunc(skip, func(column string) bool { return column == "outputs.script_hex" })
 	}
-
-	// Process remaining blocks with direct SQL
-	if len(pendingBlocks) > 0 {
-		if err := directInsert(db, baseSQL, pendingBlocks, extractBlockArgs, 17); err != nil {
-			return fmt.Errorf("failed to insert remaining blocks: %w", err)
-		}
-		totalRows += int64(len(pendingBlocks))
-		fmt.Printf("Inserted %d remaining blocks from %s (total: %d/%d)\n", len(pendingBlocks), filepath.Base(filePath), totalRows, numRows)
-
-		// Call progress callback after remaining blocks (always save at end)
-		if onProgress != nil {
-			if err := onProgress(filePath, totalRows, numRows); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: progress callback failed: %v\n", err)
-			}
-		}
+	if analyzers.Enabled(AnalyzerOpReturns) {
+		// Payloads come from the output scripts, and Counterparty keys from the first spent txid
+		skip = slices.DeleteFunc(skip, func(column string) bool {
+			return column == "outputs.script_hex" || column == "inputs.spent_transaction_hash"
+		})
 	}
+	txTable, extractTransactionArgs := projectTable(profiles, BtcTransactionsTable, extractTransactionArgs)
+	inputTable, extractInputArgs := projectTable(profiles, BtcTransactionInputsTable, extractInputArgs)
+	outputTable, extractOutputArgs := projectTable(profiles, BtcTransactionOutputsTable, extractOutputArgs)
 
-	return nil
-}
-
-// insertTransactionsFromFile reads a transaction parquet file and inserts into btc_transactions, btc_transaction_inputs, and btc_transaction_outputs tables
-func insertTransactionsFromFile(db *sql.DB, filePath string, batchSize, inputBatchSize, outputBatchSize int, onProgress ProgressCallback, startRow int64) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %w", filePath, err)
@@ -386,29 +348,24 @@ func insertTransactionsFromFile(db *sql.DB, filePath string, batchSize, inputBat
 		return fmt.Errorf("failed to open parquet file: %w", err)
 	}
 
-	schema := parquet.SchemaOf(chain.BtcTransaction{})
-	reader := parquet.NewGenericReader[chain.BtcTransaction](parquetFile, schema)
-	defer reader.Close()
-
 	// Get total number of rows in the file
 	numRows := parquetFile.NumRows()
 
-	// Seek to start row if resuming
+	// Start reading at startRow if resuming
+	reader, err := openRowReader[chain.BtcTransaction](cfg, parquetFile, filePath, "transactions", skip, startRow)
+	if err != nil {
+		return fmt.Errorf("failed to seek to row %d: %w", startRow, err)
+	}
+	defer reader.Close()
 	if startRow > 0 {
-		if err := reader.SeekToRow(startRow); err != nil {
-			return fmt.Errorf("failed to seek to row %d: %w", startRow, err)
-		}
 		fmt.Printf("Resuming from row %d/%d in %s\n", startRow, numRows, filepath.Base(filePath))
 	}
 
 	// Prepare transaction statement once for reuse
-	txBaseSQL := "INSERT IGNORE INTO btc_transactions (" +
-		"record_date, hash, size, virtual_size, version, lock_time, block_hash, block_number," +
-		"block_timestamp, tx_index, input_count, output_count, input_value, output_value," +
-		"is_coinbase, fee" +
-		") VALUES "
+	txBaseSQL := txTable.insertSQL()
+	txColumns := len(txTable.Columns)
 
-	txValuesSQL := buildValuesSQL(batchSize, 16)
+	txValuesSQL := buildValuesSQL(batchSize, txColumns)
 	txBatchSQL := txBaseSQL + txValuesSQL
 
 	txStmt, err := retryWithBackoff(func() (*sql.Stmt, error) {
@@ -419,43 +376,27 @@ func insertTransactionsFromFile(db *sql.DB, filePath string, batchSize, inputBat
 	}
 	defer txStmt.Close()
 
-	// Prepare input and output statements once for reuse
-	inputBaseSQL := "INSERT IGNORE INTO btc_transaction_inputs (" +
-		"record_date, transaction_hash, input_index, spent_transaction_hash, spent_output_index," +
-		"script_asm, script_hex, sequence, required_signatures, input_type, address, spent_value" +
-		") VALUES "
-
-	outputBaseSQL := "INSERT IGNORE INTO btc_transaction_outputs (" +
-		"record_date, transaction_hash, output_index, script_asm, script_hex, required_signatures," +
-		"output_type, address, output_amount" +
-		") VALUES "
-
-	// Prepare statements for input/output batch sizes
-	inputValuesSQL := buildValuesSQL(inputBatchSize, 12)
-	inputBatchSQL := inputBaseSQL + inputValuesSQL
-	inputStmt, err := retryWithBackoff(func() (*sql.Stmt, error) {
-		return db.Prepare(inputBatchSQL)
-	}, "prepare input statement")
+	inputs, err := newBatchInserter(db, inputTable, inputBatchSize, extractInputArgs)
 	if err != nil {
-		return fmt.Errorf("failed to prepare input statement: %w", err)
+		return err
 	}
-	defer inputStmt.Close()
-
-	outputValuesSQL := buildValuesSQL(outputBatchSize, 9)
-	outputBatchSQL := outputBaseSQL + outputValuesSQL
-	outputStmt, err := retryWithBackoff(func() (*sql.Stmt, error) {
-		return db.Prepare(outputBatchSQL)
-	}, "prepare output statement")
+	defer inputs.Close()
+	outputs, err := newBatchInserter(db, outputTable, outputBatchSize, extractOutputArgs)
 	if err != nil {
-		return fmt.Errorf("failed to prepare output statement: %w", err)
+		return err
+	}
+	defer outputs.Close()
+	var opReturns *batchInserter[opReturnRow]
+	if analyzers.Enabled(AnalyzerOpReturns) {
+		opReturns, err = newBatchInserter(db, BtcOpReturnsTable, outputBatchSize, extractOpReturnArgs)
+		if err != nil {
+			return err
+		}
+		defer opReturns.Close()
 	}
-	defer outputStmt.Close()
 
 	pendingTxs := make([]chain.BtcTransaction, batchSize)
 
-	pendingInputs := make([]inputRow, 0, inputBatchSize)
-	pendingOutputs := make([]outputRow, 0, outputBatchSize)
-
 	var totalRows int64 = startRow
 
 	for {
@@ -465,7 +406,10 @@ func insertTransactionsFromFile(db *sql.DB, filePath string, batchSize, inputBat
 		}
 
 		pendingTxs = pendingTxs[:n]
-		pendingInputs, pendingOutputs = collectTransactionData(pendingTxs[:n], pendingInputs, pendingOutputs)
+		inputs.pending, outputs.pending = collectTransactionData(pendingTxs[:n], deriveAddresses, inputs.pending, outputs.pending)
+		if opReturns != nil {
+			opReturns.pending = collectOpReturns(pendingTxs[:n], opReturns.pending)
+		}
 
 		processedCount := 0
 		if len(pendingTxs) == batchSize {
@@ -476,22 +420,17 @@ func insertTransactionsFromFile(db *sql.DB, filePath string, batchSize, inputBat
 			processedCount = batchSize
 		}
 
-		inputNum := 0
-		for len(pendingInputs) >= inputBatchSize {
-			batch := pendingInputs[:inputBatchSize]
-			pendingInputs = pendingInputs[inputBatchSize:]
-			inputNum += inputBatchSize
-			if err := batchInsertWithStmt(inputStmt, batch, extractInputArgs); err != nil {
-				return fmt.Errorf("failed to insert input batch: %w", err)
-			}
+		inputNum, flushErr := inputs.flushBatches()
+		if flushErr != nil {
+			return flushErr
 		}
-		outputNum := 0
-		for len(pendingOutputs) >= outputBatchSize {
-			batch := pendingOutputs[:outputBatchSize]
-			pendingOutputs = pendingOutputs[outputBatchSize:]
-			outputNum += outputBatchSize
-			if err := batchInsertWithStmt(outputStmt, batch, extractOutputArgs); err != nil {
-				return fmt.Errorf("failed to insert output batch: %w", err)
+		outputNum, flushErr := outputs.flushBatches()
+		if flushErr != nil {
+			return flushErr
+		}
+		if opReturns != nil {
+			if _, err := opReturns.flushBatches(); err != nil {
+				return err
 			}
 		}
 
@@ -515,25 +454,34 @@ func insertTransactionsFromFile(db *sql.DB, filePath string, batchSize, inputBat
 
 	// Process remaining transactions - try batch first, then direct for remaining
 	if len(pendingTxs) > 0 {
-		if err := directInsert(db, txBaseSQL, pendingTxs, extractTransactionArgs, 16); err != nil {
+		if err := directInsert(db, txBaseSQL, pendingTxs, extractTransactionArgs, txColumns); err != nil {
 			return fmt.Errorf("failed to insert remaining transactions: %w", err)
 		}
 		// Only increment if we haven't already processed these transactions
 		// (they were read but not processed in the loop because n < batchSize)
 		totalRows += int64(len(pendingTxs))
 	}
-	if len(pendingInputs) > 0 {
-		if err := directInsert(db, inputBaseSQL, pendingInputs, extractInputArgs, 12); err != nil {
-			return fmt.Errorf("failed to insert remaining inputs: %w", err)
-		}
+	remainingInputs, err := inputs.flush()
+	if err != nil {
+		return err
 	}
-	if len(pendingOutputs) > 0 {
-		if err := directInsert(db, outputBaseSQL, pendingOutputs, extractOutputArgs, 9); err != nil {
-			return fmt.Errorf("failed to insert remaining outputs: %w", err)
+	remainingOutputs, err := outputs.flush()
+	if err != nil {
+		return err
+	}
+	if opReturns != nil {
+		if _, err := opReturns.flush(); err != nil {
+			return err
 		}
 	}
-	if len(pendingTxs) > 0 || len(pendingInputs) > 0 || len(pendingOutputs) > 0 {
-		fmt.Printf("Inserted remaining %d transactions, %d inputs, %d outputs from %s (total rows: %d/%d)\n", len(pendingTxs), len(pendingInputs), len(pendingOutputs), filepath.Base(filePath), totalRows, numRows)
+	if len(pendingTxs) > 0 || remainingInputs > 0 || remainingOutputs > 0 {
+		fmt.Printf("Inserted remaining %d transactions, %d inputs, %d outputs from %s (total rows: %d/%d)\n", len(pendingTxs), remainingInputs, remainingOutputs, filepath.Base(filePath), totalRows, numRows)
+	}
+
+	if analyzers.Enabled(AnalyzerInscriptions) {
+		if err := insertInscriptionsFromFile(db, cfg, parquetFile, filePath); err != nil {
+			return fmt.Errorf("failed to insert inscriptions: %w", err)
+		}
 	}
 
 	// Call progress callback after remaining items (always save at end)
@@ -546,8 +494,9 @@ func insertTransactionsFromFile(db *sql.DB, filePath string, batchSize, inputBat
 	return nil
 }
 
-// collectTransactionData collects inputs and outputs from transactions
-func collectTransactionData(transactions []chain.BtcTransaction, allInputs []inputRow, allOutputs []outputRow) ([]inputRow, []outputRow) {
+// collectTransactionData collects inputs and outputs from transactions, deriving
+// output addresses according to deriveAddresses
+func collectTransactionData(transactions []chain.BtcTransaction, deriveAddresses string, allInputs []inputRow, allOutputs []outputRow) ([]inputRow, []outputRow) {
 	for _, tx := range transactions {
 		// Parse date string to time.Time
 		date, err := time.Parse("2006-01-02", tx.Date)
@@ -580,7 +529,7 @@ func collectTransactionData(transactions []chain.BtcTransaction, allInputs []inp
 				scriptHex:       output.ScriptHex,
 				requiredSigs:    output.RequiredSignatures,
 				outputType:      output.Type,
-				address:         output.Address,
+				address:         outputAddress(output, deriveAddresses),
 				outputAmount:    output.Value,
 			})
 		}
@@ -588,3 +537,72 @@ func collectTransactionData(transactions []chain.BtcTransaction, allInputs []inp
 
 	return allInputs, allOutputs
 }
+
+// collectOpReturns collects the payloads of the OP_RETURN outputs of transactions
+func collectOpReturns(transactions []chain.BtcTransaction, allOpReturns []opReturnRow) []opReturnRow {
+	for _, tx := range transactions {
+		date, err := time.Parse("2006-01-02", tx.Date)
+		if err != nil {
+			date = time.Time{}
+		}
+		var firstInputTxid string
+		if len(tx.Inputs) > 0 {
+			firstInputTxid = tx.Inputs[0].SpentTransactionHash
+		}
+		for i, output := range tx.Outputs {
+			payload, ok, err := opreturn.ParseHex(output.ScriptHex, firstInputTxid)
+			if err != nil || !ok {
+				continue
+			}
+			var dataText interface{}
+			if text, ok := payload.Text(); ok {
+				dataText = text
+			}
+			allOpReturns = append(allOpReturns, opReturnRow{
+				recordDate:      date,
+				transactionHash: tx.Hash,
+				outputIndex:     int64(i),
+				blockNumber:     tx.BlockNumber,
+				protocol:        string(payload.Protocol),
+				data:            payload.Data,
+				dataText:        dataText,
+				pushCount:       payload.Pushes,
+				outputAmount:    output.Value,
+			})
+		}
+	}
+	return allOpReturns
+}
+
+// Output address derivation modes, set with derive_addresses
+const (
+	DeriveAddressesOff       = "off"
+	DeriveAddressesMissing   = "missing"   // Fill empty output addresses from script_hex
+	DeriveAddressesCanonical = "canonical" // Also replace dataset addresses with the derived encoding
+)
+
+// ParseDeriveAddresses validates a derive_addresses value; empty means off
+func ParseDeriveAddresses(v string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(v)); mode {
+	case "":
+		return DeriveAddressesOff, nil
+	case DeriveAddressesOff, DeriveAddressesMissing, DeriveAddressesCanonical:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown derive_addresses mode: %s (expected off, missing or canonical)", v)
+	}
+}
+
+// outputAddress returns the address stored for an output. Derived addresses
+// are only used when the script pays to exactly one address, so bare multisig
+// outputs with several keys keep the dataset value.
+func outputAddress(output chain.BtcTransactionOutput, mode string) string {
+	if mode == DeriveAddressesOff || (mode == DeriveAddressesMissing && output.Address != "") {
+		return output.Address
+	}
+	addresses, err := address.FromScriptHex(output.ScriptHex, address.Mainnet)
+	if err != nil || len(addresses) != 1 {
+		return output.Address
+	}
+	return addresses[0]
+}
@@ -0,0 +1,315 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/siddon/web3insights/internal/clusters"
+	"github.com/siddon/web3insights/internal/config"
+)
+
+// clusterLookupBatch is the number of addresses or clusters per lookup query
+const clusterLookupBatch = 1000
+
+// clusterBatchRows is the number of address rows a cluster update transaction
+// writes before it is committed
+const clusterBatchRows = 5000
+
+// clusterTransaction is the part of a transaction row the clusters analyzer reads
+type clusterTransaction struct {
+	IsCoinbase bool          `parquet:"is_coinbase,optional"`
+	Inputs     []privacyCoin `parquet:"inputs,list,optional"`
+	Outputs    []privacyCoin `parquet:"outputs,list,optional"`
+}
+
+// clusterStats is a btc_cluster_stats row
+type clusterStats struct {
+	addressCount int64
+	firstDate    time.Time
+	lastDate     time.Time
+}
+
+// clusterMerge relabels clusters into a target cluster and adds new addresses to it
+type clusterMerge struct {
+	target  int64
+	merged  []int64          // Clusters relabeled into target
+	anchors map[int64]string // An address of the date in each merged cluster
+	added   []string         // Addresses new to any cluster
+	stats   clusterStats
+}
+
+// deriveClusters applies the common-input-ownership heuristic to the
+// transactions of a date. btc_address_clusters is a union-find kept on disk:
+// each linked address points straight at its cluster, and when a date links
+// clusters together the smaller ones are relabeled into the largest. Large
+// clusters are relabeled in chunks, each its own database transaction, but the
+// date's own addresses move last along with the stats, so an interrupted date
+// finds the same clusters to merge when it is rerun.
+func deriveClusters(db *sql.DB, cfg *config.Config, transactionsDir, date string) error {
+	recordDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return fmt.Errorf("invalid date %s: %w", date, err)
+	}
+
+	// Link the date's addresses in memory first
+	uf := &clusters.UnionFind{}
+	index := make(map[string]int)
+	var addresses []string
+	node := func(address string) int {
+		id, ok := index[address]
+		if !ok {
+			id = uf.Add()
+			index[address] = id
+			addresses = append(addresses, address)
+		}
+		return id
+	}
+	linking := 0
+	if err := forEachDirRow(cfg, transactionsDir, func(row *clusterTransaction) {
+		linked := clusters.LinkedAddresses(privacyTransaction(row.IsCoinbase, row.Inputs, row.Outputs))
+		if len(linked) == 0 {
+			return
+		}
+		linking++
+		first := node(linked[0])
+		for _, address := range linked[1:] {
+			uf.Union(first, node(address))
+		}
+	}); err != nil {
+		return err
+	}
+	if linking == 0 {
+		return nil
+	}
+
+	// Then join in the clusters the addresses already belong to
+	existing, err := lookupAddressClusters(db, addresses)
+	if err != nil {
+		return err
+	}
+	clusterNodes := make(map[int64]int)
+	nodeClusters := make(map[int]int64)
+	for address, cluster := range existing {
+		id, ok := clusterNodes[cluster]
+		if !ok {
+			id = uf.Add()
+			clusterNodes[cluster] = id
+			nodeClusters[id] = cluster
+		}
+		uf.Union(index[address], id)
+	}
+	ids := make([]int64, 0, len(clusterNodes))
+	for cluster := range clusterNodes {
+		ids = append(ids, cluster)
+	}
+	stats, err := lookupClusterStats(db, ids)
+	if err != nil {
+		return err
+	}
+	var nextID int64
+	if err := db.QueryRow("SELECT COALESCE(MAX(cluster_id), 0) + 1 FROM btc_cluster_stats").Scan(&nextID); err != nil {
+		return fmt.Errorf("failed to query the next cluster id: %w", err)
+	}
+
+	var merges []clusterMerge
+	for _, set := range uf.Sets() {
+		merge := clusterMerge{
+			anchors: make(map[int64]string),
+			stats:   clusterStats{firstDate: recordDate, lastDate: recordDate},
+		}
+		var found []int64
+		for _, id := range set {
+			if cluster, ok := nodeClusters[id]; ok {
+				found = append(found, cluster)
+			} else if cluster, ok := existing[addresses[id]]; ok {
+				merge.anchors[cluster] = addresses[id]
+			} else {
+				merge.added = append(merge.added, addresses[id])
+			}
+		}
+		// The largest cluster keeps its id, so the fewest addresses are relabeled
+		sort.Slice(found, func(i, j int) bool {
+			if stats[found[i]].addressCount != stats[found[j]].addressCount {
+				return stats[found[i]].addressCount > stats[found[j]].addressCount
+			}
+			return found[i] < found[j]
+		})
+		if len(found) == 0 {
+			merge.target = nextID
+			nextID++
+		} else {
+			merge.target, merge.merged = found[0], found[1:]
+		}
+		merge.stats.addressCount = int64(len(merge.added))
+		for _, cluster := range found {
+			s, ok := stats[cluster]
+			if !ok {
+				continue
+			}
+			merge.stats.addressCount += s.addressCount
+			if s.firstDate.Before(merge.stats.firstDate) {
+				merge.stats.firstDate = s.firstDate
+			}
+			if s.lastDate.After(merge.stats.lastDate) {
+				merge.stats.lastDate = s.lastDate
+			}
+		}
+		merges = append(merges, merge)
+	}
+
+	relabeled := 0
+	for start := 0; start < len(merges); {
+		end, rows := start, 0
+		for end < len(merges) && (end == start || rows < clusterBatchRows) {
+			rows += len(merges[end].added) + len(merges[end].merged)
+			end++
+		}
+		if err := applyClusterMerges(db, merges[start:end]); err != nil {
+			return err
+		}
+		for _, merge := range merges[start:end] {
+			relabeled += len(merge.merged)
+		}
+		start = end
+	}
+	fmt.Printf("Clustered %d addresses linked by %d transactions (%d clusters merged)\n", len(addresses), linking, relabeled)
+	return nil
+}
+
+// applyClusterMerges writes a batch of merges. The merged clusters are
+// relabeled in chunks first, except for their anchors, which move in the one
+// transaction that writes the new addresses and the stats.
+func applyClusterMerges(db *sql.DB, merges []clusterMerge) error {
+	for _, merge := range merges {
+		for _, cluster := range merge.merged {
+			if err := relabelCluster(db, cluster, merge.target, merge.anchors[cluster]); err != nil {
+				return err
+			}
+		}
+	}
+	return retryWithBackoffNoReturn(func() error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		var addressRows, statsRows [][]interface{}
+		for _, merge := range merges {
+			if len(merge.merged) > 0 {
+				placeholders := strings.Repeat("?, ", len(merge.merged)-1) + "?"
+				args := make([]interface{}, 0, len(merge.merged)+1)
+				args = append(args, merge.target)
+				for _, cluster := range merge.merged {
+					args = append(args, cluster)
+				}
+				if _, err := tx.Exec("UPDATE btc_address_clusters SET cluster_id = ? WHERE cluster_id IN ("+placeholders+")", args...); err != nil {
+					return fmt.Errorf("failed to relabel clusters: %w", err)
+				}
+				if _, err := tx.Exec("DELETE FROM btc_cluster_stats WHERE cluster_id IN ("+placeholders+")", args[1:]...); err != nil {
+					return fmt.Errorf("failed to delete merged cluster stats: %w", err)
+				}
+			}
+			for _, address := range merge.added {
+				addressRows = append(addressRows, []interface{}{address, merge.target})
+			}
+			statsRows = append(statsRows, []interface{}{
+				merge.target, merge.stats.addressCount, merge.stats.firstDate, merge.stats.lastDate,
+			})
+		}
+		if err := insertTokenRows(tx, BtcAddressClustersTable.insertSQL(), len(BtcAddressClustersTable.Columns), addressRows); err != nil {
+			return fmt.Errorf("failed to insert clustered addresses: %w", err)
+		}
+		if err := insertTokenRows(tx, BtcClusterStatsTable.insertSQL(), len(BtcClusterStatsTable.Columns), statsRows); err != nil {
+			return fmt.Errorf("failed to update cluster stats: %w", err)
+		}
+		return tx.Commit()
+	}, "apply cluster merges")
+}
+
+// relabelCluster moves the addresses of a cluster other than anchor into
+// target, clusterBatchRows at a time
+func relabelCluster(db *sql.DB, cluster, target int64, anchor string) error {
+	for {
+		var relabeled int64
+		if err := retryWithBackoffNoReturn(func() error {
+			result, err := db.Exec("UPDATE btc_address_clusters SET cluster_id = ? WHERE cluster_id = ? AND address <> ? LIMIT ?",
+				target, cluster, anchor, clusterBatchRows)
+			if err != nil {
+				return err
+			}
+			relabeled, err = result.RowsAffected()
+			return err
+		}, "relabel cluster"); err != nil {
+			return fmt.Errorf("failed to relabel cluster %d: %w", cluster, err)
+		}
+		if relabeled < clusterBatchRows {
+			return nil
+		}
+	}
+}
+
+// lookupAddressClusters returns the cluster of each address that has one
+func lookupAddressClusters(db *sql.DB, addresses []string) (map[string]int64, error) {
+	clustersByAddress := make(map[string]int64)
+	for start := 0; start < len(addresses); start += clusterLookupBatch {
+		batch := addresses[start:min(start+clusterLookupBatch, len(addresses))]
+		args := make([]interface{}, len(batch))
+		for i, address := range batch {
+			args[i] = address
+		}
+		rows, err := db.Query("SELECT address, cluster_id FROM btc_address_clusters WHERE address IN ("+
+			strings.Repeat("?, ", len(batch)-1)+"?)", args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query address clusters: %w", err)
+		}
+		for rows.Next() {
+			var address string
+			var cluster int64
+			if err := rows.Scan(&address, &cluster); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan address cluster: %w", err)
+			}
+			clustersByAddress[address] = cluster
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read address clusters: %w", err)
+		}
+	}
+	return clustersByAddress, nil
+}
+
+// lookupClusterStats returns the stats of each cluster that has them
+func lookupClusterStats(db *sql.DB, ids []int64) (map[int64]clusterStats, error) {
+	stats := make(map[int64]clusterStats, len(ids))
+	for start := 0; start < len(ids); start += clusterLookupBatch {
+		batch := ids[start:min(start+clusterLookupBatch, len(ids))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		rows, err := db.Query("SELECT cluster_id, address_count, first_date, last_date FROM btc_cluster_stats WHERE cluster_id IN ("+
+			strings.Repeat("?, ", len(batch)-1)+"?)", args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query cluster stats: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			var s clusterStats
+			if err := rows.Scan(&id, &s.addressCount, &s.firstDate, &s.lastDate); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan cluster stats: %w", err)
+			}
+			stats[id] = s
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read cluster stats: %w", err)
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,178 @@
+package tidb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// coinDaysMetricName is the btc_metrics_progress row of the coin_days metric
+const coinDaysMetricName = "coin_days"
+
+// hashPrefixes splits the statements of a date by the first hex digit of the
+// transaction hash, so each stays well within TiDB's transaction size limit
+const hashPrefixes = "0123456789abcdef"
+
+// utxoDeleteBatch is the number of spent outputs deleted per statement
+const utxoDeleteBatch = 50000
+
+// ageBucket is a HODL wave band of unspent outputs at least minDays old
+type ageBucket struct {
+	label   string
+	minDays int
+}
+
+// ageBuckets are the HODL wave bands, youngest first
+var ageBuckets = []ageBucket{
+	{"<1d", 0}, {"1d-1w", 1}, {"1w-1m", 7}, {"1m-6m", 30}, {"6m-1y", 182}, {"1y-2y", 365}, {"2y-5y", 730}, {"5y+", 1825},
+}
+
+// ageBucketSQL returns a CASE expression for the band of an age in days,
+// oldest band first
+func ageBucketSQL(age string) (label, minDays string) {
+	var labels, mins strings.Builder
+	labels.WriteString("CASE")
+	mins.WriteString("CASE")
+	for i := len(ageBuckets) - 1; i >= 0; i-- {
+		fmt.Fprintf(&labels, " WHEN %s >= %d THEN '%s'", age, ageBuckets[i].minDays, ageBuckets[i].label)
+		fmt.Fprintf(&mins, " WHEN %s >= %d THEN %d", age, ageBuckets[i].minDays, ageBuckets[i].minDays)
+	}
+	labels.WriteString(" END")
+	mins.WriteString(" END")
+	return labels.String(), mins.String()
+}
+
+// CoinDaysProgress returns the first and last dates the coin_days metric has
+// computed, or empty strings if it hasn't started
+func CoinDaysProgress(db *sql.DB) (first, last string, err error) {
+	var firstDate, lastDate time.Time
+	err = db.QueryRow("SELECT first_date, last_date FROM btc_metrics_progress WHERE metric = ?", coinDaysMetricName).
+		Scan(&firstDate, &lastDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to query coin_days progress: %w", err)
+	}
+	return firstDate.Format("2006-01-02"), lastDate.Format("2006-01-02"), nil
+}
+
+// ResetCoinDays clears the unspent output ages and progress of the coin_days
+// metric, so it starts again from the next date computed
+func ResetCoinDays(db *sql.DB) error {
+	for _, statement := range []string{
+		"TRUNCATE TABLE btc_utxo_ages",
+		"DELETE FROM btc_metrics_progress WHERE metric = '" + coinDaysMetricName + "'",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("failed to reset coin_days: %w", err)
+		}
+	}
+	return nil
+}
+
+// ComputeCoinDays computes the coin days destroyed and HODL waves of a date.
+// Dates must be computed in order: btc_utxo_ages holds the creation date of
+// every output since the first computed date that is unspent as of the last
+// one. Spends of outputs created before the first date aren't aged and are
+// counted as untracked. The last computed date can be computed again; earlier
+// ones are skipped.
+func ComputeCoinDays(db *sql.DB, date string) error {
+	first, last, err := CoinDaysProgress(db)
+	if err != nil {
+		return err
+	}
+	if last != "" {
+		lastDate, _ := time.Parse("2006-01-02", last)
+		current, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return fmt.Errorf("invalid date %s: %w", date, err)
+		}
+		if current.Before(lastDate) {
+			fmt.Printf("Skipping %s: coin_days is computed through %s\n", date, last)
+			return nil
+		}
+		if next := lastDate.AddDate(0, 0, 1); current.After(next) {
+			return fmt.Errorf("date %s is missing: coin_days is computed through %s (use -reset to start again from a later date)",
+				next.Format("2006-01-02"), last)
+		}
+	} else {
+		first = date
+	}
+
+	// Outputs spent before this date are no longer needed
+	for {
+		result, err := db.Exec("DELETE FROM btc_utxo_ages WHERE spent_date < ? LIMIT ?", date, utxoDeleteBatch)
+		if err != nil {
+			return fmt.Errorf("failed to delete spent outputs: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n < utxoDeleteBatch {
+			break
+		}
+	}
+
+	for _, prefix := range hashPrefixes {
+		pattern := string(prefix) + "%"
+		if err := retryWithBackoffNoReturn(func() error {
+			_, err := db.Exec(`INSERT IGNORE INTO btc_utxo_ages (transaction_hash, output_index, created_date, value)
+SELECT transaction_hash, output_index, record_date, output_amount FROM btc_transaction_outputs
+WHERE record_date = ? AND output_amount > 0 AND transaction_hash LIKE ?`, date, pattern)
+			return err
+		}, "insert outputs of "+date); err != nil {
+			return fmt.Errorf("failed to insert outputs: %w", err)
+		}
+		if err := retryWithBackoffNoReturn(func() error {
+			_, err := db.Exec(`UPDATE btc_utxo_ages u JOIN btc_transaction_inputs i
+  ON u.transaction_hash = i.spent_transaction_hash AND u.output_index = i.spent_output_index
+SET u.spent_date = ?
+WHERE i.record_date = ? AND i.spent_transaction_hash LIKE ?`, date, date, pattern)
+			return err
+		}, "mark spent outputs of "+date); err != nil {
+			return fmt.Errorf("failed to mark spent outputs (needs inputs.spent_transaction_hash and spent_output_index, which the minimal column profile omits): %w", err)
+		}
+	}
+
+	label, minDays := ageBucketSQL("age")
+	if err := retryWithBackoffNoReturn(func() error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec("DELETE FROM btc_hodl_waves WHERE record_date = ?", date); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO btc_hodl_waves (record_date, age_bucket, min_age_days, utxo_count, value, value_share)
+SELECT ?, b.age_bucket, b.min_age_days, b.utxo_count, b.value, b.value / t.total
+FROM (SELECT `+label+` AS age_bucket, `+minDays+` AS min_age_days, COUNT(*) AS utxo_count, SUM(value) AS value
+  FROM (SELECT DATEDIFF(?, created_date) AS age, value FROM btc_utxo_ages WHERE spent_date IS NULL) a
+  GROUP BY age_bucket, min_age_days) b
+CROSS JOIN (SELECT SUM(value) AS total FROM btc_utxo_ages WHERE spent_date IS NULL) t`, date, date); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`REPLACE INTO btc_coin_days (record_date, tracked_since, spent_count, spent_value,
+  coin_days_destroyed, dormancy, avg_spent_age_days, untracked_inputs, utxo_count, unspent_value)
+SELECT ?, ?, s.spent_count, s.spent_value, s.coin_days_destroyed,
+  s.coin_days_destroyed / NULLIF(s.spent_value, 0), s.avg_age, GREATEST(i.inputs - s.spent_count, 0),
+  w.utxo_count, w.unspent_value
+FROM (SELECT COUNT(*) AS spent_count, COALESCE(SUM(value), 0) AS spent_value,
+    COALESCE(SUM(value * DATEDIFF(?, created_date)), 0) AS coin_days_destroyed, AVG(DATEDIFF(?, created_date)) AS avg_age
+  FROM btc_utxo_ages WHERE spent_date = ?) s
+CROSS JOIN (SELECT COUNT(*) AS inputs FROM btc_transaction_inputs
+  WHERE record_date = ? AND spent_transaction_hash IS NOT NULL AND spent_transaction_hash <> '') i
+CROSS JOIN (SELECT COALESCE(SUM(utxo_count), 0) AS utxo_count, COALESCE(SUM(value), 0) AS unspent_value
+  FROM btc_hodl_waves WHERE record_date = ?) w`, date, first, date, date, date, date, date); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO btc_metrics_progress (metric, first_date, last_date) VALUES (?, ?, ?)
+ON DUPLICATE KEY UPDATE last_date = VALUES(last_date)`, coinDaysMetricName, first, date); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}, "compute coin days of "+date); err != nil {
+		return fmt.Errorf("failed to compute coin days: %w", err)
+	}
+	return nil
+}
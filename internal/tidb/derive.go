@@ -0,0 +1,124 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/config"
+)
+
+// DateDeriverFunc derives tables from the local files of a date once all of
+// its datasets are loaded. dir returns the directory of a dataset for the date.
+type DateDeriverFunc func(db *sql.DB, cfg *config.Config, dir func(dataset string) string, date string) error
+
+// DeriveBtcDate runs the enabled btc analyzers that need a whole date, then
+// rolls the date up into btc_daily_stats
+func DeriveBtcDate(db *sql.DB, cfg *config.Config, dir func(dataset string) string, date string) error {
+	analyzers, err := ParseAnalyzers(cfg.Analyzers)
+	if err != nil {
+		return err
+	}
+	if analyzers.Enabled(AnalyzerMiners) {
+		if err := deriveBlockMiners(db, cfg, dir("blocks"), dir("transactions"), date); err != nil {
+			return fmt.Errorf("failed to attribute blocks to mining pools: %w", err)
+		}
+	}
+	if analyzers.Enabled(AnalyzerBlockStats) {
+		if err := deriveBlockStats(db, cfg, dir("blocks"), dir("transactions")); err != nil {
+			return fmt.Errorf("failed to compute block statistics: %w", err)
+		}
+	}
+	if analyzers.Enabled(AnalyzerSupply) {
+		if err := deriveSupply(db, cfg, dir("blocks"), dir("transactions")); err != nil {
+			return fmt.Errorf("failed to track the supply: %w", err)
+		}
+	}
+	if analyzers.Enabled(AnalyzerPrivacy) {
+		if err := derivePrivacyLabels(db, cfg, dir("transactions"), date); err != nil {
+			return fmt.Errorf("failed to label privacy transactions: %w", err)
+		}
+	}
+	if analyzers.Enabled(AnalyzerClusters) {
+		if err := deriveClusters(db, cfg, dir("transactions"), date); err != nil {
+			return fmt.Errorf("failed to cluster addresses: %w", err)
+		}
+	}
+	if err := RollupBtcDate(db, date); err != nil {
+		return fmt.Errorf("failed to roll up daily stats: %w", err)
+	}
+	return nil
+}
+
+// readDirRows reads the rows of every parquet file in dir as T, keeping those
+// keep accepts. Only the columns of T are decoded.
+func readDirRows[T any](cfg *config.Config, dir string, keep func(*T) bool) ([]T, error) {
+	var kept []T
+	err := forEachDirRow(cfg, dir, func(row *T) {
+		if keep == nil || keep(row) {
+			kept = append(kept, *row)
+		}
+	})
+	return kept, err
+}
+
+// forEachDirRow calls fn with each row of every parquet file in dir decoded
+// as T, in file name order
+func forEachDirRow[T any](cfg *config.Config, dir string, fn func(*T)) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if err != nil {
+		return fmt.Errorf("failed to list files in %s: %w", dir, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no parquet files in %s", dir)
+	}
+	sort.Strings(files)
+	for _, filePath := range files {
+		if err := readFileRows(cfg, filePath, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFileRows calls fn with each row of a parquet file decoded as T
+func readFileRows[T any](cfg *config.Config, filePath string, fn func(*T)) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+	parquetFile, err := parquet.OpenFile(file, fileInfo.Size())
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	var zero T
+	reader, err := newRowReader[T](parquetFile, parquet.SchemaOf(zero), cfg.ReadConcurrency, 0)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(filePath), err)
+	}
+	defer reader.Close()
+
+	rows := make([]T, readChunkSize)
+	for {
+		n, err := reader.Read(rows)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read parquet file: %w", err)
+		}
+		for i := range rows[:n] {
+			fn(&rows[i])
+		}
+		if err == io.EOF || n == 0 {
+			return nil
+		}
+	}
+}
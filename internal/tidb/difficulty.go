@@ -0,0 +1,259 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/consensus"
+)
+
+// Hashrate windows in blocks: about a day and about a week
+const (
+	hashrateWindowDay  = 144
+	hashrateWindowWeek = 1008
+)
+
+// difficultyBlock is the part of a btc_blocks row the difficulty metric reads
+type difficultyBlock struct {
+	recordDate time.Time
+	number     int64
+	timestamp  time.Time
+	difficulty float64
+	bits       string
+	chainwork  string
+}
+
+// hashrateRow represents a row to insert into btc_hashrate
+type hashrateRow struct {
+	recordDate          time.Time
+	blockNumber         int64
+	blockTimestamp      time.Time
+	difficulty          float64
+	hashrateDay         interface{} // NULL until a whole window is loaded
+	hashrateWeek        interface{}
+	chainwork           string
+	chainworkIncreasing interface{} // NULL when the previous block isn't loaded
+}
+
+func extractHashrateArgs(row hashrateRow) []interface{} {
+	return []interface{}{
+		row.recordDate, row.blockNumber, row.blockTimestamp, row.difficulty, row.hashrateDay, row.hashrateWeek,
+		row.chainwork, row.chainworkIncreasing,
+	}
+}
+
+// epochRow represents a row to insert into btc_difficulty_epochs
+type epochRow struct {
+	epoch                 int64
+	startHeight           int64
+	endHeight             int64
+	blockCount            int64
+	startTime             time.Time
+	endTime               time.Time
+	difficulty            float64
+	bits                  string
+	nextDifficulty        interface{} // NULL until the next epoch's first block is loaded
+	adjustmentPct         interface{}
+	expectedAdjustmentPct interface{} // NULL until the epoch is complete
+}
+
+func (e epochRow) complete() bool {
+	return e.blockCount == consensus.RetargetInterval && e.startHeight == e.epoch*consensus.RetargetInterval
+}
+
+func extractEpochArgs(e epochRow) []interface{} {
+	actual := int64(e.endTime.Sub(e.startTime) / time.Second)
+	var interval interface{}
+	if e.blockCount > 1 {
+		interval = float64(actual) / float64(e.blockCount-1)
+	}
+	return []interface{}{
+		e.epoch, e.startHeight, e.endHeight, e.blockCount, e.complete(), e.startTime, e.endTime, e.difficulty,
+		e.bits, actual, consensus.TargetTimespan, interval, e.expectedAdjustmentPct, e.nextDifficulty, e.adjustmentPct,
+	}
+}
+
+// DifficultyReport summarizes a run of ComputeDifficultyMetrics
+type DifficultyReport struct {
+	Blocks            int     // Blocks written to btc_hashrate
+	Epochs            int     // Epochs written to btc_difficulty_epochs
+	ChainworkDecrease []int64 // Blocks whose chainwork isn't above the previous block's
+}
+
+// ComputeDifficultyMetrics recomputes the hashrate rows of the blocks loaded
+// for dates start through end, and the difficulty epochs they fall in. Blocks
+// of earlier and later dates are read as needed to complete the windows and
+// epochs. The actual timespan of an epoch runs from its first block to its
+// last, as in the retarget rule.
+func ComputeDifficultyMetrics(db *sql.DB, cfg *config.Config, start, end string) (*DifficultyReport, error) {
+	var first, last sql.NullInt64
+	if err := db.QueryRow("SELECT MIN(number), MAX(number) FROM btc_blocks WHERE record_date BETWEEN ? AND ?",
+		start, end).Scan(&first, &last); err != nil {
+		return nil, fmt.Errorf("failed to query block range: %w", err)
+	}
+	report := &DifficultyReport{}
+	if !first.Valid {
+		return report, nil
+	}
+	firstEpoch := first.Int64 / consensus.RetargetInterval
+	lastEpoch := last.Int64 / consensus.RetargetInterval
+	from := max(min(firstEpoch*consensus.RetargetInterval, first.Int64-hashrateWindowWeek), 0)
+	to := (lastEpoch + 1) * consensus.RetargetInterval
+	blocks, err := loadDifficultyBlocks(db, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hashrate over a window of n blocks ending at blocks[i], from the work of the
+	// window's blocks and the time since the block before it
+	work := make([]float64, len(blocks)+1)
+	for i, block := range blocks {
+		work[i+1] = work[i] + block.difficulty*consensus.HashesPerDifficulty
+	}
+	runStart := 0 // Index of the first block of the current gapless run
+	hashrate := func(i, n int) interface{} {
+		j := i - n
+		if j < runStart {
+			return nil
+		}
+		seconds := blocks[i].timestamp.Sub(blocks[j].timestamp).Seconds()
+		if seconds <= 0 {
+			return nil
+		}
+		return (work[i+1] - work[j+1]) / seconds
+	}
+
+	var rows []hashrateRow
+	var previousWork *big.Int
+	for i, block := range blocks {
+		if i > 0 && block.number != blocks[i-1].number+1 {
+			runStart = i
+			previousWork = nil
+		}
+		chainwork, ok := new(big.Int).SetString(strings.TrimPrefix(block.chainwork, "0x"), 16)
+		if !ok {
+			chainwork = nil
+		}
+		var increasing interface{}
+		if chainwork != nil && previousWork != nil {
+			increased := chainwork.Cmp(previousWork) > 0
+			if !increased {
+				report.ChainworkDecrease = append(report.ChainworkDecrease, block.number)
+			}
+			increasing = increased
+		}
+		previousWork = chainwork
+		if block.number < first.Int64 || block.number > last.Int64 {
+			continue
+		}
+		rows = append(rows, hashrateRow{
+			recordDate:          block.recordDate,
+			blockNumber:         block.number,
+			blockTimestamp:      block.timestamp,
+			difficulty:          block.difficulty,
+			hashrateDay:         hashrate(i, hashrateWindowDay),
+			hashrateWeek:        hashrate(i, hashrateWindowWeek),
+			chainwork:           block.chainwork,
+			chainworkIncreasing: increasing,
+		})
+	}
+
+	epochs := make(map[int64]*epochRow)
+	firstOfEpoch := make(map[int64]difficultyBlock)
+	for _, block := range blocks {
+		epoch := block.number / consensus.RetargetInterval
+		if block.number == epoch*consensus.RetargetInterval {
+			firstOfEpoch[epoch] = block
+		}
+		if epoch < firstEpoch || epoch > lastEpoch {
+			continue
+		}
+		e, ok := epochs[epoch]
+		if !ok {
+			e = &epochRow{epoch: epoch, startHeight: block.number, startTime: block.timestamp,
+				difficulty: block.difficulty, bits: block.bits}
+			epochs[epoch] = e
+		}
+		e.endHeight = block.number
+		e.endTime = block.timestamp
+		e.blockCount++
+	}
+	var epochRows []epochRow
+	for epoch := firstEpoch; epoch <= lastEpoch; epoch++ {
+		e, ok := epochs[epoch]
+		if !ok {
+			continue
+		}
+		if e.complete() {
+			actual := int64(e.endTime.Sub(e.startTime) / time.Second)
+			e.expectedAdjustmentPct = (consensus.RetargetFactor(actual) - 1) * 100
+		}
+		if next, ok := firstOfEpoch[epoch+1]; ok && e.difficulty > 0 {
+			e.nextDifficulty = next.difficulty
+			e.adjustmentPct = (next.difficulty/e.difficulty - 1) * 100
+		}
+		epochRows = append(epochRows, *e)
+	}
+
+	if err := insertAll(db, cfg, BtcHashrateTable, rows, extractHashrateArgs); err != nil {
+		return nil, err
+	}
+	if err := insertAll(db, cfg, BtcDifficultyEpochsTable, epochRows, extractEpochArgs); err != nil {
+		return nil, err
+	}
+	for _, number := range report.ChainworkDecrease {
+		fmt.Fprintf(os.Stderr, "Warning: chainwork of block %d is not above block %d\n", number, number-1)
+	}
+	report.Blocks = len(rows)
+	report.Epochs = len(epochRows)
+	return report, nil
+}
+
+// loadDifficultyBlocks reads blocks from through to-1 in height order
+func loadDifficultyBlocks(db *sql.DB, from, to int64) ([]difficultyBlock, error) {
+	rows, err := db.Query(`SELECT record_date, number, block_timestamp, COALESCE(difficulty, 0), COALESCE(bits, ''),
+  COALESCE(chainwork, '') FROM btc_blocks WHERE number >= ? AND number < ? ORDER BY number`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	defer rows.Close()
+	var blocks []difficultyBlock
+	for rows.Next() {
+		var block difficultyBlock
+		var timestamp sql.NullTime
+		if err := rows.Scan(&block.recordDate, &block.number, &timestamp, &block.difficulty, &block.bits,
+			&block.chainwork); err != nil {
+			return nil, fmt.Errorf("failed to scan block: %w", err)
+		}
+		block.timestamp = timestamp.Time
+		// A block loaded under two dates is read once
+		if len(blocks) > 0 && blocks[len(blocks)-1].number == block.number {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocks: %w", err)
+	}
+	return blocks, nil
+}
+
+// insertAll inserts rows into table in batches of the block batch size
+func insertAll[T any](db *sql.DB, cfg *config.Config, table Table, rows []T, extractArgs extractArgsFunc[T]) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	inserter, err := newBatchInserter(db, table, cfg.BlockBatchSize, extractArgs)
+	if err != nil {
+		return err
+	}
+	defer inserter.Close()
+	inserter.add(rows...)
+	_, err = inserter.flush()
+	return err
+}
@@ -0,0 +1,187 @@
+package tidb
+
+import (
+	"time"
+
+	"github.com/siddon/web3insights/internal/chain"
+)
+
+// ETH row mappings; each ETH dataset maps one parquet row to one table row
+var (
+	EthBlocks = RowMapping[chain.EthBlock]{
+		Table: EthBlocksTable, Dataset: "blocks", Label: "blocks", Args: extractEthBlockArgs, BatchSize: blockBatchSize,
+	}
+	EthTransactions = RowMapping[chain.EthTransaction]{
+		Table: EthTransactionsTable, Dataset: "transactions", Label: "transactions", Args: extractEthTransactionArgs, BatchSize: transactionBatchSize,
+	}
+	EthLogs = RowMapping[chain.EthLog]{
+		Table: EthLogsTable, Dataset: "logs", Label: "logs", Args: extractEthLogArgs, BatchSize: transactionBatchSize,
+	}
+	EthTokenTransfers = RowMapping[chain.EthTokenTransfer]{
+		Table: EthTokenTransfersTable, Dataset: "token_transfers", Label: "token transfers", Args: extractEthTokenTransferArgs, BatchSize: transactionBatchSize,
+	}
+	EthReceipts = RowMapping[chain.EthReceipt]{
+		Table: EthReceiptsTable, Dataset: "receipts", Label: "receipts", Args: extractEthReceiptArgs, BatchSize: transactionBatchSize,
+	}
+	EthTraces = RowMapping[chain.EthTrace]{
+		Table: EthTracesTable, Dataset: "traces", Label: "traces", Args: extractEthTraceArgs, BatchSize: transactionBatchSize,
+	}
+)
+
+// recordDate parses the dataset date partition value, returning zero time on failure
+// (which will be handled by the database)
+func recordDate(date string) time.Time {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// nullableTimestamp converts an Int96Timestamp to a SQL argument (zero means NULL)
+func nullableTimestamp(ts chain.Int96Timestamp) interface{} {
+	t := ts.Time()
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// extractEthBlockArgs extracts SQL arguments from an EthBlock
+func extractEthBlockArgs(block chain.EthBlock) []interface{} {
+	return []interface{}{
+		recordDate(block.Date),
+		block.Hash,
+		block.Number,
+		nullableTimestamp(block.Timestamp),
+		block.ParentHash,
+		block.Nonce,
+		block.Sha3Uncles,
+		block.LogsBloom,
+		block.TransactionsRoot,
+		block.StateRoot,
+		block.ReceiptsRoot,
+		block.Miner,
+		block.Difficulty,
+		block.TotalDifficulty,
+		block.Size,
+		block.ExtraData,
+		block.GasLimit,
+		block.GasUsed,
+		block.TransactionCount,
+		block.BaseFeePerGas,
+		block.WithdrawalsRoot,
+		len(block.Withdrawals),
+	}
+}
+
+// extractEthTransactionArgs extracts SQL arguments from an EthTransaction
+func extractEthTransactionArgs(tx chain.EthTransaction) []interface{} {
+	return []interface{}{
+		recordDate(tx.Date),
+		tx.Hash,
+		tx.Nonce,
+		tx.TransactionIndex,
+		tx.FromAddress,
+		tx.ToAddress,
+		tx.Value,
+		tx.Gas,
+		tx.GasPrice,
+		tx.Input,
+		tx.ReceiptCumulativeGasUsed,
+		tx.ReceiptGasUsed,
+		tx.ReceiptContractAddress,
+		tx.ReceiptStatus,
+		nullableTimestamp(tx.BlockTimestamp),
+		tx.BlockNumber,
+		tx.BlockHash,
+		tx.MaxFeePerGas,
+		tx.MaxPriorityFeePerGas,
+		tx.TransactionType,
+		tx.ReceiptEffectiveGasPrice,
+	}
+}
+
+// extractEthLogArgs extracts SQL arguments from an EthLog.
+// Topics are spread over topic0..topic3 (a log has at most four topics).
+func extractEthLogArgs(log chain.EthLog) []interface{} {
+	var topics [4]interface{}
+	for i := 0; i < len(topics) && i < len(log.Topics); i++ {
+		topics[i] = log.Topics[i]
+	}
+	return []interface{}{
+		recordDate(log.Date),
+		log.BlockNumber,
+		log.LogIndex,
+		log.TransactionHash,
+		log.TransactionIndex,
+		log.Address,
+		log.Data,
+		topics[0],
+		topics[1],
+		topics[2],
+		topics[3],
+		nullableTimestamp(log.BlockTimestamp),
+		log.BlockHash,
+	}
+}
+
+// extractEthTokenTransferArgs extracts SQL arguments from an EthTokenTransfer
+func extractEthTokenTransferArgs(transfer chain.EthTokenTransfer) []interface{} {
+	return []interface{}{
+		recordDate(transfer.Date),
+		transfer.TransactionHash,
+		transfer.LogIndex,
+		transfer.TokenAddress,
+		transfer.FromAddress,
+		transfer.ToAddress,
+		transfer.Value,
+		nullableTimestamp(transfer.BlockTimestamp),
+		transfer.BlockNumber,
+		transfer.BlockHash,
+	}
+}
+
+// extractEthReceiptArgs extracts SQL arguments from an EthReceipt
+func extractEthReceiptArgs(receipt chain.EthReceipt) []interface{} {
+	return []interface{}{
+		recordDate(receipt.Date),
+		receipt.TransactionHash,
+		receipt.TransactionIndex,
+		receipt.BlockHash,
+		receipt.BlockNumber,
+		receipt.CumulativeGasUsed,
+		receipt.GasUsed,
+		receipt.ContractAddress,
+		receipt.Root,
+		receipt.Status,
+		receipt.EffectiveGasPrice,
+	}
+}
+
+// extractEthTraceArgs extracts SQL arguments from an EthTrace
+func extractEthTraceArgs(trace chain.EthTrace) []interface{} {
+	return []interface{}{
+		recordDate(trace.Date),
+		trace.TraceID,
+		trace.TransactionHash,
+		trace.TransactionIndex,
+		trace.FromAddress,
+		trace.ToAddress,
+		trace.Value,
+		trace.Input,
+		trace.Output,
+		trace.TraceType,
+		trace.CallType,
+		trace.RewardType,
+		trace.Gas,
+		trace.GasUsed,
+		trace.Subtraces,
+		trace.TraceAddress,
+		trace.Error,
+		trace.Status,
+		nullableTimestamp(trace.BlockTimestamp),
+		trace.BlockNumber,
+		trace.BlockHash,
+	}
+}
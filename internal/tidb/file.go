@@ -0,0 +1,121 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/config"
+)
+
+// insertRowsFromFile reads a parquet file of flat rows of type T and inserts them
+// into table, keeping the columns of its profile in cfg.ColumnProfiles.
+// Full batches go through a prepared statement; the remainder is inserted directly.
+// dataset selects the columns skipped by cfg.SkipColumns; label is only used
+// for progress messages (e.g. "blocks", "token transfers").
+func insertRowsFromFile[T any](db *sql.DB, cfg *config.Config, filePath, dataset, label string, table Table, batchSize int, extractArgs extractArgsFunc[T], onProgress ProgressCallback, startRow int64) error {
+	profiles, err := ParseProfiles(cfg.ColumnProfiles)
+	if err != nil {
+		return err
+	}
+	skip := profiles.SkippedSources(table)
+	table, extractArgs = projectTable(profiles, table, extractArgs)
+	baseSQL, placeholderCount := table.insertSQL(), len(table.Columns)
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	parquetFile, err := parquet.OpenFile(file, fileInfo.Size())
+	if err != nil {
+		return fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	// Get total number of rows in the file
+	numRows := parquetFile.NumRows()
+
+	// Start reading at startRow if resuming
+	reader, err := openRowReader[T](cfg, parquetFile, filePath, dataset, skip, startRow)
+	if err != nil {
+		return fmt.Errorf("failed to seek to row %d: %w", startRow, err)
+	}
+	defer reader.Close()
+	if startRow > 0 {
+		fmt.Printf("Resuming from row %d/%d in %s\n", startRow, numRows, filepath.Base(filePath))
+	}
+
+	// Prepare statement once for reuse
+	valuesSQL := buildValuesSQL(batchSize, placeholderCount)
+	batchSQL := baseSQL + valuesSQL
+
+	stmt, err := retryWithBackoff(func() (*sql.Stmt, error) {
+		return db.Prepare(batchSQL)
+	}, "prepare "+label+" statement")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	pending := make([]T, 0, batchSize)
+
+	var totalRows int64 = startRow
+
+	for {
+		n, err := reader.Read(pending[:batchSize])
+
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read parquet file: %w", err)
+		}
+
+		pending = pending[:n]
+
+		if n < batchSize || err == io.EOF {
+			break
+		}
+
+		batch := pending[:batchSize]
+
+		if err := batchInsertWithStmt(stmt, batch, extractArgs); err != nil {
+			return fmt.Errorf("failed to insert %s batch: %w", label, err)
+		}
+
+		totalRows += int64(len(batch))
+
+		fmt.Printf("Inserted %d %s from %s (total: %d/%d)\n", len(batch), label, filepath.Base(filePath), totalRows, numRows)
+
+		// Call progress callback after each batch
+		if onProgress != nil {
+			if err := onProgress(filePath, totalRows, numRows); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: progress callback failed: %v\n", err)
+			}
+		}
+	}
+
+	// Process remaining rows with direct SQL
+	if len(pending) > 0 {
+		if err := directInsert(db, baseSQL, pending, extractArgs, placeholderCount); err != nil {
+			return fmt.Errorf("failed to insert remaining %s: %w", label, err)
+		}
+		totalRows += int64(len(pending))
+		fmt.Printf("Inserted %d remaining %s from %s (total: %d/%d)\n", len(pending), label, filepath.Base(filePath), totalRows, numRows)
+
+		// Call progress callback after remaining rows (always save at end)
+		if onProgress != nil {
+			if err := onProgress(filePath, totalRows, numRows); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: progress callback failed: %v\n", err)
+			}
+		}
+	}
+
+	return nil
+}
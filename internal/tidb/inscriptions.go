@@ -0,0 +1,147 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/ordinals"
+)
+
+// witnessColumn is the transaction column holding the witness stack of each
+// input as hex strings. The AWS dataset doesn't publish it; files from sources
+// that do can be dropped into the transactions directory.
+const witnessColumn = "inputs.witness"
+
+// witnessTransaction is the part of a transaction row the inscriptions
+// analyzer decodes
+type witnessTransaction struct {
+	Date        string         `parquet:"date"`
+	Hash        string         `parquet:"hash"`
+	BlockNumber int64          `parquet:"block_number"`
+	Inputs      []witnessInput `parquet:"inputs,list,optional"`
+}
+
+type witnessInput struct {
+	Witness []string `parquet:"witness,list,optional"`
+}
+
+// inscriptionRow represents a row to insert into btc_inscriptions
+type inscriptionRow struct {
+	recordDate      time.Time
+	inscriptionID   string
+	transactionHash string
+	inputIndex      int64
+	envelopeIndex   int64
+	blockNumber     int64
+	contentType     string
+	contentEncoding string
+	metaprotocol    string
+	contentSize     int
+}
+
+// extractInscriptionArgs extracts SQL arguments from an inscriptionRow
+func extractInscriptionArgs(inscription inscriptionRow) []interface{} {
+	return []interface{}{
+		inscription.recordDate,
+		inscription.inscriptionID,
+		inscription.transactionHash,
+		inscription.inputIndex,
+		inscription.envelopeIndex,
+		inscription.blockNumber,
+		inscription.contentType,
+		inscription.contentEncoding,
+		inscription.metaprotocol,
+		inscription.contentSize,
+	}
+}
+
+// insertInscriptionsFromFile scans the input witnesses of a transaction file
+// for inscription envelopes and inserts them into btc_inscriptions. Files
+// without witness data are reported and skipped, since an empty table would
+// otherwise read as "no inscriptions". The whole file is scanned on every call;
+// inserts are idempotent.
+func insertInscriptionsFromFile(db *sql.DB, cfg *config.Config, parquetFile *parquet.File, filePath string) error {
+	if !hasColumn(parquetFile.Schema(), witnessColumn) {
+		fmt.Fprintf(os.Stderr, "Warning: inscriptions analyzer skipped %s: it has no %s column, so inscription envelopes can't be found (the AWS dataset doesn't publish witness data)\n",
+			filepath.Base(filePath), witnessColumn)
+		return nil
+	}
+
+	reader, err := newRowReader[witnessTransaction](parquetFile, parquet.SchemaOf(witnessTransaction{}), cfg.ReadConcurrency, 0)
+	if err != nil {
+		return fmt.Errorf("failed to read witnesses: %w", err)
+	}
+	defer reader.Close()
+
+	inscriptions, err := newBatchInserter(db, BtcInscriptionsTable, cfg.InputBatchSize, extractInscriptionArgs)
+	if err != nil {
+		return err
+	}
+	defer inscriptions.Close()
+
+	total := 0
+	transactions := make([]witnessTransaction, cfg.TransactionBatchSize)
+	for {
+		n, err := reader.Read(transactions)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read parquet file: %w", err)
+		}
+		inscriptions.pending = collectInscriptions(transactions[:n], inscriptions.pending)
+		inserted, flushErr := inscriptions.flushBatches()
+		if flushErr != nil {
+			return flushErr
+		}
+		total += inserted
+		if err == io.EOF || n == 0 {
+			break
+		}
+	}
+	inserted, err := inscriptions.flush()
+	if err != nil {
+		return err
+	}
+	total += inserted
+	fmt.Printf("Inserted %d inscriptions from %s\n", total, filepath.Base(filePath))
+	return nil
+}
+
+// collectInscriptions collects the inscriptions revealed by transactions.
+// Inscription ids number the envelopes of a transaction across its inputs,
+// as ord does: <txid>i<n>.
+func collectInscriptions(transactions []witnessTransaction, allInscriptions []inscriptionRow) []inscriptionRow {
+	for _, tx := range transactions {
+		date, err := time.Parse("2006-01-02", tx.Date)
+		if err != nil {
+			date = time.Time{}
+		}
+		envelopes := 0
+		for i, input := range tx.Inputs {
+			tapscript, ok, err := ordinals.TapscriptHex(input.Witness)
+			if err != nil || !ok {
+				continue
+			}
+			for j, inscription := range ordinals.ParseEnvelopes(tapscript) {
+				allInscriptions = append(allInscriptions, inscriptionRow{
+					recordDate:      date,
+					inscriptionID:   fmt.Sprintf("%si%d", tx.Hash, envelopes),
+					transactionHash: tx.Hash,
+					inputIndex:      int64(i),
+					envelopeIndex:   int64(j),
+					blockNumber:     tx.BlockNumber,
+					contentType:     inscription.ContentType,
+					contentEncoding: inscription.ContentEncoding,
+					metaprotocol:    inscription.Metaprotocol,
+					contentSize:     len(inscription.Body),
+				})
+				envelopes++
+			}
+		}
+	}
+	return allInscriptions
+}
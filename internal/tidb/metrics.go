@@ -0,0 +1,45 @@
+package tidb
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Metrics are computed by the metrics command from the loaded tables, since
+// they span more than one date. Like analyzers, their tables are part of the
+// schema DDL.
+const (
+	MetricDifficulty = "difficulty" // Difficulty epochs into btc_difficulty_epochs, hashrate and chainwork checks into btc_hashrate
+	MetricCoinDays   = "coin_days"  // Coin days destroyed and dormancy into btc_coin_days, unspent value by age into btc_hodl_waves
+)
+
+var metricNames = []string{MetricDifficulty, MetricCoinDays}
+
+// Metrics is the set of metrics to compute
+type Metrics map[string]bool
+
+// ParseMetrics parses a comma-separated list of metric names, or "all"
+func ParseMetrics(v string) (Metrics, error) {
+	metrics := make(Metrics)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+		case name == "all":
+			for _, name := range metricNames {
+				metrics[name] = true
+			}
+		case slices.Contains(metricNames, name):
+			metrics[name] = true
+		default:
+			return nil, fmt.Errorf("unknown metric: %s (expected %s or all)", name, strings.Join(metricNames, ", "))
+		}
+	}
+	return metrics, nil
+}
+
+// Enabled reports whether the named metric is computed
+func (m Metrics) Enabled(name string) bool {
+	return m[name]
+}
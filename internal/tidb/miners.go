@@ -0,0 +1,158 @@
+package tidb
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/siddon/web3insights/internal/address"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/pools"
+)
+
+// minerBlock is the part of a block row the miners analyzer reads
+type minerBlock struct {
+	Date          string  `parquet:"date"`
+	Hash          string  `parquet:"hash"`
+	Number        int64   `parquet:"number"`
+	CoinbaseParam string  `parquet:"coinbase_param,optional"`
+	Difficulty    float64 `parquet:"difficulty,optional"`
+}
+
+// minerTransaction is the part of a transaction row the miners analyzer reads
+type minerTransaction struct {
+	BlockNumber int64         `parquet:"block_number"`
+	IsCoinbase  bool          `parquet:"is_coinbase,optional"`
+	Outputs     []tokenOutput `parquet:"outputs,list,optional"`
+}
+
+// blockMinerRow represents a row to insert into btc_block_miners
+type blockMinerRow struct {
+	recordDate    time.Time
+	blockNumber   int64
+	blockHash     string
+	match         pools.Match
+	coinbaseText  string
+	payoutAddress string
+	coinbaseValue float64
+	difficulty    float64
+}
+
+func extractBlockMinerArgs(row blockMinerRow) []interface{} {
+	return []interface{}{
+		row.recordDate, row.blockNumber, row.blockHash, row.match.Pool.Name, row.match.Pool.Link,
+		row.match.Method, row.match.Matched, row.coinbaseText, row.payoutAddress, row.coinbaseValue, row.difficulty,
+	}
+}
+
+// deriveBlockMiners attributes the blocks of a date to mining pools into
+// btc_block_miners, then recomputes the date's btc_pool_daily_shares
+func deriveBlockMiners(db *sql.DB, cfg *config.Config, blocksDir, transactionsDir, date string) error {
+	database, err := pools.Load(cfg.PoolsFile)
+	if err != nil {
+		return err
+	}
+	blocks, err := readDirRows[minerBlock](cfg, blocksDir, nil)
+	if err != nil {
+		return err
+	}
+	coinbases, err := readDirRows(cfg, transactionsDir, func(tx *minerTransaction) bool { return tx.IsCoinbase })
+	if err != nil {
+		return err
+	}
+	outputs := make(map[int64][]tokenOutput, len(coinbases))
+	for _, tx := range coinbases {
+		outputs[tx.BlockNumber] = tx.Outputs
+	}
+
+	rows := make([]blockMinerRow, 0, len(blocks))
+	for _, block := range blocks {
+		recordDate, err := time.Parse("2006-01-02", block.Date)
+		if err != nil {
+			return fmt.Errorf("invalid date %q in block %d: %w", block.Date, block.Number, err)
+		}
+		// coinbase_param is hex, but keep the raw text if a file has it decoded
+		coinbase, err := hex.DecodeString(block.CoinbaseParam)
+		if err != nil {
+			coinbase = []byte(block.CoinbaseParam)
+		}
+		var payouts []string
+		var value float64
+		for _, output := range outputs[block.Number] {
+			value += output.Value
+			if output.Value <= 0 {
+				continue
+			}
+			addr := output.Address
+			if addr == "" {
+				if addresses, err := address.FromScriptHex(output.ScriptHex, address.Mainnet); err == nil && len(addresses) == 1 {
+					addr = addresses[0]
+				}
+			}
+			if addr != "" {
+				payouts = append(payouts, addr)
+			}
+		}
+		row := blockMinerRow{
+			recordDate:    recordDate,
+			blockNumber:   block.Number,
+			blockHash:     block.Hash,
+			match:         database.Identify(coinbase, payouts),
+			coinbaseText:  pools.Text(coinbase),
+			coinbaseValue: value,
+			difficulty:    block.Difficulty,
+		}
+		if len(payouts) > 0 {
+			row.payoutAddress = payouts[0]
+		}
+		rows = append(rows, row)
+	}
+
+	inserter, err := newBatchInserter(db, BtcBlockMinersTable, cfg.BlockBatchSize, extractBlockMinerArgs)
+	if err != nil {
+		return err
+	}
+	defer inserter.Close()
+	inserter.add(rows...)
+	if _, err := inserter.flush(); err != nil {
+		return err
+	}
+	if err := updatePoolDailyShares(db, date); err != nil {
+		return err
+	}
+
+	unknown := 0
+	for _, row := range rows {
+		if row.match.Pool.Name == pools.Unknown {
+			unknown++
+		}
+	}
+	fmt.Printf("Attributed %d blocks to mining pools (%d unknown)\n", len(rows), unknown)
+	return nil
+}
+
+// updatePoolDailyShares recomputes the pool shares of a date from
+// btc_block_miners. The estimated hashrate is each pool's share of the work
+// its blocks represent (difficulty * 2^32 hashes each) spread over the day.
+func updatePoolDailyShares(db *sql.DB, date string) error {
+	return retryWithBackoffNoReturn(func() error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec("DELETE FROM btc_pool_daily_shares WHERE record_date = ?", date); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO btc_pool_daily_shares (record_date, pool_name, block_count, block_share, estimated_hashrate)
+SELECT m.record_date, m.pool_name, COUNT(*), COUNT(*) / t.total, SUM(m.difficulty) * 4294967296 / 86400
+FROM btc_block_miners m
+CROSS JOIN (SELECT COUNT(*) AS total FROM btc_block_miners WHERE record_date = ?) t
+WHERE m.record_date = ?
+GROUP BY m.record_date, m.pool_name, t.total`, date, date); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}, "update pool daily shares")
+}
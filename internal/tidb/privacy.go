@@ -0,0 +1,99 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/privacy"
+)
+
+// privacyTransactionRow is the part of a transaction row the privacy analyzer reads
+type privacyTransactionRow struct {
+	Hash        string        `parquet:"hash"`
+	BlockNumber int64         `parquet:"block_number"`
+	IsCoinbase  bool          `parquet:"is_coinbase,optional"`
+	Inputs      []privacyCoin `parquet:"inputs,list,optional"`
+	Outputs     []privacyCoin `parquet:"outputs,list,optional"`
+}
+
+type privacyCoin struct {
+	Address string  `parquet:"address,optional"`
+	Value   float64 `parquet:"value,optional"`
+}
+
+// privacyTransaction converts the inputs and outputs of a transaction row for
+// the privacy classifier
+func privacyTransaction(isCoinbase bool, inputs, outputs []privacyCoin) privacy.Transaction {
+	tx := privacy.Transaction{Coinbase: isCoinbase}
+	for _, input := range inputs {
+		tx.Inputs = append(tx.Inputs, privacy.Coin{Address: input.Address, Value: satoshis(input.Value)})
+	}
+	for _, output := range outputs {
+		tx.Outputs = append(tx.Outputs, privacy.Coin{Address: output.Address, Value: satoshis(output.Value)})
+	}
+	return tx
+}
+
+// privacyLabelRow represents a row to insert into btc_privacy_labels
+type privacyLabelRow struct {
+	recordDate      time.Time
+	blockNumber     int64
+	transactionHash string
+	label           privacy.Label
+	inputCount      int
+	outputCount     int
+}
+
+func extractPrivacyLabelArgs(row privacyLabelRow) []interface{} {
+	return []interface{}{
+		row.recordDate, row.blockNumber, row.transactionHash, row.label.Name, row.label.Confidence,
+		row.inputCount, row.outputCount, row.label.EqualOutputs, btcValue(row.label.Denomination),
+	}
+}
+
+// derivePrivacyLabels labels the CoinJoin and payjoin transactions of a date
+// into btc_privacy_labels. The date's labels are replaced, so syncing it again
+// drops transactions a changed classifier no longer flags.
+func derivePrivacyLabels(db *sql.DB, cfg *config.Config, transactionsDir, date string) error {
+	recordDate, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return fmt.Errorf("invalid date %s: %w", date, err)
+	}
+	var rows []privacyLabelRow
+	if err := forEachDirRow(cfg, transactionsDir, func(row *privacyTransactionRow) {
+		label := privacy.Classify(privacyTransaction(row.IsCoinbase, row.Inputs, row.Outputs))
+		if label == nil {
+			return
+		}
+		rows = append(rows, privacyLabelRow{
+			recordDate:      recordDate,
+			blockNumber:     row.BlockNumber,
+			transactionHash: row.Hash,
+			label:           *label,
+			inputCount:      len(row.Inputs),
+			outputCount:     len(row.Outputs),
+		})
+	}); err != nil {
+		return err
+	}
+
+	if err := retryWithBackoffNoReturn(func() error {
+		_, err := db.Exec("DELETE FROM btc_privacy_labels WHERE record_date = ?", date)
+		return err
+	}, "delete privacy labels of "+date); err != nil {
+		return fmt.Errorf("failed to delete privacy labels: %w", err)
+	}
+	inserter, err := newBatchInserter(db, BtcPrivacyLabelsTable, cfg.TransactionBatchSize, extractPrivacyLabelArgs)
+	if err != nil {
+		return err
+	}
+	defer inserter.Close()
+	inserter.add(rows...)
+	if _, err := inserter.flush(); err != nil {
+		return fmt.Errorf("failed to insert privacy labels: %w", err)
+	}
+	fmt.Printf("Labeled %d privacy transactions\n", len(rows))
+	return nil
+}
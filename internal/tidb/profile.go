@@ -0,0 +1,136 @@
+package tidb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Column profiles trade stored detail for database size. Tables list the
+// columns they leave out under each profile other than full.
+const (
+	ProfileFull    = "full"    // Every column
+	ProfileLean    = "lean"    // Without columns derivable from others, e.g. script_asm from script_hex
+	ProfileMinimal = "minimal" // Keys, addresses and values only
+)
+
+// Profiles selects the column profile of each table
+type Profiles struct {
+	Default string            // Profile of tables not listed in Tables
+	Tables  map[string]string // Profile by table name
+}
+
+// ParseProfiles parses a comma-separated list of profiles where a bare name sets
+// the default and "<table>=<profile>" overrides one table, e.g.
+// "lean,btc_transaction_outputs=minimal". An empty list means full everywhere.
+func ParseProfiles(v string) (Profiles, error) {
+	profiles := Profiles{Default: ProfileFull, Tables: make(map[string]string)}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		table, profile, ok := strings.Cut(entry, "=")
+		if !ok {
+			table, profile = "", table
+		}
+		table, profile = strings.TrimSpace(table), strings.ToLower(strings.TrimSpace(profile))
+		switch profile {
+		case ProfileFull, ProfileLean, ProfileMinimal:
+		default:
+			return Profiles{}, fmt.Errorf("unknown column profile: %s (expected full, lean or minimal)", profile)
+		}
+		if table == "" {
+			profiles.Default = profile
+		} else {
+			profiles.Tables[table] = profile
+		}
+	}
+	return profiles, nil
+}
+
+// Profile returns the profile of a table
+func (p Profiles) Profile(table string) string {
+	if profile, ok := p.Tables[table]; ok {
+		return profile
+	}
+	if p.Default == "" {
+		return ProfileFull
+	}
+	return p.Default
+}
+
+// Omitted returns the columns each table leaves out under its profile, by table
+// name. Tables that keep every column are not listed.
+func (p Profiles) Omitted(tables ...Table) map[string][]string {
+	omitted := make(map[string][]string)
+	for _, table := range tables {
+		if columns := table.Omit[p.Profile(table.Name)]; len(columns) > 0 {
+			omitted[table.Name] = columns
+		}
+	}
+	return omitted
+}
+
+// SkippedSources returns the dataset columns that no table needs under the
+// profiles, so loaders don't have to decode them
+func (p Profiles) SkippedSources(tables ...Table) []string {
+	needed := make(map[string]bool)
+	omitted := make(map[string]bool)
+	for _, table := range tables {
+		drop := make(map[string]bool)
+		for _, column := range table.Omit[p.Profile(table.Name)] {
+			drop[column] = true
+		}
+		for column, source := range table.Sources {
+			if drop[column] {
+				omitted[source] = true
+			} else {
+				needed[source] = true
+			}
+		}
+	}
+	var skipped []string
+	for source := range omitted {
+		if !needed[source] {
+			skipped = append(skipped, source)
+		}
+	}
+	sort.Strings(skipped)
+	return skipped
+}
+
+// Project returns the table with the columns omitted under profile removed, and
+// the positions of the kept columns in t.Columns
+func (t Table) Project(profile string) (Table, []int) {
+	drop := make(map[string]bool)
+	for _, column := range t.Omit[profile] {
+		drop[column] = true
+	}
+	projected := Table{Name: t.Name}
+	var keep []int
+	for i, column := range t.Columns {
+		if !drop[column] {
+			projected.Columns = append(projected.Columns, column)
+			keep = append(keep, i)
+		}
+	}
+	return projected, keep
+}
+
+// projectTable applies the profile of table to its columns and to the
+// arguments extracted for each row
+func projectTable[T any](profiles Profiles, table Table, extractArgs extractArgsFunc[T]) (Table, extractArgsFunc[T]) {
+	projected, keep := table.Project(profiles.Profile(table.Name))
+	if len(keep) == len(table.Columns) {
+		return table, extractArgs
+	}
+	return projected, func(item T) []interface{} {
+		all := extractArgs(item)
+		args := make([]interface{}, len(keep))
+		for i, j := range keep {
+			args[i] = all[j]
+		}
+		return args
+	}
+}
@@ -0,0 +1,302 @@
+package tidb
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/chain"
+	"github.com/siddon/web3insights/internal/config"
+)
+
+// readChunkSize is the number of rows a row group worker decodes at a time
+const readChunkSize = 256
+
+// readChunksBuffered is the number of decoded chunks a worker may hold before
+// the consumer catches up; it bounds memory at concurrency*readChunksBuffered chunks
+const readChunksBuffered = 2
+
+// rowChunk is a slice of decoded rows, or the error that stopped a worker
+type rowChunk[T any] struct {
+	rows []T
+	err  error
+}
+
+// rowReader reads the rows of a parquet file as T. Up to concurrency row groups
+// are decoded by parallel goroutines, but rows are always returned in file
+// order so progress checkpoints stay valid.
+type rowReader[T any] struct {
+	chunks []chan rowChunk[T] // One channel per remaining row group, in file order
+	group  int                // Index in chunks of the row group being consumed
+	rows   []T                // Undelivered rows of the current chunk
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// newRowReader starts decoding file with schema from startRow
+func newRowReader[T any](file *parquet.File, schema *parquet.Schema, concurrency int, startRow int64) (*rowReader[T], error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if startRow > file.NumRows() {
+		return nil, fmt.Errorf("start row %d is past the end of the file (%d rows)", startRow, file.NumRows())
+	}
+
+	// Skip whole row groups before startRow and seek within the first remaining one
+	type task struct {
+		rowGroup parquet.RowGroup
+		offset   int64
+	}
+	var tasks []task
+	for _, rowGroup := range file.RowGroups() {
+		n := rowGroup.NumRows()
+		if startRow >= n {
+			startRow -= n
+			continue
+		}
+		tasks = append(tasks, task{rowGroup: rowGroup, offset: startRow})
+		startRow = 0
+	}
+
+	r := &rowReader[T]{
+		chunks: make([]chan rowChunk[T], len(tasks)),
+		done:   make(chan struct{}),
+	}
+	for i := range r.chunks {
+		r.chunks[i] = make(chan rowChunk[T], readChunksBuffered)
+	}
+
+	// Start workers in file order, at most concurrency at a time
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		slots := make(chan struct{}, concurrency)
+		for i, t := range tasks {
+			select {
+			case slots <- struct{}{}:
+			case <-r.done:
+				return
+			}
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				defer func() { <-slots }()
+				r.decode(t.rowGroup, schema, t.offset, r.chunks[i])
+			}()
+		}
+	}()
+
+	return r, nil
+}
+
+// decode reads one row group into out and closes it
+func (r *rowReader[T]) decode(rowGroup parquet.RowGroup, schema *parquet.Schema, offset int64, out chan<- rowChunk[T]) {
+	defer close(out)
+
+	send := func(chunk rowChunk[T]) bool {
+		select {
+		case out <- chunk:
+			return true
+		case <-r.done:
+			return false
+		}
+	}
+
+	rowGroup, err := chain.NormalizeTimestamps(rowGroup, schema)
+	if err != nil {
+		send(rowChunk[T]{err: err})
+		return
+	}
+	reader := parquet.NewGenericRowGroupReader[T](rowGroup, schema)
+	defer reader.Close()
+
+	if offset > 0 {
+		if err := reader.SeekToRow(offset); err != nil {
+			send(rowChunk[T]{err: fmt.Errorf("failed to seek to row %d: %w", offset, err)})
+			return
+		}
+	}
+
+	for {
+		rows := make([]T, readChunkSize)
+		n, err := reader.Read(rows)
+		if n > 0 && !send(rowChunk[T]{rows: rows[:n]}) {
+			return
+		}
+		if err == io.EOF || (err == nil && n == 0) {
+			return
+		}
+		if err != nil {
+			send(rowChunk[T]{err: err})
+			return
+		}
+	}
+}
+
+// Read fills rows with the next rows of the file. Like parquet.GenericReader,
+// it returns io.EOF once every row has been read.
+func (r *rowReader[T]) Read(rows []T) (int, error) {
+	n := 0
+	for n < len(rows) {
+		if len(r.rows) == 0 {
+			if r.group >= len(r.chunks) {
+				return n, io.EOF
+			}
+			chunk, ok := <-r.chunks[r.group]
+			if !ok {
+				r.group++
+				continue
+			}
+			if chunk.err != nil {
+				return n, chunk.err
+			}
+			r.rows = chunk.rows
+		}
+		copied := copy(rows[n:], r.rows)
+		r.rows = r.rows[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+// Close stops the workers and waits for them to exit
+func (r *rowReader[T]) Close() {
+	r.once.Do(func() { close(r.done) })
+	r.wg.Wait()
+}
+
+// openRowReader reads the rows of a dataset file with the read concurrency and
+// column projection configured in cfg. Columns in skip are not decoded either.
+func openRowReader[T any](cfg *config.Config, parquetFile *parquet.File, filePath, dataset string, skip []string, startRow int64) (*rowReader[T], error) {
+	schema, skipped := readSchema[T](cfg, dataset, skip)
+	if len(skipped) > 0 {
+		fmt.Printf("Skipping columns %s in %s\n", strings.Join(skipped, ", "), filepath.Base(filePath))
+	}
+	return newRowReader[T](parquetFile, schema, cfg.ReadConcurrency, startRow)
+}
+
+// readSchema returns the schema loaders decode dataset files with: the schema
+// of T without the columns listed for the dataset in cfg.SkipColumns or in extra.
+// It also returns the skipped column paths.
+func readSchema[T any](cfg *config.Config, dataset string, extra []string) (*parquet.Schema, []string) {
+	var zero T
+	schema := parquet.SchemaOf(zero)
+	skip := skippedColumns(cfg, dataset)
+	for _, column := range extra {
+		skip[column] = true
+	}
+	return projectSchema(schema, skip)
+}
+
+// projectSchema returns schema without the column paths in skip, and the
+// skipped paths
+func projectSchema(schema *parquet.Schema, skip map[string]bool) (*parquet.Schema, []string) {
+	if len(skip) == 0 {
+		return schema, nil
+	}
+	p := &projection{skip: skip, skipped: make(map[string]bool)}
+	root := projectedNode{Node: schema, p: p}
+	visitGroups(root)
+	if len(p.skipped) == 0 {
+		return schema, nil
+	}
+	skipped := make([]string, 0, len(p.skipped))
+	for path := range p.skipped {
+		skipped = append(skipped, path)
+	}
+	sort.Strings(skipped)
+	p.skipped = nil // Fields may later be called from concurrent readers
+	return parquet.NewSchema(schema.Name(), root), skipped
+}
+
+// visitGroups calls Fields on every group below node
+func visitGroups(node parquet.Node) {
+	for _, field := range node.Fields() {
+		if !field.Leaf() {
+			visitGroups(field)
+		}
+	}
+}
+
+// skippedColumns returns the column paths listed for dataset in cfg.SkipColumns,
+// which holds comma-separated "<dataset>.<column>" entries such as
+// "transactions.inputs.script_asm"
+func skippedColumns(cfg *config.Config, dataset string) map[string]bool {
+	skip := make(map[string]bool)
+	for _, entry := range strings.Split(cfg.SkipColumns, ",") {
+		entry = strings.TrimSpace(entry)
+		if column, ok := strings.CutPrefix(entry, dataset+"."); ok && column != "" {
+			skip[column] = true
+		}
+	}
+	return skip
+}
+
+// projection drops the columns in skip while walking a schema. Paths leave out
+// the list/element levels of LIST groups, so "inputs.script_asm" names the
+// script_asm leaf of every input; naming a group skips all of its columns.
+type projection struct {
+	skip    map[string]bool
+	skipped map[string]bool // Paths of skip that matched a column, collected by readSchema
+}
+
+func (p *projection) fields(node parquet.Node, path string) []parquet.Field {
+	isList := false
+	if lt := node.Type().LogicalType(); lt != nil && lt.List != nil {
+		isList = true
+	}
+
+	var fields []parquet.Field
+	for _, field := range node.Fields() {
+		fieldPath := path
+		// The repeated "list" group of a LIST and its "element" are wrappers
+		if !isList && !(field.Name() == "element" && node.Repeated()) {
+			if fieldPath != "" {
+				fieldPath += "."
+			}
+			fieldPath += field.Name()
+		}
+		if p.skip[fieldPath] && fieldPath != path {
+			if p.skipped != nil {
+				p.skipped[fieldPath] = true
+			}
+			continue
+		}
+		if field.Leaf() {
+			fields = append(fields, field)
+		} else {
+			fields = append(fields, projectedField{Field: field, p: p, path: fieldPath})
+		}
+	}
+	return fields
+}
+
+// projectedNode is a schema root whose Fields omit skipped columns
+type projectedNode struct {
+	parquet.Node
+	p *projection
+}
+
+func (n projectedNode) Fields() []parquet.Field { return n.p.fields(n.Node, "") }
+
+// projectedField is a group field whose Fields omit skipped columns
+type projectedField struct {
+	parquet.Field
+	p    *projection
+	path string
+}
+
+func (f projectedField) Fields() []parquet.Field { return f.p.fields(f.Field, f.path) }
+
+// hasColumn reports whether a file schema has the column or group at path,
+// written like skip_columns paths, e.g. "inputs.witness"
+func hasColumn(schema parquet.Node, path string) bool {
+	p := &projection{skip: map[string]bool{path: true}, skipped: make(map[string]bool)}
+	visitGroups(projectedNode{Node: schema, p: p})
+	return p.skipped[path]
+}
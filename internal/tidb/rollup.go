@@ -0,0 +1,51 @@
+package tidb
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// btcDailyStatsSQL computes the btc_daily_stats row of a date from the loaded
+// tables. Every subquery filters on record_date, so each reads one partition.
+const btcDailyStatsSQL = `INSERT INTO btc_daily_stats (record_date, block_count, first_block, last_block,
+  total_transactions, avg_difficulty, avg_block_size, avg_block_weight, transaction_count, total_volume,
+  total_fees, avg_fee, input_count, output_count, active_addresses, sending_addresses, receiving_addresses)
+SELECT ?, b.block_count, b.first_block, b.last_block, b.total_transactions, b.avg_difficulty, b.avg_block_size,
+  b.avg_block_weight, t.transaction_count, t.total_volume, t.total_fees, t.avg_fee, t.input_count, t.output_count,
+  a.active_addresses, s.sending_addresses, r.receiving_addresses
+FROM (SELECT COUNT(*) AS block_count, MIN(number) AS first_block, MAX(number) AS last_block,
+    COALESCE(SUM(transaction_count), 0) AS total_transactions, AVG(difficulty) AS avg_difficulty,
+    AVG(size) AS avg_block_size, AVG(weight) AS avg_block_weight
+  FROM btc_blocks WHERE record_date = ?) b
+CROSS JOIN (SELECT COUNT(*) AS transaction_count, COALESCE(SUM(output_value), 0) AS total_volume,
+    COALESCE(SUM(fee), 0) AS total_fees, COALESCE(AVG(fee), 0) AS avg_fee,
+    COALESCE(SUM(input_count), 0) AS input_count, COALESCE(SUM(output_count), 0) AS output_count
+  FROM btc_transactions WHERE record_date = ?) t
+CROSS JOIN (SELECT COUNT(DISTINCT address) AS active_addresses FROM (
+    SELECT address FROM btc_transaction_inputs WHERE record_date = ? AND address <> ''
+    UNION SELECT address FROM btc_transaction_outputs WHERE record_date = ? AND address <> '') u) a
+CROSS JOIN (SELECT COUNT(DISTINCT address) AS sending_addresses
+  FROM btc_transaction_inputs WHERE record_date = ? AND address <> '') s
+CROSS JOIN (SELECT COUNT(DISTINCT address) AS receiving_addresses
+  FROM btc_transaction_outputs WHERE record_date = ? AND address <> '') r
+WHERE b.block_count > 0`
+
+// RollupBtcDate recomputes the btc_daily_stats row of a date from the loaded
+// tables, so dashboards read one row per day instead of scanning them. A date
+// without blocks loses its row.
+func RollupBtcDate(db *sql.DB, date string) error {
+	return retryWithBackoffNoReturn(func() error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if _, err := tx.Exec("DELETE FROM btc_daily_stats WHERE record_date = ?", date); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(btcDailyStatsSQL, date, date, date, date, date, date, date); err != nil {
+			return fmt.Errorf("failed to compute daily stats: %w", err)
+		}
+		return tx.Commit()
+	}, "roll up daily stats for "+date)
+}
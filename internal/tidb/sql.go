@@ -9,6 +9,7 @@ import (
 	"github.com/go-sql-driver/mysql" // Import registers the driver and provides mysql package
 
 	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/schema"
 )
 
 // OpenSQL opens a sql.DB connection to TiDB Cloud using MySQL protocol with TLS.
@@ -39,3 +40,18 @@ func OpenSQL(cfg *config.Config) (*sql.DB, error) {
 	db.SetMaxOpenConns(20)
 	return db, nil
 }
+
+// CreateTables executes each statement of a schema DDL file.
+// The DDL uses CREATE TABLE IF NOT EXISTS, so this is safe to run repeatedly.
+func CreateTables(db *sql.DB, ddl string) error {
+	for _, statement := range schema.Statements(ddl) {
+		err := retryWithBackoffNoReturn(func() error {
+			_, err := db.Exec(statement)
+			return err
+		}, "create table")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package tidb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/consensus"
+)
+
+// supplyBlock is the part of a block row the supply analyzer reads
+type supplyBlock struct {
+	Date   string `parquet:"date"`
+	Hash   string `parquet:"hash"`
+	Number int64  `parquet:"number"`
+}
+
+// supplyTransaction is the part of a transaction row the supply analyzer reads
+type supplyTransaction struct {
+	BlockNumber int64   `parquet:"block_number"`
+	IsCoinbase  bool    `parquet:"is_coinbase,optional"`
+	OutputValue float64 `parquet:"output_value,optional"`
+	Fee         float64 `parquet:"fee,optional"`
+}
+
+// Supply is the issuance of a block and the supply after it. Amounts are in
+// satoshis.
+type Supply struct {
+	RecordDate      time.Time
+	BlockNumber     int64
+	BlockHash       string
+	ExpectedSubsidy int64 // From the halving schedule
+	ClaimedSubsidy  int64 // Coinbase output value minus the block's fees
+	Fees            int64
+	Unclaimed       int64 // Subsidy the coinbase didn't claim, destroyed forever
+	Supply          int64 // Circulating supply after the block
+	ExpectedSupply  int64 // Supply if every block had claimed its full subsidy
+	Exact           bool  // False if blocks before the first tracked one are assumed to claim their full subsidy
+}
+
+func extractSupplyArgs(s Supply) []interface{} {
+	return []interface{}{
+		s.RecordDate, s.BlockNumber, s.BlockHash, FormatSats(s.ExpectedSubsidy), FormatSats(s.ClaimedSubsidy),
+		FormatSats(s.Fees), FormatSats(s.Unclaimed), s.Unclaimed > 0, FormatSats(s.Supply),
+		FormatSats(s.ExpectedSupply), s.Exact,
+	}
+}
+
+// FormatSats formats satoshis as a BTC decimal with 8 places
+func FormatSats(sats int64) string {
+	sign := ""
+	if sats < 0 {
+		sign, sats = "-", -sats
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, sats/1e8, sats%1e8)
+}
+
+// deriveSupply computes the issuance of each block of a date into btc_supply,
+// continuing the circulating supply from the block before the date. If that
+// block isn't in btc_supply, earlier blocks are assumed to have claimed their
+// full subsidy and the rows are marked inexact.
+func deriveSupply(db *sql.DB, cfg *config.Config, blocksDir, transactionsDir string) error {
+	blocks, err := readDirRows[supplyBlock](cfg, blocksDir, nil)
+	if err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	transactions, err := readDirRows[supplyTransaction](cfg, transactionsDir, nil)
+	if err != nil {
+		return err
+	}
+	coinbaseValue := make(map[int64]int64, len(blocks))
+	fees := make(map[int64]int64, len(blocks))
+	for _, tx := range transactions {
+		if tx.IsCoinbase {
+			coinbaseValue[tx.BlockNumber] += satoshis(tx.OutputValue)
+		} else {
+			fees[tx.BlockNumber] += satoshis(tx.Fee)
+		}
+	}
+
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Number < blocks[j].Number })
+	supply, exact := int64(0), true
+	if first := blocks[0].Number; first > 0 {
+		previous, err := SupplyAt(db, first-1)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			supply, exact = previous.Supply, previous.Exact
+		} else {
+			supply, exact = consensus.ExpectedSupply(first-1), false
+		}
+	}
+
+	rows := make([]Supply, 0, len(blocks))
+	underClaimed := 0
+	for i, block := range blocks {
+		if i > 0 && block.Number != blocks[i-1].Number+1 {
+			return fmt.Errorf("block %d is missing from %s", blocks[i-1].Number+1, blocksDir)
+		}
+		recordDate, err := time.Parse("2006-01-02", block.Date)
+		if err != nil {
+			return fmt.Errorf("invalid date %q in block %d: %w", block.Date, block.Number, err)
+		}
+		row := Supply{
+			RecordDate:      recordDate,
+			BlockNumber:     block.Number,
+			BlockHash:       block.Hash,
+			ExpectedSubsidy: consensus.Subsidy(block.Number),
+			ClaimedSubsidy:  coinbaseValue[block.Number] - fees[block.Number],
+			Fees:            fees[block.Number],
+			ExpectedSupply:  consensus.ExpectedSupply(block.Number),
+			Exact:           exact,
+		}
+		// A claim above the schedule means the fee data is incomplete, not extra issuance
+		issued := min(row.ClaimedSubsidy, row.ExpectedSubsidy)
+		row.Unclaimed = row.ExpectedSubsidy - issued
+		if row.Unclaimed > 0 {
+			underClaimed++
+		}
+		supply += issued
+		row.Supply = supply
+		rows = append(rows, row)
+	}
+
+	inserter, err := newBatchInserter(db, BtcSupplyTable, cfg.BlockBatchSize, extractSupplyArgs)
+	if err != nil {
+		return err
+	}
+	defer inserter.Close()
+	inserter.add(rows...)
+	if _, err := inserter.flush(); err != nil {
+		return err
+	}
+	last := rows[len(rows)-1]
+	fmt.Printf("Tracked the supply of %d blocks: %s BTC after block %d (%d under-claimed)\n",
+		len(rows), FormatSats(last.Supply), last.BlockNumber, underClaimed)
+	return nil
+}
+
+// SupplyAt returns the btc_supply row of a block, or nil if it isn't tracked
+func SupplyAt(db *sql.DB, height int64) (*Supply, error) {
+	var s Supply
+	err := db.QueryRow(`SELECT record_date, block_number, block_hash,
+  CAST(expected_subsidy * 100000000 AS SIGNED), CAST(claimed_subsidy * 100000000 AS SIGNED),
+  CAST(fees * 100000000 AS SIGNED), CAST(unclaimed * 100000000 AS SIGNED), CAST(supply * 100000000 AS SIGNED),
+  CAST(expected_supply * 100000000 AS SIGNED), supply_exact
+FROM btc_supply WHERE block_number = ? ORDER BY record_date DESC LIMIT 1`, height).Scan(
+		&s.RecordDate, &s.BlockNumber, &s.BlockHash, &s.ExpectedSubsidy, &s.ClaimedSubsidy, &s.Fees, &s.Unclaimed,
+		&s.Supply, &s.ExpectedSupply, &s.Exact)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the supply at block %d: %w", height, err)
+	}
+	return &s, nil
+}
+
+// LatestSupply returns the btc_supply row of the highest tracked block, or nil
+// if there is none
+func LatestSupply(db *sql.DB) (*Supply, error) {
+	var height sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(block_number) FROM btc_supply").Scan(&height); err != nil {
+		return nil, fmt.Errorf("failed to query the latest supply: %w", err)
+	}
+	if !height.Valid {
+		return nil, nil
+	}
+	return SupplyAt(db, height.Int64)
+}
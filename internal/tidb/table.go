@@ -0,0 +1,205 @@
+package tidb
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/siddon/web3insights/internal/config"
+)
+
+// Table describes a target table and the columns a loader writes, in argument order
+type Table struct {
+	Name    string
+	Columns []string
+	Omit    map[string][]string // Columns left out under each column profile, e.g. "lean"
+	Sources map[string]string   // Dataset column each omittable column is decoded from, e.g. "inputs.script_asm"
+	Replace bool                // Rows replace existing rows with the same key instead of being ignored
+}
+
+// insertSQL returns "INSERT IGNORE INTO <table> (<columns>) VALUES ", or
+// REPLACE INTO for Replace tables, ready for buildValuesSQL
+func (t Table) insertSQL() string {
+	verb := "INSERT IGNORE INTO "
+	if t.Replace {
+		verb = "REPLACE INTO "
+	}
+	return verb + t.Name + " (" + strings.Join(t.Columns, ", ") + ") VALUES "
+}
+
+// LoaderFunc loads a single parquet file into TiDB starting at startRow
+type LoaderFunc func(db *sql.DB, filePath string, cfg *config.Config, onProgress ProgressCallback, startRow int64) error
+
+// RowMapping maps each parquet row of type T to exactly one row of Table.
+// Args must return one value per column in Table.Columns.
+type RowMapping[T any] struct {
+	Table     Table
+	Dataset   string // Dataset name, used to look up skipped columns, e.g. "token_transfers"
+	Label     string // Used in progress messages, e.g. "token transfers"
+	Args      func(T) []interface{}
+	BatchSize func(cfg *config.Config) int
+}
+
+// Load reads a parquet file of T rows and inserts them into the mapped table.
+// It has the LoaderFunc signature so it can be used as a method value.
+func (m RowMapping[T]) Load(db *sql.DB, filePath string, cfg *config.Config, onProgress ProgressCallback, startRow int64) error {
+	return insertRowsFromFile(db, cfg, filePath, m.Dataset, m.Label, m.Table, m.BatchSize(cfg), m.Args, onProgress, startRow)
+}
+
+func blockBatchSize(cfg *config.Config) int       { return cfg.BlockBatchSize }
+func transactionBatchSize(cfg *config.Config) int { return cfg.TransactionBatchSize }
+
+// BTC tables
+var (
+	// Blocks keep their dashboard columns under every profile
+	BtcBlocksTable = Table{Name: "btc_blocks", Columns: []string{
+		"record_date", "hash", "size", "stripped_size", "weight", "number", "version", "merkle_root",
+		"block_timestamp", "nonce", "bits", "coinbase_param", "transaction_count", "mediantime",
+		"difficulty", "chainwork", "previousblockhash",
+	}, Omit: map[string][]string{
+		ProfileLean:    {"coinbase_param"},
+		ProfileMinimal: {"coinbase_param"},
+	}, Sources: map[string]string{
+		"coinbase_param": "coinbase_param",
+	}}
+	BtcTransactionsTable = Table{Name: "btc_transactions", Columns: []string{
+		"record_date", "hash", "size", "virtual_size", "version", "lock_time", "block_hash", "block_number",
+		"block_timestamp", "tx_index", "input_count", "output_count", "input_value", "output_value",
+		"is_coinbase", "fee",
+	}}
+	BtcTransactionInputsTable = Table{Name: "btc_transaction_inputs", Columns: []string{
+		"record_date", "transaction_hash", "input_index", "spent_transaction_hash", "spent_output_index",
+		"script_asm", "script_hex", "sequence", "required_signatures", "input_type", "address", "spent_value",
+	}, Omit: map[string][]string{
+		ProfileLean: {"script_asm"},
+		ProfileMinimal: {"spent_transaction_hash", "spent_output_index", "script_asm", "script_hex",
+			"sequence", "required_signatures", "input_type"},
+	}, Sources: map[string]string{
+		"spent_transaction_hash": "inputs.spent_transaction_hash",
+		"spent_output_index":     "inputs.spent_output_index",
+		"script_asm":             "inputs.script_asm",
+		"script_hex":             "inputs.script_hex",
+		"sequence":               "inputs.sequence",
+		"required_signatures":    "inputs.required_signatures",
+		"input_type":             "inputs.type",
+	}}
+	BtcTransactionOutputsTable = Table{Name: "btc_transaction_outputs", Columns: []string{
+		"record_date", "transaction_hash", "output_index", "script_asm", "script_hex", "required_signatures",
+		"output_type", "address", "output_amount",
+	}, Omit: map[string][]string{
+		ProfileLean:    {"script_asm"},
+		ProfileMinimal: {"script_asm", "script_hex", "required_signatures", "output_type"},
+	}, Sources: map[string]string{
+		"script_asm":          "outputs.script_asm",
+		"script_hex":          "outputs.script_hex",
+		"required_signatures": "outputs.required_signatures",
+		"output_type":         "outputs.type",
+	}}
+	// Written by the op_returns analyzer
+	BtcOpReturnsTable = Table{Name: "btc_op_returns", Columns: []string{
+		"record_date", "transaction_hash", "output_index", "block_number", "protocol", "data", "data_size",
+		"data_text", "push_count", "output_amount",
+	}}
+	// Written by the inscriptions analyzer
+	BtcInscriptionsTable = Table{Name: "btc_inscriptions", Columns: []string{
+		"record_date", "inscription_id", "transaction_hash", "input_index", "envelope_index", "block_number",
+		"content_type", "content_encoding", "metaprotocol", "content_size",
+	}}
+	// Written by the miners analyzer; rows are replaced so an updated pool database relabels blocks
+	BtcBlockMinersTable = Table{Name: "btc_block_miners", Columns: []string{
+		"record_date", "block_number", "block_hash", "pool_name", "pool_link", "match_method", "matched",
+		"coinbase_text", "payout_address", "coinbase_value", "difficulty",
+	}, Replace: true}
+	// Written by the block_stats analyzer; fee rates are in sat/vB
+	BtcBlockStatsTable = Table{Name: "btc_block_stats", Columns: []string{
+		"record_date", "block_number", "block_hash", "tx_count", "total_fees", "subsidy", "fee_share", "total_vsize",
+		"weight", "fullness", "min_fee_rate", "p10_fee_rate", "p25_fee_rate", "median_fee_rate", "p75_fee_rate",
+		"p90_fee_rate", "max_fee_rate", "avg_fee_rate",
+	}, Replace: true}
+	BtcBlockStatsDailyTable = Table{Name: "btc_block_stats_daily", Columns: []string{
+		"record_date", "block_count", "tx_count", "total_fees", "total_subsidy", "fee_share", "total_vsize",
+		"total_weight", "avg_fullness", "min_fee_rate", "p10_fee_rate", "p25_fee_rate", "median_fee_rate",
+		"p75_fee_rate", "p90_fee_rate", "max_fee_rate", "avg_fee_rate",
+	}, Replace: true}
+	// Written by the supply analyzer; amounts are BTC decimals
+	BtcSupplyTable = Table{Name: "btc_supply", Columns: []string{
+		"record_date", "block_number", "block_hash", "expected_subsidy", "claimed_subsidy", "fees", "unclaimed",
+		"under_claimed", "supply", "expected_supply", "supply_exact",
+	}, Replace: true}
+	// Written by the privacy analyzer
+	BtcPrivacyLabelsTable = Table{Name: "btc_privacy_labels", Columns: []string{
+		"record_date", "block_number", "transaction_hash", "label", "confidence", "input_count", "output_count",
+		"equal_outputs", "denomination",
+	}, Replace: true}
+	// Written by the clusters analyzer
+	BtcAddressClustersTable = Table{Name: "btc_address_clusters", Columns: []string{
+		"address", "cluster_id",
+	}, Replace: true}
+	BtcClusterStatsTable = Table{Name: "btc_cluster_stats", Columns: []string{
+		"cluster_id", "address_count", "first_date", "last_date",
+	}, Replace: true}
+	// Written by the metrics command's difficulty metric
+	BtcHashrateTable = Table{Name: "btc_hashrate", Columns: []string{
+		"record_date", "block_number", "block_timestamp", "difficulty", "hashrate_144", "hashrate_1008", "chainwork",
+		"chainwork_increasing",
+	}, Replace: true}
+	BtcDifficultyEpochsTable = Table{Name: "btc_difficulty_epochs", Columns: []string{
+		"epoch", "start_height", "end_height", "block_count", "complete", "start_time", "end_time", "difficulty", "bits",
+		"actual_timespan", "target_timespan", "avg_block_interval", "expected_adjustment_pct", "next_difficulty",
+		"adjustment_pct",
+	}, Replace: true}
+	// Written by the token indexer
+	BtcTokenEventsTable = Table{Name: "btc_token_events", Columns: []string{
+		"record_date", "block_number", "tx_index", "event_index", "transaction_hash", "protocol", "event_type",
+		"token", "from_address", "to_address", "amount", "output_index",
+	}}
+	BtcTokenBalancesTable = Table{Name: "btc_token_balances", Columns: []string{
+		"protocol", "token", "address", "balance", "transferable",
+	}}
+	BtcRunesTable = Table{Name: "btc_runes", Columns: []string{
+		"rune_id", "rune", "spaced_rune", "block_number", "tx_index", "transaction_hash", "divisibility", "symbol",
+		"premine", "terms_amount", "terms_cap", "terms_height_start", "terms_height_end", "terms_offset_start",
+		"terms_offset_end", "turbo", "cenotaph",
+	}}
+	BtcRuneOutpointsTable = Table{Name: "btc_rune_outpoints", Columns: []string{
+		"transaction_hash", "output_index", "rune_id", "address", "amount", "created_height",
+	}}
+	BtcBrc20TokensTable = Table{Name: "btc_brc20_tokens", Columns: []string{
+		"tick", "max_supply", "mint_limit", "decimals", "inscription_id", "deployer", "block_number",
+	}}
+	BtcBrc20TransfersTable = Table{Name: "btc_brc20_transfers", Columns: []string{
+		"inscription_id", "tick", "amount", "from_address", "transaction_hash", "output_index", "sat_offset",
+		"created_height",
+	}}
+)
+
+// ETH tables
+var (
+	EthBlocksTable = Table{Name: "eth_blocks", Columns: []string{
+		"record_date", "hash", "number", "block_timestamp", "parent_hash", "nonce", "sha3_uncles", "logs_bloom",
+		"transactions_root", "state_root", "receipts_root", "miner", "difficulty", "total_difficulty", "size",
+		"extra_data", "gas_limit", "gas_used", "transaction_count", "base_fee_per_gas", "withdrawals_root", "withdrawal_count",
+	}}
+	EthTransactionsTable = Table{Name: "eth_transactions", Columns: []string{
+		"record_date", "hash", "nonce", "transaction_index", "from_address", "to_address", "value", "gas", "gas_price",
+		"input", "receipt_cumulative_gas_used", "receipt_gas_used", "receipt_contract_address", "receipt_status",
+		"block_timestamp", "block_number", "block_hash", "max_fee_per_gas", "max_priority_fee_per_gas",
+		"transaction_type", "receipt_effective_gas_price",
+	}}
+	EthLogsTable = Table{Name: "eth_logs", Columns: []string{
+		"record_date", "block_number", "log_index", "transaction_hash", "transaction_index", "address", "data",
+		"topic0", "topic1", "topic2", "topic3", "block_timestamp", "block_hash",
+	}}
+	EthTokenTransfersTable = Table{Name: "eth_token_transfers", Columns: []string{
+		"record_date", "transaction_hash", "log_index", "token_address", "from_address", "to_address", "value",
+		"block_timestamp", "block_number", "block_hash",
+	}}
+	EthReceiptsTable = Table{Name: "eth_receipts", Columns: []string{
+		"record_date", "transaction_hash", "transaction_index", "block_hash", "block_number", "cumulative_gas_used",
+		"gas_used", "contract_address", "root", "status", "effective_gas_price",
+	}}
+	EthTracesTable = Table{Name: "eth_traces", Columns: []string{
+		"record_date", "trace_id", "transaction_hash", "transaction_index", "from_address", "to_address", "value",
+		"input", "output", "trace_type", "call_type", "reward_type", "gas", "gas_used", "subtraces", "trace_address",
+		"error", "status", "block_timestamp", "block_number", "block_hash",
+	}}
+)
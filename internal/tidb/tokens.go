@@ -0,0 +1,787 @@
+package tidb
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/siddon/web3insights/internal/address"
+	"github.com/siddon/web3insights/internal/brc20"
+	"github.com/siddon/web3insights/internal/config"
+	"github.com/siddon/web3insights/internal/ordinals"
+	"github.com/siddon/web3insights/internal/runes"
+	"github.com/siddon/web3insights/internal/tokens"
+)
+
+// tokenIndexerName is the btc_token_progress row of the token indexer
+const tokenIndexerName = "tokens"
+
+// tokenInsertRows is the number of rows per INSERT statement when saving a block
+const tokenInsertRows = 500
+
+// tokenTransaction is the part of a transaction row the token indexer reads
+type tokenTransaction struct {
+	Date        string        `parquet:"date"`
+	Hash        string        `parquet:"hash"`
+	BlockNumber int64         `parquet:"block_number"`
+	Index       int64         `parquet:"index"`
+	IsCoinbase  bool          `parquet:"is_coinbase,optional"`
+	Inputs      []tokenInput  `parquet:"inputs,list,optional"`
+	Outputs     []tokenOutput `parquet:"outputs,list,optional"`
+}
+
+type tokenInput struct {
+	SpentTransactionHash string   `parquet:"spent_transaction_hash,optional"`
+	SpentOutputIndex     int64    `parquet:"spent_output_index,optional"`
+	Value                float64  `parquet:"value,optional"`
+	Witness              []string `parquet:"witness,list,optional"`
+}
+
+type tokenOutput struct {
+	ScriptHex string  `parquet:"script_hex,optional"`
+	Address   string  `parquet:"address,optional"`
+	Value     float64 `parquet:"value,optional"`
+}
+
+// TokenIndex indexes Runes and BRC-20 events from transaction files into the
+// token tables. Each block is saved in one database transaction together with
+// the indexed height, so an interrupted run resumes after the last saved block.
+type TokenIndex struct {
+	db      *sql.DB
+	cfg     *config.Config
+	indexer *tokens.Indexer
+	height  int64 // Last indexed block, or -1
+}
+
+// OpenTokenIndex loads the indexer state saved in the token tables
+func OpenTokenIndex(db *sql.DB, cfg *config.Config) (*TokenIndex, error) {
+	height, err := tokenHeight(db)
+	if err != nil {
+		return nil, err
+	}
+	state, err := loadTokenState(db)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenIndex{db: db, cfg: cfg, indexer: tokens.NewIndexer(state), height: height}, nil
+}
+
+// Height returns the last indexed block, or -1 if no block was indexed
+func (t *TokenIndex) Height() int64 {
+	return t.height
+}
+
+// indexedBlock is a block read from the transaction files of a date
+type indexedBlock struct {
+	number       int64
+	date         time.Time
+	transactions []tokens.Transaction
+}
+
+// IndexDate indexes the blocks in the transaction files of dir that are above
+// the indexed height, in block and transaction order. Blocks must follow the
+// indexed height without gaps; a fresh index must start at or before the first
+// BRC-20 block, since both protocols need the state built before it.
+func (t *TokenIndex) IndexDate(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	if err != nil {
+		return fmt.Errorf("failed to list transaction files: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no transaction files in %s", dir)
+	}
+	sort.Strings(files)
+
+	var transactions []tokenTransaction
+	hasWitness := true
+	for _, file := range files {
+		fileTransactions, witness, err := readTokenTransactions(t.cfg, file, t.height)
+		if err != nil {
+			return err
+		}
+		if !witness {
+			hasWitness = false
+			fmt.Fprintf(os.Stderr, "Warning: %s has no %s column, so BRC-20 inscriptions can't be found and rune etching commitments aren't verified (the AWS dataset doesn't publish witness data)\n",
+				filepath.Base(file), witnessColumn)
+		}
+		transactions = append(transactions, fileTransactions...)
+	}
+	blocks := groupTokenBlocks(transactions)
+	if len(blocks) == 0 {
+		fmt.Printf("No blocks above height %d in %s\n", t.height, dir)
+		return nil
+	}
+
+	t.indexer.VerifyCommitments = hasWitness
+	events := 0
+	for _, block := range blocks {
+		if t.height < 0 && block.number > brc20.FirstHeight {
+			return fmt.Errorf("the token index is empty, so it must start at or before block %d (the first BRC-20 block), not %d", brc20.FirstHeight, block.number)
+		}
+		if t.height >= 0 && block.number != t.height+1 {
+			return fmt.Errorf("block %d is missing: the token index is at block %d and the next block in %s is %d", t.height+1, t.height, dir, block.number)
+		}
+		changes := t.indexer.IndexBlock(uint64(block.number), block.transactions)
+		if err := t.saveBlock(changes, block.date); err != nil {
+			return fmt.Errorf("failed to save block %d: %w", block.number, err)
+		}
+		t.height = block.number
+		events += len(changes.Events)
+	}
+	fmt.Printf("Indexed blocks %d-%d with %d token events from %s\n", blocks[0].number, blocks[len(blocks)-1].number, events, dir)
+	return nil
+}
+
+// readTokenTransactions reads the transactions above height from a file. It
+// also reports whether the file has witness data.
+func readTokenTransactions(cfg *config.Config, filePath string, height int64) ([]tokenTransaction, bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get file info: %w", err)
+	}
+	parquetFile, err := parquet.OpenFile(file, fileInfo.Size())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open parquet file: %w", err)
+	}
+
+	hasWitness := hasColumn(parquetFile.Schema(), witnessColumn)
+	skip := make(map[string]bool)
+	if !hasWitness {
+		skip[witnessColumn] = true
+	}
+	schema, _ := projectSchema(parquet.SchemaOf(tokenTransaction{}), skip)
+	reader, err := newRowReader[tokenTransaction](parquetFile, schema, cfg.ReadConcurrency, 0)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read transactions: %w", err)
+	}
+	defer reader.Close()
+
+	var transactions []tokenTransaction
+	rows := make([]tokenTransaction, cfg.TransactionBatchSize)
+	for {
+		n, err := reader.Read(rows)
+		if err != nil && err != io.EOF {
+			return nil, false, fmt.Errorf("failed to read parquet file: %w", err)
+		}
+		for _, row := range rows[:n] {
+			if row.BlockNumber > height {
+				transactions = append(transactions, row)
+			}
+		}
+		if err == io.EOF || n == 0 {
+			break
+		}
+	}
+	return transactions, hasWitness, nil
+}
+
+// groupTokenBlocks sorts transactions by block and index and converts them
+// for the indexer
+func groupTokenBlocks(transactions []tokenTransaction) []indexedBlock {
+	sort.Slice(transactions, func(i, j int) bool {
+		if transactions[i].BlockNumber != transactions[j].BlockNumber {
+			return transactions[i].BlockNumber < transactions[j].BlockNumber
+		}
+		return transactions[i].Index < transactions[j].Index
+	})
+	var blocks []indexedBlock
+	for _, tx := range transactions {
+		if len(blocks) == 0 || blocks[len(blocks)-1].number != tx.BlockNumber {
+			date, err := time.Parse("2006-01-02", tx.Date)
+			if err != nil {
+				date = time.Time{}
+			}
+			blocks = append(blocks, indexedBlock{number: tx.BlockNumber, date: date})
+		}
+		block := &blocks[len(blocks)-1]
+		block.transactions = append(block.transactions, convertTokenTransaction(tx))
+	}
+	return blocks
+}
+
+// convertTokenTransaction converts a transaction row for the indexer. Only
+// taproot script-path witnesses are kept, since the indexer reads nothing else.
+func convertTokenTransaction(tx tokenTransaction) tokens.Transaction {
+	converted := tokens.Transaction{Hash: tx.Hash, Index: uint32(tx.Index), Coinbase: tx.IsCoinbase}
+	for _, input := range tx.Inputs {
+		in := tokens.Input{
+			Outpoint: tokens.Outpoint{Hash: input.SpentTransactionHash, Index: uint32(input.SpentOutputIndex)},
+			Value:    satoshis(input.Value),
+		}
+		if len(input.Witness) > 0 {
+			witness := make([][]byte, len(input.Witness))
+			for i, element := range input.Witness {
+				witness[i], _ = hex.DecodeString(element)
+			}
+			if _, ok := ordinals.Tapscript(witness); ok {
+				in.Witness = witness
+			}
+		}
+		converted.Inputs = append(converted.Inputs, in)
+	}
+	for _, output := range tx.Outputs {
+		script, _ := hex.DecodeString(output.ScriptHex)
+		addr := output.Address
+		if addr == "" {
+			if addresses, err := address.FromScriptHex(output.ScriptHex, address.Mainnet); err == nil && len(addresses) == 1 {
+				addr = addresses[0]
+			}
+		}
+		converted.Outputs = append(converted.Outputs, tokens.Output{Script: script, Address: addr, Value: satoshis(output.Value)})
+	}
+	return converted
+}
+
+// satoshis converts a BTC value to satoshis
+func satoshis(btc float64) int64 {
+	return int64(math.Round(btc * 1e8))
+}
+
+// saveBlock writes the changes of a block and its height in one transaction
+func (t *TokenIndex) saveBlock(changes *tokens.Changes, date time.Time) error {
+	return retryWithBackoffNoReturn(func() error {
+		tx, err := t.db.Begin()
+		if err != nil {
+			return err
+		}
+		if err := saveTokenChanges(tx, changes, date); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}, fmt.Sprintf("save block %d", changes.Height))
+}
+
+func saveTokenChanges(tx *sql.Tx, changes *tokens.Changes, date time.Time) error {
+	height := int64(changes.Height)
+
+	var rows [][]interface{}
+	for _, e := range changes.Events {
+		var output interface{}
+		if e.Output >= 0 {
+			output = e.Output
+		}
+		rows = append(rows, []interface{}{date, e.Block, e.TxIndex, e.Index, e.TxHash, e.Protocol, e.Type, e.Token,
+			e.From, e.To, formatTokenAmount(e.Protocol, e.Amount), output})
+	}
+	if err := insertTokenRows(tx, BtcTokenEventsTable.insertSQL(), len(BtcTokenEventsTable.Columns), rows); err != nil {
+		return fmt.Errorf("failed to insert token events: %w", err)
+	}
+	if err := applyBalanceDeltas(tx, tokens.BalanceDeltas(changes.Events), 1); err != nil {
+		return err
+	}
+
+	rows = rows[:0]
+	for _, entry := range changes.Runes {
+		rows = append(rows, runeArgs(entry))
+	}
+	if err := insertTokenRows(tx, BtcRunesTable.insertSQL(), len(BtcRunesTable.Columns), rows); err != nil {
+		return fmt.Errorf("failed to insert runes: %w", err)
+	}
+
+	rows = rows[:0]
+	for _, outpoint := range changes.RuneOutpoints {
+		for id, amount := range outpoint.Balances {
+			rows = append(rows, []interface{}{outpoint.Outpoint.Hash, outpoint.Outpoint.Index, id.String(), outpoint.Address, amount.String(), height})
+		}
+	}
+	if err := insertTokenRows(tx, BtcRuneOutpointsTable.insertSQL(), len(BtcRuneOutpointsTable.Columns), rows); err != nil {
+		return fmt.Errorf("failed to insert rune outpoints: %w", err)
+	}
+	for _, outpoint := range changes.SpentRuneOutpoints {
+		if _, err := tx.Exec("UPDATE btc_rune_outpoints SET spent_height = ? WHERE transaction_hash = ? AND output_index = ?",
+			height, outpoint.Hash, outpoint.Index); err != nil {
+			return fmt.Errorf("failed to mark rune outpoint %s spent: %w", outpoint, err)
+		}
+	}
+
+	rows = rows[:0]
+	for _, token := range changes.Tokens {
+		rows = append(rows, []interface{}{token.Tick, brc20.FormatAmount(token.Max), brc20.FormatAmount(token.Limit), token.Decimals,
+			token.InscriptionID, token.Deployer, token.Block})
+	}
+	if err := insertTokenRows(tx, BtcBrc20TokensTable.insertSQL(), len(BtcBrc20TokensTable.Columns), rows); err != nil {
+		return fmt.Errorf("failed to insert BRC-20 tokens: %w", err)
+	}
+
+	rows = rows[:0]
+	for _, transfer := range changes.Transfers {
+		rows = append(rows, []interface{}{transfer.InscriptionID, transfer.Tick, brc20.FormatAmount(transfer.Amount), transfer.From,
+			transfer.Location.Hash, transfer.Location.Index, transfer.Offset, height})
+	}
+	if err := insertTokenRows(tx, BtcBrc20TransfersTable.insertSQL(), len(BtcBrc20TransfersTable.Columns), rows); err != nil {
+		return fmt.Errorf("failed to insert BRC-20 transfers: %w", err)
+	}
+	for _, id := range changes.SpentTransfers {
+		if _, err := tx.Exec("UPDATE btc_brc20_transfers SET spent_height = ? WHERE inscription_id = ?", height, id); err != nil {
+			return fmt.Errorf("failed to mark BRC-20 transfer %s spent: %w", id, err)
+		}
+	}
+
+	if _, err := tx.Exec("REPLACE INTO btc_token_progress (indexer, height) VALUES (?, ?)", tokenIndexerName, height); err != nil {
+		return fmt.Errorf("failed to save token progress: %w", err)
+	}
+	return nil
+}
+
+// runeArgs returns the btc_runes row of a rune entry
+func runeArgs(entry *tokens.RuneEntry) []interface{} {
+	var symbol, amount, limit, heightStart, heightEnd, offsetStart, offsetEnd interface{}
+	if entry.Symbol != 0 {
+		symbol = string(entry.Symbol)
+	}
+	if terms := entry.Terms; terms != nil {
+		if terms.Amount != nil {
+			amount = terms.Amount.String()
+		}
+		if terms.Cap != nil {
+			limit = terms.Cap.String()
+		}
+		heightStart, heightEnd = nullableUint64(terms.HeightStart), nullableUint64(terms.HeightEnd)
+		offsetStart, offsetEnd = nullableUint64(terms.OffsetStart), nullableUint64(terms.OffsetEnd)
+	}
+	return []interface{}{
+		entry.ID.String(),
+		entry.Rune.String(),
+		entry.Rune.Spaced(entry.Spacers),
+		entry.ID.Block,
+		entry.ID.Tx,
+		entry.TxHash,
+		entry.Divisibility,
+		symbol,
+		entry.Premine.String(),
+		amount,
+		limit,
+		heightStart,
+		heightEnd,
+		offsetStart,
+		offsetEnd,
+		entry.Turbo,
+		entry.Cenotaph,
+	}
+}
+
+func nullableUint64(v *uint64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// insertTokenRows inserts rows in statements of at most tokenInsertRows rows
+func insertTokenRows(tx *sql.Tx, baseSQL string, columns int, rows [][]interface{}) error {
+	for len(rows) > 0 {
+		n := min(len(rows), tokenInsertRows)
+		args := make([]interface{}, 0, n*columns)
+		for _, row := range rows[:n] {
+			args = append(args, row...)
+		}
+		if _, err := tx.Exec(baseSQL+buildValuesSQL(n, columns), args...); err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+// applyBalanceDeltas adds sign times each delta to btc_token_balances
+func applyBalanceDeltas(tx *sql.Tx, deltas map[tokens.BalanceKey]*tokens.Balance, sign int64) error {
+	keys := make([]tokens.BalanceKey, 0, len(deltas))
+	for key := range deltas {
+		if key.Address != "" {
+			keys = append(keys, key)
+		}
+	}
+	// A stable order keeps concurrent upserts from deadlocking
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.Token != b.Token {
+			return a.Token < b.Token
+		}
+		return a.Address < b.Address
+	})
+	var rows [][]interface{}
+	for _, key := range keys {
+		delta := deltas[key]
+		balance := new(big.Int).Mul(delta.Balance, big.NewInt(sign))
+		transferable := new(big.Int).Mul(delta.Transferable, big.NewInt(sign))
+		if balance.Sign() == 0 && transferable.Sign() == 0 {
+			continue
+		}
+		rows = append(rows, []interface{}{key.Protocol, key.Token, key.Address,
+			formatTokenAmount(key.Protocol, balance), formatTokenAmount(key.Protocol, transferable)})
+	}
+	upsertSQL := "INSERT INTO " + BtcTokenBalancesTable.Name + " (" + strings.Join(BtcTokenBalancesTable.Columns, ", ") + ") VALUES "
+	for len(rows) > 0 {
+		n := min(len(rows), tokenInsertRows)
+		args := make([]interface{}, 0, n*len(BtcTokenBalancesTable.Columns))
+		for _, row := range rows[:n] {
+			args = append(args, row...)
+		}
+		query := upsertSQL + buildValuesSQL(n, len(BtcTokenBalancesTable.Columns)) +
+			" ON DUPLICATE KEY UPDATE balance = balance + VALUES(balance), transferable = transferable + VALUES(transferable)"
+		if _, err := tx.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to update token balances: %w", err)
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+// formatTokenAmount formats an amount for a DECIMAL column: rune units as
+// integers, BRC-20 amounts with their 18 decimals
+func formatTokenAmount(protocol string, amount *big.Int) string {
+	if protocol == tokens.ProtocolBRC20 {
+		return brc20.FormatAmount(amount)
+	}
+	return amount.String()
+}
+
+// parseTokenAmount parses a DECIMAL column value formatted by formatTokenAmount
+func parseTokenAmount(protocol, s string) (*big.Int, error) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var n *big.Int
+	if protocol == tokens.ProtocolBRC20 {
+		amount, err := brc20.ParseAmount(s, brc20.MaxDecimals)
+		if err != nil {
+			return nil, err
+		}
+		n = amount
+	} else {
+		whole, _, _ := strings.Cut(s, ".")
+		amount, ok := new(big.Int).SetString(whole, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+		n = amount
+	}
+	if negative {
+		n.Neg(n)
+	}
+	return n, nil
+}
+
+// tokenHeight returns the last indexed block, or -1
+func tokenHeight(db *sql.DB) (int64, error) {
+	var height int64
+	err := db.QueryRow("SELECT height FROM btc_token_progress WHERE indexer = ?", tokenIndexerName).Scan(&height)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read token progress: %w", err)
+	}
+	return height, nil
+}
+
+// RollbackTokens removes everything the token indexer saved for blocks at or
+// above height, so indexing can be repeated from height
+func RollbackTokens(db *sql.DB, height int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin rollback: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Undo the balance changes of the removed events
+	events, err := loadTokenEvents(tx, height)
+	if err != nil {
+		return err
+	}
+	if err := applyBalanceDeltas(tx, tokens.BalanceDeltas(events), -1); err != nil {
+		return err
+	}
+
+	statements := []string{
+		"DELETE FROM btc_token_events WHERE block_number >= ?",
+		"DELETE FROM btc_runes WHERE block_number >= ?",
+		"DELETE FROM btc_rune_outpoints WHERE created_height >= ?",
+		"UPDATE btc_rune_outpoints SET spent_height = NULL WHERE spent_height >= ?",
+		"DELETE FROM btc_brc20_tokens WHERE block_number >= ?",
+		"DELETE FROM btc_brc20_transfers WHERE created_height >= ?",
+		"UPDATE btc_brc20_transfers SET spent_height = NULL WHERE spent_height >= ?",
+		"DELETE FROM btc_token_balances WHERE balance = 0 AND transferable = 0",
+	}
+	for _, statement := range statements {
+		args := []interface{}{height}
+		if !strings.Contains(statement, "?") {
+			args = nil
+		}
+		if _, err := tx.Exec(statement, args...); err != nil {
+			return fmt.Errorf("failed to roll back token tables: %w", err)
+		}
+	}
+	if _, err := tx.Exec("UPDATE btc_token_progress SET height = ? WHERE indexer = ? AND height >= ?", height-1, tokenIndexerName, height); err != nil {
+		return fmt.Errorf("failed to roll back token progress: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback: %w", err)
+	}
+	fmt.Printf("Rolled back %d token events at or above block %d\n", len(events), height)
+	return nil
+}
+
+// loadTokenEvents loads the events at or above height
+func loadTokenEvents(tx *sql.Tx, height int64) ([]tokens.Event, error) {
+	rows, err := tx.Query("SELECT protocol, event_type, token, from_address, to_address, amount FROM btc_token_events WHERE block_number >= ?", height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query token events: %w", err)
+	}
+	defer rows.Close()
+	var events []tokens.Event
+	for rows.Next() {
+		var e tokens.Event
+		var amount string
+		if err := rows.Scan(&e.Protocol, &e.Type, &e.Token, &e.From, &e.To, &amount); err != nil {
+			return nil, fmt.Errorf("failed to scan token event: %w", err)
+		}
+		if e.Amount, err = parseTokenAmount(e.Protocol, amount); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// loadTokenState loads the indexer state from the token tables
+func loadTokenState(db *sql.DB) (*tokens.State, error) {
+	state := tokens.NewState()
+	loaders := []struct {
+		name string
+		load func(*sql.DB, *tokens.State) error
+	}{
+		{"runes", loadRunes},
+		{"rune outpoints", loadRuneOutpoints},
+		{"BRC-20 tokens", loadBrc20Tokens},
+		{"BRC-20 balances", loadBrc20Balances},
+		{"BRC-20 transfers", loadBrc20Transfers},
+	}
+	for _, loader := range loaders {
+		if err := loader.load(db, state); err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", loader.name, err)
+		}
+	}
+	return state, nil
+}
+
+func loadRunes(db *sql.DB, state *tokens.State) error {
+	rows, err := db.Query(`SELECT rune_id, rune, transaction_hash, divisibility, premine, terms_amount, terms_cap,
+		terms_height_start, terms_height_end, terms_offset_start, terms_offset_end, turbo, cenotaph FROM btc_runes`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name, premine string
+		var amount, limit sql.NullString
+		var heightStart, heightEnd, offsetStart, offsetEnd sql.NullInt64
+		entry := &tokens.RuneEntry{Mints: new(big.Int)}
+		if err := rows.Scan(&id, &name, &entry.TxHash, &entry.Divisibility, &premine, &amount, &limit,
+			&heightStart, &heightEnd, &offsetStart, &offsetEnd, &entry.Turbo, &entry.Cenotaph); err != nil {
+			return err
+		}
+		if entry.ID, err = runes.ParseID(id); err != nil {
+			return err
+		}
+		if entry.Rune, err = runes.ParseRune(name); err != nil {
+			return err
+		}
+		if entry.Premine, err = parseTokenAmount(tokens.ProtocolRunes, premine); err != nil {
+			return err
+		}
+		if amount.Valid || limit.Valid || heightStart.Valid || heightEnd.Valid || offsetStart.Valid || offsetEnd.Valid {
+			terms := &runes.Terms{
+				HeightStart: nullUint64(heightStart),
+				HeightEnd:   nullUint64(heightEnd),
+				OffsetStart: nullUint64(offsetStart),
+				OffsetEnd:   nullUint64(offsetEnd),
+			}
+			if amount.Valid {
+				if terms.Amount, err = parseTokenAmount(tokens.ProtocolRunes, amount.String); err != nil {
+					return err
+				}
+			}
+			if limit.Valid {
+				if terms.Cap, err = parseTokenAmount(tokens.ProtocolRunes, limit.String); err != nil {
+					return err
+				}
+			}
+			entry.Terms = terms
+		}
+		state.Runes[entry.ID] = entry
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Mint counts come from the mint events, so they follow rollbacks
+	mints, err := db.Query("SELECT token, COUNT(*) FROM btc_token_events WHERE protocol = ? AND event_type = ? GROUP BY token",
+		tokens.ProtocolRunes, tokens.EventMint)
+	if err != nil {
+		return err
+	}
+	defer mints.Close()
+	for mints.Next() {
+		var token string
+		var count int64
+		if err := mints.Scan(&token, &count); err != nil {
+			return err
+		}
+		id, err := runes.ParseID(token)
+		if err != nil {
+			return err
+		}
+		if entry, ok := state.Runes[id]; ok {
+			entry.Mints.SetInt64(count)
+		}
+	}
+	return mints.Err()
+}
+
+func nullUint64(v sql.NullInt64) *uint64 {
+	if !v.Valid {
+		return nil
+	}
+	n := uint64(v.Int64)
+	return &n
+}
+
+func loadRuneOutpoints(db *sql.DB, state *tokens.State) error {
+	rows, err := db.Query("SELECT transaction_hash, output_index, rune_id, address, amount FROM btc_rune_outpoints WHERE spent_height IS NULL")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var outpoint tokens.Outpoint
+		var id, addr, amount string
+		if err := rows.Scan(&outpoint.Hash, &outpoint.Index, &id, &addr, &amount); err != nil {
+			return err
+		}
+		runeID, err := runes.ParseID(id)
+		if err != nil {
+			return err
+		}
+		balance, err := parseTokenAmount(tokens.ProtocolRunes, amount)
+		if err != nil {
+			return err
+		}
+		entry, ok := state.RuneOutpoints[outpoint]
+		if !ok {
+			entry = &tokens.RuneOutpoint{Outpoint: outpoint, Address: addr, Balances: make(map[runes.ID]*big.Int)}
+			state.RuneOutpoints[outpoint] = entry
+		}
+		entry.Balances[runeID] = balance
+	}
+	return rows.Err()
+}
+
+func loadBrc20Tokens(db *sql.DB, state *tokens.State) error {
+	rows, err := db.Query("SELECT tick, max_supply, mint_limit, decimals, inscription_id, deployer, block_number FROM btc_brc20_tokens")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var max, limit string
+		token := &tokens.Token{Minted: new(big.Int)}
+		if err := rows.Scan(&token.Tick, &max, &limit, &token.Decimals, &token.InscriptionID, &token.Deployer, &token.Block); err != nil {
+			return err
+		}
+		if token.Max, err = parseTokenAmount(tokens.ProtocolBRC20, max); err != nil {
+			return err
+		}
+		if token.Limit, err = parseTokenAmount(tokens.ProtocolBRC20, limit); err != nil {
+			return err
+		}
+		state.Tokens[token.Tick] = token
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Minted supply comes from the mint events, so it follows rollbacks
+	minted, err := db.Query("SELECT token, SUM(amount) FROM btc_token_events WHERE protocol = ? AND event_type = ? GROUP BY token",
+		tokens.ProtocolBRC20, tokens.EventMint)
+	if err != nil {
+		return err
+	}
+	defer minted.Close()
+	for minted.Next() {
+		var tick, amount string
+		if err := minted.Scan(&tick, &amount); err != nil {
+			return err
+		}
+		if token, ok := state.Tokens[tick]; ok {
+			if token.Minted, err = parseTokenAmount(tokens.ProtocolBRC20, amount); err != nil {
+				return err
+			}
+		}
+	}
+	return minted.Err()
+}
+
+func loadBrc20Balances(db *sql.DB, state *tokens.State) error {
+	rows, err := db.Query("SELECT token, address, balance, transferable FROM btc_token_balances WHERE protocol = ?", tokens.ProtocolBRC20)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		key := tokens.BalanceKey{Protocol: tokens.ProtocolBRC20}
+		var balance, transferable string
+		if err := rows.Scan(&key.Token, &key.Address, &balance, &transferable); err != nil {
+			return err
+		}
+		b := &tokens.Balance{}
+		if b.Balance, err = parseTokenAmount(tokens.ProtocolBRC20, balance); err != nil {
+			return err
+		}
+		if b.Transferable, err = parseTokenAmount(tokens.ProtocolBRC20, transferable); err != nil {
+			return err
+		}
+		state.Balances[key] = b
+	}
+	return rows.Err()
+}
+
+func loadBrc20Transfers(db *sql.DB, state *tokens.State) error {
+	rows, err := db.Query(`SELECT inscription_id, tick, amount, from_address, transaction_hash, output_index, sat_offset, created_height
+		FROM btc_brc20_transfers WHERE spent_height IS NULL`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var amount string
+		transfer := &tokens.Transfer{}
+		if err := rows.Scan(&transfer.InscriptionID, &transfer.Tick, &amount, &transfer.From, &transfer.Location.Hash,
+			&transfer.Location.Index, &transfer.Offset, &transfer.Block); err != nil {
+			return err
+		}
+		if transfer.Amount, err = parseTokenAmount(tokens.ProtocolBRC20, amount); err != nil {
+			return err
+		}
+		state.Transfers[transfer.Location] = append(state.Transfers[transfer.Location], transfer)
+	}
+	return rows.Err()
+}
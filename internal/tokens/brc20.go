@@ -0,0 +1,226 @@
+package tokens
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/siddon/web3insights/internal/brc20"
+	"github.com/siddon/web3insights/internal/ordinals"
+)
+
+const (
+	brc20FirstHeight = brc20.FirstHeight
+	// jubileeHeight is the height from which ord stopped cursing inscriptions;
+	// BRC-20 ignores cursed inscriptions revealed before it
+	jubileeHeight = 824544
+)
+
+// flotsam is an inscription moved or revealed by a transaction, at an offset
+// into the transaction's input satoshis
+type flotsam struct {
+	offset   int64
+	transfer *Transfer // A pending transfer inscription being sent
+	id       string    // The id of a revealed inscription
+	op       brc20.Operation
+}
+
+// indexBRC20 applies the BRC-20 operations a transaction reveals and executes
+// the transfer inscriptions it sends. Inscriptions move first-in-first-out
+// across the transaction's satoshis, as in ord. Operations inscribed to fees
+// are ignored, and transfers sent to fees or to outputs without an address
+// return to the sender.
+func (ix *Indexer) indexBRC20(b *txBuilder) {
+	var moved []flotsam
+	var inputValue, outputValue int64
+	for _, output := range b.tx.Outputs {
+		outputValue += output.Value
+	}
+	envelopes := 0
+	for i, input := range b.tx.Inputs {
+		if transfers, ok := ix.state.Transfers[input.Outpoint]; ok {
+			delete(ix.state.Transfers, input.Outpoint)
+			sort.Slice(transfers, func(i, j int) bool { return transfers[i].Offset < transfers[j].Offset })
+			for _, transfer := range transfers {
+				moved = append(moved, flotsam{offset: inputValue + transfer.Offset, transfer: transfer})
+			}
+		}
+		tapscript, ok := ordinals.Tapscript(input.Witness)
+		if ok {
+			for j, inscription := range ordinals.ParseEnvelopes(tapscript) {
+				id := fmt.Sprintf("%si%d", b.tx.Hash, envelopes)
+				envelopes++
+				offset := inputValue
+				if pointer, ok := inscriptionPointer(inscription); ok && pointer < outputValue {
+					offset = pointer
+				}
+				// The common curses: not the first envelope of the first input, or not on its first satoshi
+				if b.height < jubileeHeight && (i > 0 || j > 0 || offset != 0 || hasUnrecognizedEvenField(inscription)) {
+					continue
+				}
+				if inscription.ContentEncoding != "" {
+					continue
+				}
+				op, ok := brc20.Parse(inscription.ContentType, inscription.Body)
+				if !ok {
+					continue
+				}
+				moved = append(moved, flotsam{offset: offset, id: id, op: op})
+			}
+		}
+		inputValue += input.Value
+	}
+
+	for _, f := range moved {
+		output, outputOffset := locate(b.tx.Outputs, f.offset)
+		if f.transfer != nil {
+			ix.executeTransfer(b, f.transfer, output)
+			continue
+		}
+		if output < 0 || b.tx.Outputs[output].Address == "" {
+			continue
+		}
+		owner := b.tx.Outputs[output].Address
+		switch f.op.Op {
+		case brc20.Deploy:
+			ix.deploy(b, f, owner, output)
+		case brc20.Mint:
+			ix.mintBRC20(b, f, owner, output)
+		case brc20.Transfer:
+			ix.inscribeTransfer(b, f, owner, Outpoint{Hash: b.tx.Hash, Index: uint32(output)}, outputOffset)
+		}
+	}
+}
+
+// locate returns the output holding the satoshi at offset and its offset
+// within that output, or -1 if the satoshi goes to fees
+func locate(outputs []Output, offset int64) (int, int64) {
+	var start int64
+	for i, output := range outputs {
+		if offset < start+output.Value {
+			return i, offset - start
+		}
+		start += output.Value
+	}
+	return -1, 0
+}
+
+func (ix *Indexer) deploy(b *txBuilder, f flotsam, owner string, output int) {
+	if _, exists := ix.state.Tokens[f.op.Tick]; exists {
+		return
+	}
+	token := &Token{
+		Tick:          f.op.Tick,
+		Max:           f.op.Max,
+		Limit:         f.op.Limit,
+		Decimals:      f.op.Decimals,
+		Minted:        new(big.Int),
+		InscriptionID: f.id,
+		Deployer:      owner,
+		Block:         b.height,
+	}
+	ix.state.Tokens[token.Tick] = token
+	b.changes.Tokens = append(b.changes.Tokens, token)
+	b.emit(ProtocolBRC20, EventDeploy, token.Tick, "", "", token.Max, output)
+}
+
+// mintBRC20 mints up to the remaining supply of a token
+func (ix *Indexer) mintBRC20(b *txBuilder, f flotsam, owner string, output int) {
+	token, ok := ix.state.Tokens[f.op.Tick]
+	if !ok || !brc20.FitsDecimals(f.op.Amount, token.Decimals) || f.op.Amount.Cmp(token.Limit) > 0 {
+		return
+	}
+	remaining := new(big.Int).Sub(token.Max, token.Minted)
+	if remaining.Sign() <= 0 {
+		return
+	}
+	amount := minInt(f.op.Amount, remaining)
+	token.Minted.Add(token.Minted, amount)
+	balance := ix.balance(token.Tick, owner)
+	balance.Balance.Add(balance.Balance, amount)
+	b.emit(ProtocolBRC20, EventMint, token.Tick, "", owner, amount, output)
+}
+
+// inscribeTransfer reserves an amount of the owner's available balance for a
+// transfer inscription
+func (ix *Indexer) inscribeTransfer(b *txBuilder, f flotsam, owner string, location Outpoint, offset int64) {
+	token, ok := ix.state.Tokens[f.op.Tick]
+	if !ok || !brc20.FitsDecimals(f.op.Amount, token.Decimals) {
+		return
+	}
+	balance := ix.balance(token.Tick, owner)
+	available := new(big.Int).Sub(balance.Balance, balance.Transferable)
+	if available.Cmp(f.op.Amount) < 0 {
+		return
+	}
+	balance.Transferable.Add(balance.Transferable, f.op.Amount)
+	transfer := &Transfer{
+		InscriptionID: f.id,
+		Tick:          token.Tick,
+		Amount:        f.op.Amount,
+		From:          owner,
+		Location:      location,
+		Offset:        offset,
+		Block:         b.height,
+	}
+	ix.state.Transfers[location] = append(ix.state.Transfers[location], transfer)
+	b.changes.Transfers = append(b.changes.Transfers, transfer)
+	b.emit(ProtocolBRC20, EventInscribeTransfer, token.Tick, owner, "", f.op.Amount, int(location.Index))
+}
+
+// executeTransfer sends a transfer inscription's amount to the owner of the
+// output it moved to
+func (ix *Indexer) executeTransfer(b *txBuilder, transfer *Transfer, output int) {
+	to := transfer.From
+	if output >= 0 && b.tx.Outputs[output].Address != "" {
+		to = b.tx.Outputs[output].Address
+	}
+	from := ix.balance(transfer.Tick, transfer.From)
+	from.Balance.Sub(from.Balance, transfer.Amount)
+	from.Transferable.Sub(from.Transferable, transfer.Amount)
+	recipient := ix.balance(transfer.Tick, to)
+	recipient.Balance.Add(recipient.Balance, transfer.Amount)
+	b.changes.SpentTransfers = append(b.changes.SpentTransfers, transfer.InscriptionID)
+	b.emit(ProtocolBRC20, EventTransfer, transfer.Tick, transfer.From, to, transfer.Amount, output)
+}
+
+// balance returns the BRC-20 balance of address, creating it if needed
+func (ix *Indexer) balance(tick, address string) *Balance {
+	key := BalanceKey{Protocol: ProtocolBRC20, Token: tick, Address: address}
+	balance, ok := ix.state.Balances[key]
+	if !ok {
+		balance = &Balance{Balance: new(big.Int), Transferable: new(big.Int)}
+		ix.state.Balances[key] = balance
+	}
+	return balance
+}
+
+// inscriptionPointer decodes the pointer field, a little-endian integer
+func inscriptionPointer(inscription ordinals.Inscription) (int64, bool) {
+	data, ok := inscription.Fields[ordinals.TagPointer]
+	if !ok {
+		return 0, false
+	}
+	for len(data) > 0 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	if len(data) > 8 || (len(data) == 8 && data[7]&0x80 != 0) {
+		return 0, false
+	}
+	var pointer int64
+	for i := len(data) - 1; i >= 0; i-- {
+		pointer = pointer<<8 | int64(data[i])
+	}
+	return pointer, true
+}
+
+// hasUnrecognizedEvenField reports whether an envelope has an even field ord
+// doesn't know, which cursed it before the jubilee
+func hasUnrecognizedEvenField(inscription ordinals.Inscription) bool {
+	for tag := range inscription.Fields {
+		if tag%2 == 0 && tag != ordinals.TagPointer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+package tokens
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/siddon/web3insights/internal/ordinals"
+	"github.com/siddon/web3insights/internal/runes"
+	"github.com/siddon/web3insights/internal/script"
+)
+
+// genesisID is the id of UNCOMMON•GOODS, the rune ord hardcodes as etched in
+// block 1 so it can be minted from the activation height
+var genesisID = runes.ID{Block: 1, Tx: 0}
+
+func genesisRune() *RuneEntry {
+	name, _ := new(big.Int).SetString("2055900680524219742", 10)
+	start, end := uint64(runes.FirstHeight), uint64(runes.FirstHeight+210000)
+	maxU128 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	return &RuneEntry{
+		ID:      genesisID,
+		Rune:    runes.Rune{Int: name},
+		Spacers: 128,
+		Symbol:  '⧉',
+		Premine: new(big.Int),
+		Terms: &runes.Terms{
+			Amount:      big.NewInt(1),
+			Cap:         maxU128,
+			HeightStart: &start,
+			HeightEnd:   &end,
+		},
+		Turbo:  true,
+		Mints:  new(big.Int),
+		TxHash: "0000000000000000000000000000000000000000000000000000000000000000",
+	}
+}
+
+// indexRunes applies the runestone of a transaction, following ord: the runes
+// of the spent outputs, any mint and the premine of an etching are allocated by
+// the edicts, the rest goes to the pointer output or the first output that
+// isn't OP_RETURN, and runes allocated to OP_RETURN outputs or by a cenotaph
+// are burned.
+func (ix *Indexer) indexRunes(b *txBuilder) {
+	unallocated := make(map[runes.ID]*big.Int)
+	credit := func(balances map[runes.ID]*big.Int, id runes.ID, amount *big.Int) {
+		if balances[id] == nil {
+			balances[id] = new(big.Int)
+		}
+		balances[id].Add(balances[id], amount)
+	}
+
+	for _, input := range b.tx.Inputs {
+		outpoint, ok := ix.state.RuneOutpoints[input.Outpoint]
+		if !ok {
+			continue
+		}
+		delete(ix.state.RuneOutpoints, input.Outpoint)
+		b.changes.SpentRuneOutpoints = append(b.changes.SpentRuneOutpoints, input.Outpoint)
+		for _, id := range sortedIDs(outpoint.Balances) {
+			credit(unallocated, id, outpoint.Balances[id])
+			b.emit(ProtocolRunes, EventSend, id.String(), outpoint.Address, "", outpoint.Balances[id], -1)
+		}
+	}
+
+	scripts := make([][]byte, len(b.tx.Outputs))
+	for i, output := range b.tx.Outputs {
+		scripts[i] = output.Script
+	}
+	runestone, ok := runes.Decipher(scripts)
+	allocated := make([]map[runes.ID]*big.Int, len(b.tx.Outputs))
+	for i := range allocated {
+		allocated[i] = make(map[runes.ID]*big.Int)
+	}
+
+	if ok {
+		if runestone.Mint != nil {
+			if amount := ix.mint(*runestone.Mint, b.height); amount != nil {
+				credit(unallocated, *runestone.Mint, amount)
+				b.emit(ProtocolRunes, EventMint, runestone.Mint.String(), "", "", amount, -1)
+			}
+		}
+
+		etchedID, etchedRune, etched := ix.etched(b, runestone)
+		if !runestone.Cenotaph {
+			if etched && runestone.Etching.Premine != nil {
+				credit(unallocated, etchedID, runestone.Etching.Premine)
+			}
+			for _, edict := range runestone.Edicts {
+				id := edict.ID
+				if id.IsZero() {
+					if !etched {
+						continue
+					}
+					id = etchedID
+				}
+				balance, ok := unallocated[id]
+				if !ok {
+					continue
+				}
+				allocate := func(amount *big.Int, output int) {
+					if amount.Sign() > 0 {
+						balance.Sub(balance, amount)
+						credit(allocated[output], id, amount)
+					}
+				}
+				if int(edict.Output) == len(b.tx.Outputs) {
+					// Split across every output that isn't OP_RETURN
+					var destinations []int
+					for i, output := range b.tx.Outputs {
+						if !isOpReturn(output.Script) {
+							destinations = append(destinations, i)
+						}
+					}
+					if len(destinations) == 0 {
+						continue
+					}
+					if edict.Amount.Sign() == 0 {
+						share, remainder := new(big.Int).QuoRem(balance, big.NewInt(int64(len(destinations))), new(big.Int))
+						for i, output := range destinations {
+							amount := new(big.Int).Set(share)
+							if big.NewInt(int64(i)).Cmp(remainder) < 0 {
+								amount.Add(amount, big.NewInt(1))
+							}
+							allocate(amount, output)
+						}
+					} else {
+						for _, output := range destinations {
+							allocate(minInt(edict.Amount, balance), output)
+						}
+					}
+				} else if edict.Amount.Sign() == 0 {
+					allocate(new(big.Int).Set(balance), int(edict.Output))
+				} else {
+					allocate(minInt(edict.Amount, balance), int(edict.Output))
+				}
+			}
+		}
+		if etched {
+			ix.etch(b, runestone, etchedID, etchedRune)
+		}
+	}
+
+	burned := make(map[runes.ID]*big.Int)
+	if ok && runestone.Cenotaph {
+		for id, balance := range unallocated {
+			credit(burned, id, balance)
+		}
+	} else {
+		destination := -1
+		if ok && runestone.Pointer != nil {
+			destination = int(*runestone.Pointer)
+		} else {
+			for i, output := range b.tx.Outputs {
+				if !isOpReturn(output.Script) {
+					destination = i
+					break
+				}
+			}
+		}
+		for id, balance := range unallocated {
+			if balance.Sign() == 0 {
+				continue
+			}
+			if destination >= 0 {
+				credit(allocated[destination], id, balance)
+			} else {
+				credit(burned, id, balance)
+			}
+		}
+	}
+
+	for i, balances := range allocated {
+		if len(balances) == 0 {
+			continue
+		}
+		output := b.tx.Outputs[i]
+		if isOpReturn(output.Script) {
+			for id, balance := range balances {
+				credit(burned, id, balance)
+			}
+			continue
+		}
+		for _, id := range sortedIDs(balances) {
+			b.emit(ProtocolRunes, EventReceive, id.String(), "", output.Address, balances[id], i)
+		}
+		outpoint := &RuneOutpoint{Outpoint: Outpoint{Hash: b.tx.Hash, Index: uint32(i)}, Address: output.Address, Balances: balances}
+		ix.state.RuneOutpoints[outpoint.Outpoint] = outpoint
+		b.changes.RuneOutpoints = append(b.changes.RuneOutpoints, outpoint)
+	}
+	for _, id := range sortedIDs(burned) {
+		if burned[id].Sign() > 0 {
+			b.emit(ProtocolRunes, EventBurn, id.String(), "", "", burned[id], -1)
+		}
+	}
+}
+
+// mint returns the amount a mint of id at height yields, or nil if the rune
+// can't be minted
+func (ix *Indexer) mint(id runes.ID, height uint64) *big.Int {
+	entry, ok := ix.state.Runes[id]
+	if !ok || entry.Terms == nil {
+		return nil
+	}
+	terms := entry.Terms
+	if start, ok := mintWindow(terms.HeightStart, terms.OffsetStart, id.Block, maxUint64); ok && height < start {
+		return nil
+	}
+	if end, ok := mintWindow(terms.HeightEnd, terms.OffsetEnd, id.Block, minUint64); ok && height >= end {
+		return nil
+	}
+	if terms.Cap == nil || entry.Mints.Cmp(terms.Cap) >= 0 {
+		return nil
+	}
+	entry.Mints.Add(entry.Mints, big.NewInt(1))
+	if terms.Amount == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(terms.Amount)
+}
+
+// mintWindow combines an absolute height bound with a bound relative to the
+// etching block, using pick when both are set
+func mintWindow(height, offset *uint64, block uint64, pick func(a, b uint64) uint64) (uint64, bool) {
+	var relative *uint64
+	if offset != nil {
+		r := block + *offset
+		if r < block {
+			r = ^uint64(0)
+		}
+		relative = &r
+	}
+	switch {
+	case height != nil && relative != nil:
+		return pick(*height, *relative), true
+	case height != nil:
+		return *height, true
+	case relative != nil:
+		return *relative, true
+	}
+	return 0, false
+}
+
+// etched returns the id and name of the rune a runestone etches, if any
+func (ix *Indexer) etched(b *txBuilder, runestone *runes.Runestone) (runes.ID, runes.Rune, bool) {
+	if runestone.Etching == nil {
+		return runes.ID{}, runes.Rune{}, false
+	}
+	id := runes.ID{Block: b.height, Tx: b.tx.Index}
+	name := runestone.Etching.Rune
+	if name == nil {
+		return id, runes.ReservedRune(b.height, b.tx.Index), true
+	}
+	if name.Cmp(runes.MinimumAtHeight(b.height).Int) < 0 || name.IsReserved() {
+		return runes.ID{}, runes.Rune{}, false
+	}
+	if _, exists := ix.runeNames[name.String()]; exists {
+		return runes.ID{}, runes.Rune{}, false
+	}
+	if ix.VerifyCommitments && !commitsTo(b.tx, name.Commitment()) {
+		return runes.ID{}, runes.Rune{}, false
+	}
+	return id, *name, true
+}
+
+// commitsTo reports whether an input tapscript pushes commitment. ord also
+// requires the committing output to be a mature taproot output; the spent
+// outputs aren't available here, so that part isn't checked.
+func commitsTo(tx Transaction, commitment []byte) bool {
+	for _, input := range tx.Inputs {
+		tapscript, ok := ordinals.Tapscript(input.Witness)
+		if !ok {
+			continue
+		}
+		instructions, _ := script.Disassemble(tapscript)
+		for _, instruction := range instructions {
+			if instruction.Op.IsPush() && bytes.Equal(instruction.Data, commitment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// etch records the rune etched by a runestone. Cenotaphs etch the name only.
+func (ix *Indexer) etch(b *txBuilder, runestone *runes.Runestone, id runes.ID, name runes.Rune) {
+	entry := &RuneEntry{ID: id, Rune: name, Premine: new(big.Int), Mints: new(big.Int), TxHash: b.tx.Hash, Cenotaph: runestone.Cenotaph}
+	if !runestone.Cenotaph {
+		etching := runestone.Etching
+		entry.Spacers = etching.Spacers
+		entry.Divisibility = etching.Divisibility
+		entry.Symbol = etching.Symbol
+		entry.Terms = etching.Terms
+		entry.Turbo = etching.Turbo
+		if etching.Premine != nil {
+			entry.Premine.Set(etching.Premine)
+		}
+	}
+	ix.addRune(entry, b.changes)
+	b.emit(ProtocolRunes, EventEtch, id.String(), "", "", entry.Premine, -1)
+}
+
+func (ix *Indexer) addRune(entry *RuneEntry, changes *Changes) {
+	ix.state.Runes[entry.ID] = entry
+	ix.runeNames[entry.Rune.String()] = entry.ID
+	changes.Runes = append(changes.Runes, entry)
+}
+
+func minInt(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
+
+func maxUint64(a, b uint64) uint64 { return max(a, b) }
+func minUint64(a, b uint64) uint64 { return min(a, b) }
@@ -0,0 +1,296 @@
+// Package tokens indexes Runes and BRC-20 token events from Bitcoin transactions.
+//
+// The Indexer holds the protocol state in memory: rune entries, the rune
+// balances of unspent outputs, BRC-20 tokens and balances, and BRC-20 transfer
+// inscriptions waiting to be sent. Blocks must be indexed in height order with
+// their transactions in block order; each block returns its changes so callers
+// can persist them together with the indexed height. Indexing the same blocks
+// from the same state always produces the same events.
+package tokens
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/siddon/web3insights/internal/runes"
+	"github.com/siddon/web3insights/internal/script"
+)
+
+// Protocols
+const (
+	ProtocolRunes = "runes"
+	ProtocolBRC20 = "brc20"
+)
+
+// Event types. Every event credits its amount to To and debits it from From,
+// when they are set, except inscribe_transfer, which moves a BRC-20 amount of
+// From from available to transferable.
+const (
+	EventEtch             = "etch"              // A rune is etched; Amount is its premine
+	EventMint             = "mint"              // Runes: minted into the transaction; BRC-20: minted to To
+	EventSend             = "send"              // Runes leave From by spending an output
+	EventReceive          = "receive"           // Runes are allocated to an output of To
+	EventBurn             = "burn"              // Runes are burned
+	EventDeploy           = "deploy"            // A BRC-20 token is deployed; Amount is its max supply
+	EventInscribeTransfer = "inscribe_transfer" // A BRC-20 transfer inscription reserves Amount of From
+	EventTransfer         = "transfer"          // A BRC-20 transfer inscription is sent from From to To
+)
+
+// Outpoint identifies a transaction output
+type Outpoint struct {
+	Hash  string
+	Index uint32
+}
+
+// String formats the outpoint as TXID:VOUT
+func (o Outpoint) String() string {
+	return fmt.Sprintf("%s:%d", o.Hash, o.Index)
+}
+
+// Input is a transaction input: the output it spends, that output's value in
+// satoshis, and the witness stack, which is nil when it isn't available
+type Input struct {
+	Outpoint Outpoint
+	Value    int64
+	Witness  [][]byte
+}
+
+// Output is a transaction output. Address is empty for scripts that don't pay
+// to an address.
+type Output struct {
+	Script  []byte
+	Address string
+	Value   int64
+}
+
+// Transaction is a transaction as the indexer sees it
+type Transaction struct {
+	Hash     string
+	Index    uint32 // Index within the block
+	Coinbase bool
+	Inputs   []Input
+	Outputs  []Output
+}
+
+// Event is a token event. Output is the output index the event refers to, or -1.
+type Event struct {
+	Block    uint64
+	TxIndex  uint32
+	Index    uint32 // Index within the transaction
+	TxHash   string
+	Protocol string
+	Type     string
+	Token    string // Rune id (BLOCK:TX) or lowercased BRC-20 tick
+	From     string
+	To       string
+	Amount   *big.Int // Rune units, or BRC-20 amounts scaled by 10^18
+	Output   int
+}
+
+// BalanceKey identifies the balance of one token held by one address
+type BalanceKey struct {
+	Protocol string
+	Token    string
+	Address  string
+}
+
+// Balance is a token balance. Transferable is the part of a BRC-20 balance
+// reserved by transfer inscriptions.
+type Balance struct {
+	Balance      *big.Int
+	Transferable *big.Int
+}
+
+// BalanceDeltas returns the balance changes made by events
+func BalanceDeltas(events []Event) map[BalanceKey]*Balance {
+	deltas := make(map[BalanceKey]*Balance)
+	get := func(protocol, token, address string) *Balance {
+		key := BalanceKey{Protocol: protocol, Token: token, Address: address}
+		if deltas[key] == nil {
+			deltas[key] = &Balance{Balance: new(big.Int), Transferable: new(big.Int)}
+		}
+		return deltas[key]
+	}
+	for _, e := range events {
+		if e.Type == EventInscribeTransfer {
+			from := get(e.Protocol, e.Token, e.From)
+			from.Transferable.Add(from.Transferable, e.Amount)
+			continue
+		}
+		if e.From != "" {
+			from := get(e.Protocol, e.Token, e.From)
+			from.Balance.Sub(from.Balance, e.Amount)
+			if e.Protocol == ProtocolBRC20 && e.Type == EventTransfer {
+				from.Transferable.Sub(from.Transferable, e.Amount)
+			}
+		}
+		if e.To != "" {
+			to := get(e.Protocol, e.Token, e.To)
+			to.Balance.Add(to.Balance, e.Amount)
+		}
+	}
+	return deltas
+}
+
+// RuneEntry is an etched rune
+type RuneEntry struct {
+	ID           runes.ID
+	Rune         runes.Rune
+	Spacers      uint32
+	Divisibility uint8
+	Symbol       rune
+	Premine      *big.Int
+	Terms        *runes.Terms
+	Turbo        bool
+	Cenotaph     bool     // Etched by a cenotaph: no premine and no mints
+	Mints        *big.Int // Number of mints so far
+	TxHash       string
+}
+
+// RuneOutpoint is an unspent output holding runes
+type RuneOutpoint struct {
+	Outpoint Outpoint
+	Address  string
+	Balances map[runes.ID]*big.Int
+}
+
+// Token is a deployed BRC-20 token
+type Token struct {
+	Tick          string
+	Max           *big.Int
+	Limit         *big.Int
+	Decimals      int
+	Minted        *big.Int
+	InscriptionID string
+	Deployer      string
+	Block         uint64
+}
+
+// Transfer is a BRC-20 transfer inscription that hasn't been sent yet. Offset
+// is the position of the inscribed satoshi within its output.
+type Transfer struct {
+	InscriptionID string
+	Tick          string
+	Amount        *big.Int
+	From          string
+	Location      Outpoint
+	Offset        int64
+	Block         uint64
+}
+
+// State is the protocol state the indexer starts from
+type State struct {
+	Runes         map[runes.ID]*RuneEntry
+	RuneOutpoints map[Outpoint]*RuneOutpoint
+	Tokens        map[string]*Token
+	Balances      map[BalanceKey]*Balance // BRC-20 balances, used to validate transfer inscriptions
+	Transfers     map[Outpoint][]*Transfer
+}
+
+// NewState returns an empty state
+func NewState() *State {
+	return &State{
+		Runes:         make(map[runes.ID]*RuneEntry),
+		RuneOutpoints: make(map[Outpoint]*RuneOutpoint),
+		Tokens:        make(map[string]*Token),
+		Balances:      make(map[BalanceKey]*Balance),
+		Transfers:     make(map[Outpoint][]*Transfer),
+	}
+}
+
+// Changes are the changes one block makes to the state
+type Changes struct {
+	Height             uint64
+	Events             []Event
+	Runes              []*RuneEntry    // Runes etched by the block
+	RuneOutpoints      []*RuneOutpoint // Outputs created holding runes
+	SpentRuneOutpoints []Outpoint
+	Tokens             []*Token    // BRC-20 tokens deployed by the block
+	Transfers          []*Transfer // BRC-20 transfer inscriptions created by the block
+	SpentTransfers     []string    // Inscription ids of the BRC-20 transfers executed by the block
+}
+
+// Indexer applies blocks to a State
+type Indexer struct {
+	state     *State
+	runeNames map[string]runes.ID
+	// VerifyCommitments requires named etchings to be committed to in an input
+	// tapscript. It must be off when witness data isn't available, otherwise no
+	// named rune could be etched.
+	VerifyCommitments bool
+}
+
+// NewIndexer returns an indexer continuing from state
+func NewIndexer(state *State) *Indexer {
+	ix := &Indexer{state: state, runeNames: make(map[string]runes.ID), VerifyCommitments: true}
+	for id, entry := range state.Runes {
+		ix.runeNames[entry.Rune.String()] = id
+	}
+	return ix
+}
+
+// IndexBlock indexes the transactions of the block at height
+func (ix *Indexer) IndexBlock(height uint64, transactions []Transaction) *Changes {
+	changes := &Changes{Height: height}
+	if height >= runes.FirstHeight {
+		if _, ok := ix.state.Runes[genesisID]; !ok {
+			ix.addRune(genesisRune(), changes)
+		}
+	}
+	for _, tx := range transactions {
+		b := &txBuilder{changes: changes, height: height, tx: tx}
+		if height >= runes.FirstHeight {
+			ix.indexRunes(b)
+		}
+		if height >= brc20FirstHeight {
+			ix.indexBRC20(b)
+		}
+	}
+	return changes
+}
+
+// txBuilder numbers the events of one transaction
+type txBuilder struct {
+	changes *Changes
+	height  uint64
+	tx      Transaction
+	events  uint32
+}
+
+func (b *txBuilder) emit(protocol, eventType, token, from, to string, amount *big.Int, output int) {
+	b.changes.Events = append(b.changes.Events, Event{
+		Block:    b.height,
+		TxIndex:  b.tx.Index,
+		Index:    b.events,
+		TxHash:   b.tx.Hash,
+		Protocol: protocol,
+		Type:     eventType,
+		Token:    token,
+		From:     from,
+		To:       to,
+		Amount:   new(big.Int).Set(amount),
+		Output:   output,
+	})
+	b.events++
+}
+
+// isOpReturn reports whether an output script is provably unspendable
+func isOpReturn(s []byte) bool {
+	return len(s) > 0 && script.Opcode(s[0]) == script.OP_RETURN
+}
+
+// sortedIDs returns the keys of balances in id order
+func sortedIDs(balances map[runes.ID]*big.Int) []runes.ID {
+	ids := make([]runes.ID, 0, len(balances))
+	for id := range balances {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Block != ids[j].Block {
+			return ids[i].Block < ids[j].Block
+		}
+		return ids[i].Tx < ids[j].Tx
+	})
+	return ids
+}
@@ -33,13 +33,12 @@ export async function GET(request: Request) {
         return query<BlockResult>(
           `SELECT 
             record_date,
-            COUNT(*) as block_count,
-            SUM(transaction_count) as total_transactions,
-            AVG(difficulty) as avg_difficulty
-          FROM btc_blocks
+            block_count,
+            total_transactions,
+            avg_difficulty
+          FROM btc_daily_stats
           WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
             AND record_date < CURDATE()
-          GROUP BY record_date
           ORDER BY record_date ASC`,
           [days]
         );
@@ -47,7 +46,9 @@ export async function GET(request: Request) {
       historicalCacheKey
     );
 
-    // Always fetch today's data fresh (no caching)
+    // Always fetch today's data fresh (no caching). Today has no
+    // btc_daily_stats row until its sync completes, so it is aggregated from
+    // btc_blocks
     const todayData = await query<BlockResult>(
       `SELECT 
         record_date,
@@ -0,0 +1,85 @@
+import { NextResponse } from 'next/server';
+import { query } from '@/lib/db';
+import {
+  getCacheKey,
+  getTodayDate,
+  createCachedQuery,
+} from '@/lib/cache';
+
+export async function GET(request: Request) {
+  try {
+    const { searchParams } = new URL(request.url);
+    const range = searchParams.get('range') || '1d';
+    
+    // Parse range: 1d, 3d, 5d, 7d
+    const validRanges = ['1d', '3d', '5d', '7d'];
+    const timeRange = validRanges.includes(range) ? range : '1d';
+    
+    // Extract days from range (e.g., '1d' -> 1, '3d' -> 3)
+    const days = parseInt(timeRange);
+    const todayStr = getTodayDate();
+
+    type FeeStatsResult = {
+      record_date: Date;
+      block_count: number;
+      tx_count: number;
+      total_fees: number;
+      fee_share: number | null;
+      avg_fullness: number | null;
+      p10_fee_rate: number | null;
+      median_fee_rate: number | null;
+      p90_fee_rate: number | null;
+      avg_fee_rate: number | null;
+    };
+
+    const columns = `record_date,
+            block_count,
+            tx_count,
+            total_fees,
+            fee_share,
+            avg_fullness,
+            p10_fee_rate,
+            median_fee_rate,
+            p90_fee_rate,
+            avg_fee_rate`;
+
+    // Fetch historical data (excluding today) with caching
+    const historicalCacheKey = getCacheKey('fees-daily', timeRange);
+    const historicalData = await createCachedQuery<FeeStatsResult>(
+      async () => {
+        return query<FeeStatsResult>(
+          `SELECT ${columns}
+          FROM btc_block_stats_daily
+          WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+            AND record_date < CURDATE()
+          ORDER BY record_date ASC`,
+          [days]
+        );
+      },
+      historicalCacheKey
+    );
+
+    // Always fetch today's data fresh (no caching)
+    const todayData = await query<FeeStatsResult>(
+      `SELECT ${columns}
+      FROM btc_block_stats_daily
+      WHERE record_date = ?`,
+      [todayStr]
+    );
+
+    // Combine historical (cached) and today's (fresh) data
+    const results = [...historicalData, ...todayData].sort((a, b) => {
+      const dateA = new Date(a.record_date).getTime();
+      const dateB = new Date(b.record_date).getTime();
+      return dateA - dateB;
+    });
+
+    return NextResponse.json(results);
+  } catch (error) {
+    console.error('Error fetching daily fee statistics:', error);
+    return NextResponse.json(
+      { error: 'Failed to fetch daily fee data' },
+      { status: 500 }
+    );
+  }
+}
@@ -0,0 +1,82 @@
+import { NextResponse } from 'next/server';
+import { query } from '@/lib/db';
+import {
+  getCacheKey,
+  getTodayDate,
+  createCachedQuery,
+} from '@/lib/cache';
+
+export async function GET(request: Request) {
+  try {
+    const { searchParams } = new URL(request.url);
+    const range = searchParams.get('range') || '1d';
+    
+    // Parse range: 1d, 3d, 5d, 7d
+    const validRanges = ['1d', '3d', '5d', '7d'];
+    const timeRange = validRanges.includes(range) ? range : '1d';
+    
+    // Extract days from range (e.g., '1d' -> 1, '3d' -> 3)
+    const days = parseInt(timeRange);
+    const todayStr = getTodayDate();
+
+    type HashrateResult = {
+      record_date: Date;
+      block_count: number;
+      avg_difficulty: number;
+      avg_hashrate_144: number | null;
+      avg_hashrate_1008: number | null;
+    };
+
+    // Fetch historical data (excluding today) with caching
+    const historicalCacheKey = getCacheKey('hashrate-daily', timeRange);
+    const historicalData = await createCachedQuery<HashrateResult>(
+      async () => {
+        return query<HashrateResult>(
+          `SELECT 
+            record_date,
+            COUNT(*) as block_count,
+            AVG(difficulty) as avg_difficulty,
+            AVG(hashrate_144) as avg_hashrate_144,
+            AVG(hashrate_1008) as avg_hashrate_1008
+          FROM btc_hashrate
+          WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+            AND record_date < CURDATE()
+          GROUP BY record_date
+          ORDER BY record_date ASC`,
+          [days]
+        );
+      },
+      historicalCacheKey
+    );
+
+    // Always fetch today's data fresh (no caching)
+    const todayData = await query<HashrateResult>(
+      `SELECT 
+        record_date,
+        COUNT(*) as block_count,
+        AVG(difficulty) as avg_difficulty,
+        AVG(hashrate_144) as avg_hashrate_144,
+        AVG(hashrate_1008) as avg_hashrate_1008
+      FROM btc_hashrate
+      WHERE record_date = ?
+      GROUP BY record_date
+      ORDER BY record_date ASC`,
+      [todayStr]
+    );
+
+    // Combine historical (cached) and today's (fresh) data
+    const results = [...historicalData, ...todayData].sort((a, b) => {
+      const dateA = new Date(a.record_date).getTime();
+      const dateB = new Date(b.record_date).getTime();
+      return dateA - dateB;
+    });
+
+    return NextResponse.json(results);
+  } catch (error) {
+    console.error('Error fetching daily hashrate:', error);
+    return NextResponse.json(
+      { error: 'Failed to fetch daily hashrate data' },
+      { status: 500 }
+    );
+  }
+}
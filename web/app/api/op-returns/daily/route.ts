@@ -0,0 +1,82 @@
+import { NextResponse } from 'next/server';
+import { query } from '@/lib/db';
+import {
+  getCacheKey,
+  getTodayDate,
+  createCachedQuery,
+} from '@/lib/cache';
+
+export async function GET(request: Request) {
+  try {
+    const { searchParams } = new URL(request.url);
+    const range = searchParams.get('range') || '1d';
+    
+    // Parse range: 1d, 3d, 5d, 7d
+    const validRanges = ['1d', '3d', '5d', '7d'];
+    const timeRange = validRanges.includes(range) ? range : '1d';
+    
+    // Extract days from range (e.g., '1d' -> 1, '3d' -> 3)
+    const days = parseInt(timeRange);
+    const todayStr = getTodayDate();
+
+    type OpReturnResult = {
+      record_date: Date;
+      protocol: string;
+      op_return_count: number;
+      total_bytes: number;
+      burned_value: number;
+    };
+
+    // Fetch historical data (excluding today) with caching
+    const historicalCacheKey = getCacheKey('op-returns-daily', timeRange);
+    const historicalData = await createCachedQuery<OpReturnResult>(
+      async () => {
+        return query<OpReturnResult>(
+          `SELECT 
+            record_date,
+            protocol,
+            COUNT(*) as op_return_count,
+            COALESCE(SUM(data_size), 0) as total_bytes,
+            COALESCE(SUM(output_amount), 0) as burned_value
+          FROM btc_op_returns
+          WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+            AND record_date < CURDATE()
+          GROUP BY record_date, protocol
+          ORDER BY record_date ASC, protocol ASC`,
+          [days]
+        );
+      },
+      historicalCacheKey
+    );
+
+    // Always fetch today's data fresh (no caching)
+    const todayData = await query<OpReturnResult>(
+      `SELECT 
+        record_date,
+        protocol,
+        COUNT(*) as op_return_count,
+        COALESCE(SUM(data_size), 0) as total_bytes,
+        COALESCE(SUM(output_amount), 0) as burned_value
+      FROM btc_op_returns
+      WHERE record_date = ?
+      GROUP BY record_date, protocol
+      ORDER BY record_date ASC, protocol ASC`,
+      [todayStr]
+    );
+
+    // Combine historical (cached) and today's (fresh) data
+    const results = [...historicalData, ...todayData].sort((a, b) => {
+      const dateA = new Date(a.record_date).getTime();
+      const dateB = new Date(b.record_date).getTime();
+      return dateA - dateB;
+    });
+
+    return NextResponse.json(results);
+  } catch (error) {
+    console.error('Error fetching daily OP_RETURN counts:', error);
+    return NextResponse.json(
+      { error: 'Failed to fetch daily OP_RETURN data' },
+      { status: 500 }
+    );
+  }
+}
@@ -0,0 +1,80 @@
+import { NextResponse } from 'next/server';
+import { query } from '@/lib/db';
+import {
+  getCacheKey,
+  getTodayDate,
+  createCachedQuery,
+} from '@/lib/cache';
+
+export async function GET(request: Request) {
+  try {
+    const { searchParams } = new URL(request.url);
+    const range = searchParams.get('range') || '1d';
+    
+    // Parse range: 1d, 3d, 5d, 7d
+    const validRanges = ['1d', '3d', '5d', '7d'];
+    const timeRange = validRanges.includes(range) ? range : '1d';
+    
+    // Extract days from range (e.g., '1d' -> 1, '3d' -> 3)
+    const days = parseInt(timeRange);
+    const todayStr = getTodayDate();
+
+    type PoolShareResult = {
+      record_date: Date;
+      pool_name: string;
+      block_count: number;
+      block_share: number;
+      estimated_hashrate: number;
+    };
+
+    // Fetch historical data (excluding today) with caching
+    const historicalCacheKey = getCacheKey('pools-daily', timeRange);
+    const historicalData = await createCachedQuery<PoolShareResult>(
+      async () => {
+        return query<PoolShareResult>(
+          `SELECT 
+            record_date,
+            pool_name,
+            block_count,
+            block_share,
+            COALESCE(estimated_hashrate, 0) as estimated_hashrate
+          FROM btc_pool_daily_shares
+          WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+            AND record_date < CURDATE()
+          ORDER BY record_date ASC, block_count DESC`,
+          [days]
+        );
+      },
+      historicalCacheKey
+    );
+
+    // Always fetch today's data fresh (no caching)
+    const todayData = await query<PoolShareResult>(
+      `SELECT 
+        record_date,
+        pool_name,
+        block_count,
+        block_share,
+        COALESCE(estimated_hashrate, 0) as estimated_hashrate
+      FROM btc_pool_daily_shares
+      WHERE record_date = ?
+      ORDER BY record_date ASC, block_count DESC`,
+      [todayStr]
+    );
+
+    // Combine historical (cached) and today's (fresh) data
+    const results = [...historicalData, ...todayData].sort((a, b) => {
+      const dateA = new Date(a.record_date).getTime();
+      const dateB = new Date(b.record_date).getTime();
+      return dateA - dateB;
+    });
+
+    return NextResponse.json(results);
+  } catch (error) {
+    console.error('Error fetching daily pool shares:', error);
+    return NextResponse.json(
+      { error: 'Failed to fetch daily pool share data' },
+      { status: 500 }
+    );
+  }
+}
@@ -0,0 +1,82 @@
+import { NextResponse } from 'next/server';
+import { query } from '@/lib/db';
+import {
+  getCacheKey,
+  getTodayDate,
+  createCachedQuery,
+} from '@/lib/cache';
+
+export async function GET(request: Request) {
+  try {
+    const { searchParams } = new URL(request.url);
+    const range = searchParams.get('range') || '1d';
+    
+    // Parse range: 1d, 3d, 5d, 7d
+    const validRanges = ['1d', '3d', '5d', '7d'];
+    const timeRange = validRanges.includes(range) ? range : '1d';
+    
+    // Extract days from range (e.g., '1d' -> 1, '3d' -> 3)
+    const days = parseInt(timeRange);
+    const todayStr = getTodayDate();
+
+    type PrivacyResult = {
+      record_date: Date;
+      label: string;
+      transaction_count: number;
+      avg_confidence: number;
+      mixed_value: number;
+    };
+
+    // Fetch historical data (excluding today) with caching
+    const historicalCacheKey = getCacheKey('privacy-daily', timeRange);
+    const historicalData = await createCachedQuery<PrivacyResult>(
+      async () => {
+        return query<PrivacyResult>(
+          `SELECT 
+            record_date,
+            label,
+            COUNT(*) as transaction_count,
+            AVG(confidence) as avg_confidence,
+            COALESCE(SUM(equal_outputs * denomination), 0) as mixed_value
+          FROM btc_privacy_labels
+          WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+            AND record_date < CURDATE()
+          GROUP BY record_date, label
+          ORDER BY record_date ASC, label ASC`,
+          [days]
+        );
+      },
+      historicalCacheKey
+    );
+
+    // Always fetch today's data fresh (no caching)
+    const todayData = await query<PrivacyResult>(
+      `SELECT 
+        record_date,
+        label,
+        COUNT(*) as transaction_count,
+        AVG(confidence) as avg_confidence,
+        COALESCE(SUM(equal_outputs * denomination), 0) as mixed_value
+      FROM btc_privacy_labels
+      WHERE record_date = ?
+      GROUP BY record_date, label
+      ORDER BY record_date ASC, label ASC`,
+      [todayStr]
+    );
+
+    // Combine historical (cached) and today's (fresh) data
+    const results = [...historicalData, ...todayData].sort((a, b) => {
+      const dateA = new Date(a.record_date).getTime();
+      const dateB = new Date(b.record_date).getTime();
+      return dateA - dateB;
+    });
+
+    return NextResponse.json(results);
+  } catch (error) {
+    console.error('Error fetching daily privacy transaction counts:', error);
+    return NextResponse.json(
+      { error: 'Failed to fetch daily privacy transaction data' },
+      { status: 500 }
+    );
+  }
+}
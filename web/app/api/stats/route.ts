@@ -3,29 +3,22 @@ import { query } from '@/lib/db';
 
 export async function GET() {
   try {
-    // Get total blocks count
-    const blockCountResult = await query<{ count: number }>(
-      'SELECT COUNT(*) as count FROM btc_blocks'
-    );
-    const blockCount = blockCountResult[0] || { count: 0 };
-
-    // Get total transactions count
-    const txCountResult = await query<{ count: number }>(
-      'SELECT COUNT(*) as count FROM btc_transactions'
-    );
-    const txCount = txCountResult[0] || { count: 0 };
-
-    // Get total transaction volume (sum of output_value)
-    const txVolumeResult = await query<{ total: number }>(
-      'SELECT COALESCE(SUM(output_value), 0) as total FROM btc_transactions'
-    );
-    const txVolume = txVolumeResult[0] || { total: 0 };
-
-    // Get total fees
-    const totalFeesResult = await query<{ total: number }>(
-      'SELECT COALESCE(SUM(fee), 0) as total FROM btc_transactions WHERE fee IS NOT NULL'
+    // Totals are summed from the daily rollup rows written by sync, so the
+    // raw tables are never scanned
+    const totalsResult = await query<{
+      blocks: number;
+      transactions: number;
+      volume: number;
+      fees: number;
+    }>(
+      `SELECT
+        COALESCE(SUM(block_count), 0) as blocks,
+        COALESCE(SUM(transaction_count), 0) as transactions,
+        COALESCE(SUM(total_volume), 0) as volume,
+        COALESCE(SUM(total_fees), 0) as fees
+      FROM btc_daily_stats`
     );
-    const totalFees = totalFeesResult[0] || { total: 0 };
+    const totals = totalsResult[0] || { blocks: 0, transactions: 0, volume: 0, fees: 0 };
 
     // Get latest block
     const latestBlockResult = await query<{
@@ -43,15 +36,15 @@ export async function GET() {
       min_date: Date;
       max_date: Date;
     }>(
-      'SELECT MIN(record_date) as min_date, MAX(record_date) as max_date FROM btc_blocks'
+      'SELECT MIN(record_date) as min_date, MAX(record_date) as max_date FROM btc_daily_stats'
     );
     const dateRange = dateRangeResult[0] || null;
 
     return NextResponse.json({
-      blocks: blockCount.count,
-      transactions: txCount.count,
-      totalVolume: txVolume.total,
-      totalFees: totalFees.total,
+      blocks: totals.blocks,
+      transactions: totals.transactions,
+      totalVolume: totals.volume,
+      totalFees: totals.fees,
       latestBlock: latestBlock
         ? {
             number: latestBlock.number,
@@ -0,0 +1,85 @@
+import { NextResponse } from 'next/server';
+import { query } from '@/lib/db';
+import {
+  getCacheKey,
+  getTodayDate,
+  createCachedQuery,
+} from '@/lib/cache';
+
+export async function GET(request: Request) {
+  try {
+    const { searchParams } = new URL(request.url);
+    const range = searchParams.get('range') || '1d';
+    
+    // Parse range: 1d, 3d, 5d, 7d
+    const validRanges = ['1d', '3d', '5d', '7d'];
+    const timeRange = validRanges.includes(range) ? range : '1d';
+    
+    // Extract days from range (e.g., '1d' -> 1, '3d' -> 3)
+    const days = parseInt(timeRange);
+    const todayStr = getTodayDate();
+
+    type TokenEventResult = {
+      record_date: Date;
+      protocol: string;
+      event_type: string;
+      event_count: number;
+      token_count: number;
+      transaction_count: number;
+    };
+
+    // Fetch historical data (excluding today) with caching
+    const historicalCacheKey = getCacheKey('tokens-daily', timeRange);
+    const historicalData = await createCachedQuery<TokenEventResult>(
+      async () => {
+        return query<TokenEventResult>(
+          `SELECT 
+            record_date,
+            protocol,
+            event_type,
+            COUNT(*) as event_count,
+            COUNT(DISTINCT token) as token_count,
+            COUNT(DISTINCT transaction_hash) as transaction_count
+          FROM btc_token_events
+          WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
+            AND record_date < CURDATE()
+          GROUP BY record_date, protocol, event_type
+          ORDER BY record_date ASC, protocol ASC, event_type ASC`,
+          [days]
+        );
+      },
+      historicalCacheKey
+    );
+
+    // Always fetch today's data fresh (no caching)
+    const todayData = await query<TokenEventResult>(
+      `SELECT 
+        record_date,
+        protocol,
+        event_type,
+        COUNT(*) as event_count,
+        COUNT(DISTINCT token) as token_count,
+        COUNT(DISTINCT transaction_hash) as transaction_count
+      FROM btc_token_events
+      WHERE record_date = ?
+      GROUP BY record_date, protocol, event_type
+      ORDER BY record_date ASC, protocol ASC, event_type ASC`,
+      [todayStr]
+    );
+
+    // Combine historical (cached) and today's (fresh) data
+    const results = [...historicalData, ...todayData].sort((a, b) => {
+      const dateA = new Date(a.record_date).getTime();
+      const dateB = new Date(b.record_date).getTime();
+      return dateA - dateB;
+    });
+
+    return NextResponse.json(results);
+  } catch (error) {
+    console.error('Error fetching daily token events:', error);
+    return NextResponse.json(
+      { error: 'Failed to fetch daily token event data' },
+      { status: 500 }
+    );
+  }
+}
@@ -34,14 +34,13 @@ export async function GET(request: Request) {
         return query<TransactionResult>(
           `SELECT 
             record_date,
-            COUNT(*) as transaction_count,
-            COALESCE(SUM(output_value), 0) as total_volume,
-            COALESCE(SUM(fee), 0) as total_fees,
-            COALESCE(AVG(fee), 0) as avg_fee
-          FROM btc_transactions
+            transaction_count,
+            total_volume,
+            total_fees,
+            avg_fee
+          FROM btc_daily_stats
           WHERE record_date >= DATE_SUB(CURDATE(), INTERVAL ? DAY)
             AND record_date < CURDATE()
-          GROUP BY record_date
           ORDER BY record_date ASC`,
           [days]
         );
@@ -49,7 +48,9 @@ export async function GET(request: Request) {
       historicalCacheKey
     );
 
-    // Always fetch today's data fresh (no caching)
+    // Always fetch today's data fresh (no caching). Today has no
+    // btc_daily_stats row until its sync completes, so it is aggregated from
+    // btc_transactions
     const todayData = await query<TransactionResult>(
       `SELECT 
         record_date,